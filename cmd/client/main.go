@@ -8,11 +8,15 @@ import (
 	"github.com/col3name/lines/pkg/common/infrastructure/env"
 	"github.com/col3name/lines/pkg/common/infrastructure/logrusLogger"
 	"io"
+	"sync"
 	"time"
 
+	"github.com/col3name/lines/pkg/kiddy-line-processor/domain/model"
+	grpcServer "github.com/col3name/lines/pkg/kiddy-line-processor/infrastructure/transport/grpc"
 	pb "github.com/col3name/lines/pkg/kiddy-line-processor/infrastructure/transport/grpc/proto"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
 )
 
 func main() {
@@ -26,20 +30,30 @@ func main() {
 	defer conn.Close()
 	client := pb.NewKiddyLineProcessorClient(conn)
 
-	handleGrpc(logger, client)
+	sessionId := env.GetEnvVariable("KIDDY_SESSION_ID", "")
+
+	handleGrpc(logger, client, sessionId)
 }
 
-func handleGrpc(logger loggerInterface.Logger, client pb.KiddyLineProcessorClient) {
+func handleGrpc(logger loggerInterface.Logger, client pb.KiddyLineProcessorClient, sessionId string) {
 	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
 	defer cancel()
+	if sessionId != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, grpcServer.SessionIdHeader, sessionId)
+	}
 	stream, err := client.SubscribeOnSportsLines(ctx)
 	if err != nil {
 		logger.Fatal("client.RouteChat failed: ", err)
 	}
+	header, err := stream.Header()
+	if err != nil {
+		logger.Fatal("client.RouteChat failed: ", err)
+	}
+	logger.Println("session", header.Get(grpcServer.SessionIdHeader), "resumed", header.Get(grpcServer.SessionResumedHeader))
 
 	sports := []string{string(commonDomain.Soccer)}
 
-	handle := clientHandle{stream: stream, logger: logger}
+	handle := clientHandle{stream: stream, logger: logger, lastSequences: make(map[string]uint64)}
 
 	go handle.subscribeToSports(sports)
 	go handle.receiveMessages()
@@ -48,8 +62,10 @@ func handleGrpc(logger loggerInterface.Logger, client pb.KiddyLineProcessorClien
 }
 
 type clientHandle struct {
-	stream pb.KiddyLineProcessor_SubscribeOnSportsLinesClient
-	logger loggerInterface.Logger
+	stream        pb.KiddyLineProcessor_SubscribeOnSportsLinesClient
+	logger        loggerInterface.Logger
+	sendMu        sync.Mutex
+	lastSequences map[string]uint64
 }
 
 func (c *clientHandle) subscribeToSports(sports []string) {
@@ -60,7 +76,12 @@ func (c *clientHandle) subscribeToSports(sports []string) {
 	c.sendSubscribeRequests(subscriptions, 0)
 
 	subscriptions = []*pb.SubscribeRequest{
-		{Sports: []string{commonDomain.Soccer.String()}, IntervalInSecond: 1},
+		{
+			Sports:           []string{commonDomain.Soccer.String()},
+			Markets:          []string{commonDomain.Moneyline.String(), commonDomain.Totals.String()},
+			OddsFormat:       commonDomain.OddsAmerican.String(),
+			IntervalInSecond: 1,
+		},
 	}
 	c.sendSubscribeRequests(subscriptions, 10)
 
@@ -76,7 +97,34 @@ func (c *clientHandle) subscribeToSports(sports []string) {
 	c.sendSubscribeRequests(subscriptions, 10)
 	subscriptions = []*pb.SubscribeRequest{
 		{
-			Sports:           []string{commonDomain.Baseball.String()},
+			SubscriptionId: "live",
+			Sports:         []string{commonDomain.Baseball.String()},
+			DeliveryMode:   model.DeliveryOnChange.String(),
+		},
+	}
+	c.sendSubscribeRequests(subscriptions, 10)
+	subscriptions = []*pb.SubscribeRequest{
+		{
+			SubscriptionId:   "fast",
+			Sports:           []string{commonDomain.Soccer.String(), commonDomain.Football.String()},
+			IntervalMs:       2000,
+			SportIntervalsMs: map[string]int64{commonDomain.Soccer.String(): 250},
+			DeltaMode:        model.DeltaThreshold.String(),
+			DeltaThreshold:   "0.05",
+		},
+	}
+	c.sendSubscribeRequests(subscriptions, 10)
+	subscriptions = []*pb.SubscribeRequest{
+		{
+			SubscriptionId: "live",
+			Operation:      pb.Operation_ADD_SPORTS,
+			Sports:         []string{commonDomain.Soccer.String()},
+		},
+	}
+	c.sendSubscribeRequests(subscriptions, 10)
+	subscriptions = []*pb.SubscribeRequest{
+		{
+			EventIds:         []string{"soccer-1", "football-2"},
 			IntervalInSecond: 1,
 		},
 	}
@@ -100,13 +148,45 @@ func (c *clientHandle) receiveMessage() bool {
 	if err != nil {
 		c.logger.Fatal("client.RouteChat failed:", err)
 	}
-	c.printSports(recv)
+	switch payload := recv.Payload.(type) {
+	case *pb.SubscribeResponse_Ack:
+		c.logger.Println("subscribed", payload.Ack.SubscriptionId, payload.Ack.Removed, payload.Ack.Sports, payload.Ack.EventIds, payload.Ack.DeliveryMode, payload.Ack.IntervalMs, payload.Ack.SportIntervalsMs, payload.Ack.DeltaMode, payload.Ack.DeltaThreshold)
+	case *pb.SubscribeResponse_Error:
+		c.logger.Println("rejected", payload.Error.SubscriptionId, payload.Error.Code, payload.Error.Field, payload.Error.Rejected, payload.Error.Message)
+	case *pb.SubscribeResponse_Update:
+		c.handleUpdate(payload.Update)
+	case *pb.SubscribeResponse_GoingAway:
+		c.logger.Println("going away", payload.GoingAway.Reason)
+		return false
+	}
 	return true
 }
 
-func (c *clientHandle) printSports(recv *pb.SubscribeResponse) {
+func (c *clientHandle) handleUpdate(update *pb.LineUpdate) {
+	lastSequence := c.lastSequences[update.SubscriptionId]
+	if update.Sequence <= lastSequence {
+		return
+	}
+	if !update.Snapshot && update.Sequence > lastSequence+1 {
+		c.logger.Println("missed updates of", update.SubscriptionId, "from", lastSequence+1, "to", update.Sequence-1)
+		c.send(&pb.SubscribeRequest{SubscriptionId: update.SubscriptionId, ReplayFrom: lastSequence + 1})
+		return
+	}
+	c.lastSequences[update.SubscriptionId] = update.Sequence
+	c.printSports(update)
+}
+
+func (c *clientHandle) printSports(recv *pb.LineUpdate) {
 	for _, sport := range recv.Sports {
 		fmt.Println(sport.Type, sport.Line)
+		for _, market := range sport.Markets {
+			for _, selection := range market.Selections {
+				fmt.Println("  ", market.Type, market.Point, selection.Name, selection.Price)
+			}
+		}
+	}
+	for _, event := range recv.Events {
+		fmt.Println(event.Id, event.HomeTeam, "-", event.AwayTeam, event.Status, event.Line)
 	}
 }
 
@@ -114,8 +194,14 @@ func (c *clientHandle) sendSubscribeRequests(subscriptions []*pb.SubscribeReques
 	time.Sleep(time.Duration(sec) * time.Second)
 
 	for _, sub := range subscriptions {
-		if err := c.stream.Send(sub); err != nil {
-			c.logger.Fatalf("client.RouteChat: stream.Send(%v) failed: %v", sub, err)
-		}
+		c.send(sub)
+	}
+}
+
+func (c *clientHandle) send(request *pb.SubscribeRequest) {
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+	if err := c.stream.Send(request); err != nil {
+		c.logger.Fatalf("client.RouteChat: stream.Send(%v) failed: %v", request, err)
 	}
 }
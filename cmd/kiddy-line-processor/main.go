@@ -1,27 +1,40 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"github.com/col3name/lines/cmd/kiddy-line-processor/config"
 	"github.com/col3name/lines/data/migrations/pg"
 	loggerInterface "github.com/col3name/lines/pkg/common/application/logger"
+	"github.com/col3name/lines/pkg/common/application/service/sport-registry"
 	commonDomain "github.com/col3name/lines/pkg/common/domain"
 	"github.com/col3name/lines/pkg/common/infrastructure/logrusLogger"
 	commonPostgres "github.com/col3name/lines/pkg/common/infrastructure/postgres"
+	commonQuery "github.com/col3name/lines/pkg/common/infrastructure/postgres/query"
 	grpcUtil "github.com/col3name/lines/pkg/common/infrastructure/transport/grpc"
 	httpUtil "github.com/col3name/lines/pkg/common/infrastructure/transport/http"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/application/service"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/application/service/line-change"
 	"github.com/col3name/lines/pkg/kiddy-line-processor/application/service/sport-line"
 	domainQuery "github.com/col3name/lines/pkg/kiddy-line-processor/domain/query"
 	"github.com/col3name/lines/pkg/kiddy-line-processor/infrastructure/adapter"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/infrastructure/postgres/listener"
 	"github.com/col3name/lines/pkg/kiddy-line-processor/infrastructure/postgres/query"
 	"github.com/col3name/lines/pkg/kiddy-line-processor/infrastructure/postgres/repo"
 	grpcServer "github.com/col3name/lines/pkg/kiddy-line-processor/infrastructure/transport/grpc"
 	pb "github.com/col3name/lines/pkg/kiddy-line-processor/infrastructure/transport/grpc/proto"
 	"github.com/col3name/lines/pkg/kiddy-line-processor/infrastructure/transport/http/router"
 	"google.golang.org/grpc"
+	"net/http"
+	"os"
+	"os/signal"
 	"sync"
+	"syscall"
 	"time"
 )
 
+var errShutdownDeadline = errors.New("shutdown deadline exceeded")
+
 func main() {
 	logger := logrusLogger.New()
 	conf := config.ParseConfig(logger)
@@ -29,93 +42,216 @@ func main() {
 
 	unitOfWork := repo.NewUnitOfWork(conn, logger)
 	sportLineQueryService := query.NewSportLineQueryService(conn, logger)
+	eventQueryService := query.NewEventQueryService(conn, logger)
+	refreshPeriod := time.Duration(conf.SportsRefreshPeriod) * time.Second
+	sportRegistry := sport_registry.NewSportRegistry(commonQuery.NewSportQueryService(conn, logger), refreshPeriod, logger)
 	linesProviderAdapter := adapter.NewLinesProviderAdapter(conf.LinesProviderUrl, logger)
+	lineChangeBus := line_change.NewLineChangeBus()
 	newSportLineUpdateService := sport_line.NewSportLinesUpdateService(conf.UpdatePeriod, linesProviderAdapter, unitOfWork)
-	migrationService := pg.NewMigrationService(sportLineQueryService, unitOfWork)
+	fanOutEngine := sport_line.NewFanOutEngine(sportLineQueryService, eventQueryService, lineChangeBus, logger)
+	lineChangeListener := listener.NewLineChangeListener(listener.PoolConnector(conn), fanOutEngine, sportRegistry, logger)
+	migrationService, err := pg.NewMigrationService(unitOfWork)
+	if err != nil {
+		logger.Fatal(err)
+	}
 
-	s := newMicroservice(conf, logger, migrationService, sportLineQueryService, newSportLineUpdateService)
+	s := newMicroservice(conf, logger, conn, migrationService, sportRegistry, sportLineQueryService, eventQueryService, newSportLineUpdateService, fanOutEngine, lineChangeListener, lineChangeBus)
 	s.run()
 }
 
 type microservice struct {
 	conf                    *config.Config
 	logger                  loggerInterface.Logger
+	conn                    commonPostgres.PgxPoolIface
 	migration               pg.MigrationService
+	sportRegistry           sport_registry.SportRegistry
 	sportLineQueryService   domainQuery.SportLineQueryService
+	eventQueryService       domainQuery.EventQueryService
 	sportLinesUpdateService sport_line.SportLinesUpdateService
+	fanOutEngine            *sport_line.FanOutEngine
+	lineChangeListener      *listener.LineChangeListener
+	lineChanges             service.LineChangeSubscriber
+	updateWorkers           sync.Map
 }
 
 func newMicroservice(
 	conf *config.Config,
 	logger loggerInterface.Logger,
+	conn commonPostgres.PgxPoolIface,
 	migration pg.MigrationService,
+	sportRegistry sport_registry.SportRegistry,
 	sportLineQueryService domainQuery.SportLineQueryService,
+	eventQueryService domainQuery.EventQueryService,
 	sportLineUpdateService sport_line.SportLinesUpdateService,
+	fanOutEngine *sport_line.FanOutEngine,
+	lineChangeListener *listener.LineChangeListener,
+	lineChanges service.LineChangeSubscriber,
 ) *microservice {
 
 	return &microservice{
 		conf:                    conf,
 		logger:                  logger,
+		conn:                    conn,
 		migration:               migration,
+		sportRegistry:           sportRegistry,
 		sportLineQueryService:   sportLineQueryService,
+		eventQueryService:       eventQueryService,
 		sportLinesUpdateService: sportLineUpdateService,
+		fanOutEngine:            fanOutEngine,
+		lineChangeListener:      lineChangeListener,
+		lineChanges:             lineChanges,
 	}
 }
 
 func (s *microservice) run() {
-	var wg sync.WaitGroup
-	wg.Add(2)
-	err := s.performDbMigrationIfNeeded()
+	err := s.performDbMigration()
 	if err != nil {
 		s.logger.Fatal(err)
 	}
-	go s.runHttpServer(&wg)
-	go s.runGrpcServer(&wg)
-	go s.runSpotLineUpdateWorkers()
-	wg.Wait()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	httpSrv := s.newHttpServer()
+	grpcSrv, server := s.newGrpcServer()
+	go httpUtil.ServeHttp(httpSrv, s.logger)
+	go grpcUtil.RunGrpcServer(s.logger, s.conf.GrpcUrl, grpcSrv)
+
+	var workers sync.WaitGroup
+	workers.Add(3)
+	go func() {
+		defer workers.Done()
+		s.sportRegistry.Run(ctx)
+	}()
+	go func() {
+		defer workers.Done()
+		s.lineChangeListener.Run(ctx)
+	}()
+	go func() {
+		defer workers.Done()
+		s.runSpotLineUpdateWorkers(ctx, &workers)
+	}()
+
+	<-ctx.Done()
+	s.logger.Info("shutting down")
+	if err = s.shutdown(httpSrv, grpcSrv, server, &workers); err != nil {
+		s.logger.Error(err)
+		os.Exit(1)
+	}
+}
+
+// shutdown gives open streams half of SHUTDOWN_TIMEOUT to drain, so the http server, the workers and the pool keep time to stop.
+func (s *microservice) shutdown(httpSrv *http.Server, grpcSrv *grpc.Server, server *grpcServer.Server, workers *sync.WaitGroup) error {
+	timeout := time.Duration(s.conf.ShutdownTimeout) * time.Second
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	server.Shutdown()
+	grpcCtx, grpcCancel := context.WithTimeout(ctx, timeout/2)
+	grpcUtil.StopGrpcServer(grpcCtx, grpcSrv)
+	grpcCancel()
+
+	if err := httpSrv.Shutdown(ctx); err != nil {
+		s.logger.Error(err)
+		_ = httpSrv.Close()
+	}
+	if !waitFor(ctx, workers.Wait) {
+		s.logger.Error("update workers did not stop in time")
+	}
+	if !waitFor(ctx, s.conn.Close) || ctx.Err() != nil {
+		return errShutdownDeadline
+	}
+	s.logger.Info("shutdown completed")
+	return nil
 }
 
-func (s *microservice) performDbMigrationIfNeeded() error {
-	return s.migration.MigrateIfNeeded()
+func waitFor(ctx context.Context, fn func()) bool {
+	done := make(chan struct{})
+	go func() {
+		fn()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-ctx.Done():
+		return false
+	}
 }
 
-func (s *microservice) runHttpServer(wg *sync.WaitGroup) {
-	defer wg.Done()
+func (s *microservice) performDbMigration() error {
+	return s.migration.Up()
+}
 
-	httpUtil.RunHttpServer(s.conf.HttpUrl, router.Router(nil), s.logger)
+func (s *microservice) newHttpServer() *http.Server {
+	candleService := sport_line.NewCandleService(s.sportLineQueryService)
+	routes := router.Router(s.sportLineQueryService, s.eventQueryService, candleService, s.sportRegistry, s.logger)
+	return &http.Server{Addr: s.conf.HttpUrl, Handler: routes}
 }
 
-func (s *microservice) runGrpcServer(wg *sync.WaitGroup) {
-	defer wg.Done()
-	sportLineService := sport_line.NewSportLineService(s.sportLineQueryService)
+func (s *microservice) newGrpcServer() (*grpc.Server, *grpcServer.Server) {
+	sportLineService := sport_line.NewSportLineService(s.fanOutEngine, s.fanOutEngine, s.logger)
+	candleService := sport_line.NewCandleService(s.sportLineQueryService)
 
-	server := grpcServer.NewServer(sportLineService, s.logger)
+	sessionGracePeriod := time.Duration(s.conf.SessionGracePeriod) * time.Second
+	intervalBounds := grpcServer.IntervalBounds{
+		Min: time.Duration(s.conf.MinUpdateInterval) * time.Millisecond,
+		Max: time.Duration(s.conf.MaxUpdateInterval) * time.Millisecond,
+	}
+	overflowPolicy, err := grpcServer.NewOverflowPolicy(s.conf.SendOverflowPolicy)
+	if err != nil {
+		s.logger.Fatal(err)
+	}
+	sendBuffer := grpcServer.BufferConfig{Size: s.conf.SendBufferSize, Policy: overflowPolicy}
+	server := grpcServer.NewServer(sportLineService, candleService, s.sportLineQueryService, s.lineChanges, sessionGracePeriod, intervalBounds, sendBuffer, s.conf.AdminToken, s.sportRegistry, s.logger)
 
 	grpcSrv := grpc.NewServer()
 	pb.RegisterKiddyLineProcessorServer(grpcSrv, server)
-
-	grpcUtil.RunGrpcServer(s.logger, s.conf.GrpcUrl, grpcSrv)
+	return grpcSrv, server
 }
 
-func (s *microservice) runSpotLineUpdateWorkers() {
-	for _, sportType := range commonDomain.SupportSports {
-		go s.runUpdateSportLineWorker(sportType)
+func (s *microservice) runSpotLineUpdateWorkers(ctx context.Context, workers *sync.WaitGroup) {
+	sleepDuration := time.Duration(s.conf.UpdatePeriod) * time.Second
+
+	for {
+		for _, sportType := range s.sportRegistry.Sports() {
+			if _, isRunning := s.updateWorkers.LoadOrStore(sportType, struct{}{}); !isRunning {
+				workers.Add(1)
+				go s.runUpdateSportLineWorker(ctx, sportType, workers)
+			}
+		}
+		if !sleep(ctx, sleepDuration) {
+			return
+		}
 	}
 }
 
-func (s *microservice) runUpdateSportLineWorker(sportType commonDomain.SportType) {
+func (s *microservice) runUpdateSportLineWorker(ctx context.Context, sportType commonDomain.SportType, workers *sync.WaitGroup) {
+	defer workers.Done()
+	defer s.updateWorkers.Delete(sportType)
 	sleepDuration := time.Duration(s.conf.UpdatePeriod) * time.Second
 
-	var err error
-	for {
-		s.updateSportLine(sportType, &err)
-		time.Sleep(sleepDuration)
+	for s.sportRegistry.IsSupported(sportType) {
+		s.updateSportLine(sportType)
+		if !sleep(ctx, sleepDuration) {
+			return
+		}
 	}
 }
 
-func (s *microservice) updateSportLine(sportType commonDomain.SportType, err *error) {
-	*err = s.sportLinesUpdateService.Update(sportType)
-	if *err != nil {
+func (s *microservice) updateSportLine(sportType commonDomain.SportType) {
+	if err := s.sportLinesUpdateService.Update(sportType); err != nil {
+		s.logger.Error(err)
+	}
+	if err := s.sportLinesUpdateService.UpdateEvents(sportType); err != nil {
 		s.logger.Error(err)
 	}
 }
+
+func sleep(ctx context.Context, duration time.Duration) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(duration):
+		return true
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"github.com/col3name/lines/pkg/common/application/logger"
+	"github.com/col3name/lines/pkg/common/infrastructure/logrusLogger"
+	commonPostgres "github.com/col3name/lines/pkg/common/infrastructure/postgres"
+	"os"
+	"text/tabwriter"
+)
+
+type linesCtl struct {
+	dbUrl      string
+	grpcUrl    string
+	adminToken string
+	logger     logger.Logger
+	conn       commonPostgres.PgxPoolIface
+}
+
+func newLinesCtl(dbUrl, grpcUrl, adminToken string) *linesCtl {
+	return &linesCtl{dbUrl: dbUrl, grpcUrl: grpcUrl, adminToken: adminToken, logger: logrusLogger.New()}
+}
+
+func (c *linesCtl) db() commonPostgres.PgxPoolIface {
+	if c.conn == nil {
+		c.conn = commonPostgres.SetupDbConnection(c.dbUrl, c.logger)
+	}
+	return c.conn
+}
+
+func (c *linesCtl) close() {
+	if c.conn != nil {
+		c.conn.Close()
+		c.conn = nil
+	}
+}
+
+func newTable() *tabwriter.Writer {
+	return tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+}
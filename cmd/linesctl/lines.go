@@ -0,0 +1,88 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/col3name/lines/pkg/common/domain"
+	commonQuery "github.com/col3name/lines/pkg/common/infrastructure/postgres/query"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/infrastructure/postgres/query"
+	"time"
+)
+
+const defaultHistoryLimit = 50
+
+func listLines(ctl *linesCtl, _ []string) error {
+	sports, err := commonQuery.NewSportQueryService(ctl.db(), ctl.logger).GetSports()
+	if err != nil {
+		return err
+	}
+	if len(sports) == 0 {
+		fmt.Println("no sports")
+		return nil
+	}
+
+	lines, err := query.NewSportLineQueryService(ctl.db(), ctl.logger).GetLinesBySportTypes(sports)
+	if err != nil {
+		return err
+	}
+
+	table := newTable()
+	fmt.Fprintln(table, "SPORT\tLINE")
+	for _, line := range lines {
+		fmt.Fprintf(table, "%s\t%s\n", line.Type, line.Score)
+	}
+	return table.Flush()
+}
+
+func lineHistory(ctl *linesCtl, args []string) error {
+	flags := flag.NewFlagSet("lines history", flag.ContinueOnError)
+	from := flags.String("from", "", "start of the range, RFC3339 (default 24h ago)")
+	to := flags.String("to", "", "end of the range, RFC3339 (default now)")
+	limit := flags.Int("limit", defaultHistoryLimit, "page size")
+	cursor := flags.String("cursor", "", "cursor of the next page")
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+	if flags.NArg() != 1 {
+		return fmt.Errorf("%w: lines history expects a sport", errUsage)
+	}
+
+	sportType, err := domain.ParseSportType(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	toTime, err := parseTime(*to, time.Now())
+	if err != nil {
+		return err
+	}
+	fromTime, err := parseTime(*from, toTime.Add(-24*time.Hour))
+	if err != nil {
+		return err
+	}
+
+	page, err := query.NewSportLineQueryService(ctl.db(), ctl.logger).GetLineHistory(sportType, fromTime, toTime, *limit, *cursor)
+	if err != nil {
+		return err
+	}
+
+	table := newTable()
+	fmt.Fprintln(table, "ID\tLINE\tFETCHED AT\tINGESTED AT")
+	for _, record := range page.Records {
+		fmt.Fprintf(table, "%d\t%s\t%s\t%s\n", record.ID, record.Line.Score,
+			record.FetchedAt.Format(time.RFC3339), record.IngestedAt.Format(time.RFC3339))
+	}
+	if err = table.Flush(); err != nil {
+		return err
+	}
+	if page.NextCursor != "" {
+		fmt.Println("next cursor:", page.NextCursor)
+	}
+	return nil
+}
+
+func parseTime(value string, defaultValue time.Time) (time.Time, error) {
+	if value == "" {
+		return defaultValue, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
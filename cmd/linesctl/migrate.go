@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"github.com/col3name/lines/data/migrations/pg"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/infrastructure/postgres/repo"
+	"time"
+)
+
+func (c *linesCtl) migrationService() (pg.MigrationService, error) {
+	return pg.NewMigrationService(repo.NewUnitOfWork(c.db(), c.logger))
+}
+
+func migrateUp(ctl *linesCtl, _ []string) error {
+	migrationService, err := ctl.migrationService()
+	if err != nil {
+		return err
+	}
+	if err = migrationService.Up(); err != nil {
+		return err
+	}
+	return migrateStatus(ctl, nil)
+}
+
+func migrateDown(ctl *linesCtl, _ []string) error {
+	migrationService, err := ctl.migrationService()
+	if err != nil {
+		return err
+	}
+	if err = migrationService.Down(); err != nil {
+		return err
+	}
+	return migrateStatus(ctl, nil)
+}
+
+func migrateStatus(ctl *linesCtl, _ []string) error {
+	migrationService, err := ctl.migrationService()
+	if err != nil {
+		return err
+	}
+	statuses, err := migrationService.Status()
+	if err != nil {
+		return err
+	}
+
+	table := newTable()
+	fmt.Fprintln(table, "VERSION\tNAME\tAPPLIED AT")
+	for _, status := range statuses {
+		appliedAt := "pending"
+		if status.AppliedAt != nil {
+			appliedAt = status.AppliedAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(table, "%04d\t%s\t%s\n", status.Version, status.Name, appliedAt)
+	}
+	return table.Flush()
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"github.com/col3name/lines/pkg/common/domain"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/application/service"
+	domainRepo "github.com/col3name/lines/pkg/kiddy-line-processor/domain/repo"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/infrastructure/postgres/repo"
+)
+
+type sportChange func(sportRepo domainRepo.SportRepo, sportType domain.SportType) error
+
+func addSport(ctl *linesCtl, args []string) error {
+	return changeSport(ctl, args, "added", domainRepo.SportRepo.Add)
+}
+
+func removeSport(ctl *linesCtl, args []string) error {
+	return changeSport(ctl, args, "removed", domainRepo.SportRepo.Remove)
+}
+
+func changeSport(ctl *linesCtl, args []string, action string, change sportChange) error {
+	if len(args) != 1 {
+		return fmt.Errorf("%w: expected exactly one sport", errUsage)
+	}
+	sportType, err := domain.ParseSportType(args[0])
+	if err != nil {
+		return err
+	}
+
+	var changeErr error
+	err = repo.NewUnitOfWork(ctl.db(), ctl.logger).Execute(func(provider service.RepositoryProvider) error {
+		changeErr = change(provider.SportRepo(), sportType)
+		return changeErr
+	})
+	if changeErr != nil {
+		return changeErr
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Println(sportType, action)
+	return nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	grpcServer "github.com/col3name/lines/pkg/kiddy-line-processor/infrastructure/transport/grpc"
+	pb "github.com/col3name/lines/pkg/kiddy-line-processor/infrastructure/transport/grpc/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"sort"
+	"strings"
+	"time"
+)
+
+const grpcTimeout = 10 * time.Second
+
+func listSubscriptions(ctl *linesCtl, _ []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), grpcTimeout)
+	defer cancel()
+
+	conn, err := grpc.DialContext(ctx, ctl.grpcUrl, grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithBlock())
+	if err != nil {
+		return fmt.Errorf("connect to %s: %w", ctl.grpcUrl, err)
+	}
+	defer conn.Close()
+
+	ctx = metadata.AppendToOutgoingContext(ctx, grpcServer.AdminTokenHeader, ctl.adminToken)
+	response, err := pb.NewKiddyLineProcessorClient(conn).ListSubscriptions(ctx, &pb.ListSubscriptionsRequest{})
+	if err != nil {
+		return err
+	}
+	if len(response.Subscriptions) == 0 {
+		fmt.Println("no subscriptions")
+		return nil
+	}
+
+	table := newTable()
+	fmt.Fprintln(table, "CLIENT\tSUBSCRIPTION\tSTATE\tMODE\tINTERVAL\tDELTA\tSPORTS\tMARKETS\tEVENTS\tODDS\tQUEUED\tDROPPED\tCOALESCED")
+	for _, sub := range response.Subscriptions {
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%d\t%d\t%d\n", sub.ClientId, subscriptionName(sub.SubscriptionId),
+			state(sub.Connected), sub.DeliveryMode, interval(sub), delta(sub), join(sub.Sports), join(sub.Markets), join(sub.EventIds), sub.OddsFormat,
+			sub.Queued, sub.Dropped, sub.Coalesced)
+	}
+	return table.Flush()
+}
+
+func interval(sub *pb.Subscription) string {
+	result := (time.Duration(sub.IntervalMs) * time.Millisecond).String()
+	sports := make([]string, 0, len(sub.SportIntervalsMs))
+	for sport := range sub.SportIntervalsMs {
+		sports = append(sports, sport)
+	}
+	sort.Strings(sports)
+	for _, sport := range sports {
+		result += "," + sport + "=" + (time.Duration(sub.SportIntervalsMs[sport]) * time.Millisecond).String()
+	}
+	return result
+}
+
+func delta(sub *pb.Subscription) string {
+	if sub.DeltaThreshold == "" {
+		return sub.DeltaMode
+	}
+	return sub.DeltaMode + ">" + sub.DeltaThreshold
+}
+
+func subscriptionName(subscriptionId string) string {
+	if subscriptionId == "" {
+		return "-"
+	}
+	return subscriptionId
+}
+
+func state(connected bool) string {
+	if connected {
+		return "connected"
+	}
+	return "detached"
+}
+
+func join(values []string) string {
+	if len(values) == 0 {
+		return "-"
+	}
+	return strings.Join(values, ",")
+}
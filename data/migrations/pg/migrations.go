@@ -1,40 +1,202 @@
 package pg
 
 import (
-	"github.com/col3name/lines/pkg/common/application/errors"
-	commonDomain "github.com/col3name/lines/pkg/common/domain"
+	"embed"
+	"errors"
+	"fmt"
 	"github.com/col3name/lines/pkg/kiddy-line-processor/application/service"
-	domainQuery "github.com/col3name/lines/pkg/kiddy-line-processor/domain/query"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/domain/model"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+//go:embed sql/*.sql
+var migrationFiles embed.FS
+
+var migrationFileRegexp = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+var (
+	ErrNoAppliedMigrations = errors.New("no applied migrations")
+	ErrUnknownMigration    = errors.New("unknown migration")
 )
 
 type MigrationService interface {
-	MigrateIfNeeded() error
+	Up() error
+	Down() error
+	Status() ([]*model.MigrationStatus, error)
 }
 
 type migrationService struct {
-	sportLineQueryService domainQuery.SportLineQueryService
-	uow                   service.UnitOfWork
+	uow        service.UnitOfWork
+	migrations []*model.Migration
+}
+
+func NewMigrationService(uow service.UnitOfWork) (MigrationService, error) {
+	sqlFiles, err := fs.Sub(migrationFiles, "sql")
+	if err != nil {
+		return nil, err
+	}
+	migrations, err := LoadMigrations(sqlFiles)
+	if err != nil {
+		return nil, err
+	}
+	return &migrationService{uow: uow, migrations: migrations}, nil
 }
 
-func NewMigrationService(sportLineQueryService domainQuery.SportLineQueryService, uow service.UnitOfWork) MigrationService {
-	return &migrationService{
-		sportLineQueryService: sportLineQueryService,
-		uow:                   uow,
+func LoadMigrations(files fs.FS) ([]*model.Migration, error) {
+	entries, err := fs.ReadDir(files, ".")
+	if err != nil {
+		return nil, err
 	}
+
+	byVersion := make(map[int]*model.Migration)
+	for _, entry := range entries {
+		match := migrationFileRegexp.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+		version, err := strconv.Atoi(match[1])
+		if err != nil {
+			return nil, err
+		}
+		content, err := fs.ReadFile(files, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &model.Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		} else if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has conflicting names %s and %s", version, migration.Name, match[2])
+		}
+		if match[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]*model.Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s must have up and down files", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
 }
 
-func (s *migrationService) MigrateIfNeeded() error {
-	defaultSubscriptions := []commonDomain.SportType{commonDomain.Baseball}
-	_, err := s.sportLineQueryService.GetLinesBySportTypes(defaultSubscriptions)
-	if err == nil {
-		return nil
+func (s *migrationService) Up() error {
+	for _, migration := range s.migrations {
+		migration := migration
+		err := s.uow.Execute(func(provider service.RepositoryProvider) error {
+			migrationRepo := provider.MigrationRepo()
+			applied, err := s.lockAndGetApplied(provider)
+			if err != nil {
+				return err
+			}
+			if _, ok := applied[migration.Version]; ok {
+				return nil
+			}
+			return migrationRepo.Apply(migration)
+		})
+		if err != nil {
+			return err
+		}
 	}
-	if err != errors.ErrTableNotExist {
+	return nil
+}
+
+func (s *migrationService) Down() error {
+	var (
+		reverted *model.Migration
+		unknown  bool
+	)
+	err := s.uow.Execute(func(provider service.RepositoryProvider) error {
+		applied, err := s.lockAndGetApplied(provider)
+		if err != nil {
+			return err
+		}
+		last := -1
+		for version := range applied {
+			if version > last {
+				last = version
+			}
+		}
+		if last < 0 {
+			return nil
+		}
+		reverted = s.findMigration(last)
+		if reverted == nil {
+			unknown = true
+			return nil
+		}
+		return provider.MigrationRepo().Revert(reverted)
+	})
+	if err != nil {
 		return err
 	}
+	if unknown {
+		return ErrUnknownMigration
+	}
+	if reverted == nil {
+		return ErrNoAppliedMigrations
+	}
+	return nil
+}
 
-	return s.uow.Execute(func(provider service.RepositoryProvider) error {
-		migrationRepo := provider.MigrationRepo()
-		return migrationRepo.Migrate()
+func (s *migrationService) Status() ([]*model.MigrationStatus, error) {
+	var applied map[int]*model.AppliedMigration
+	err := s.uow.Execute(func(provider service.RepositoryProvider) error {
+		var err error
+		applied, err = s.lockAndGetApplied(provider)
+		return err
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]*model.MigrationStatus, 0, len(s.migrations))
+	for _, migration := range s.migrations {
+		status := &model.MigrationStatus{Version: migration.Version, Name: migration.Name}
+		if appliedMigration, ok := applied[migration.Version]; ok {
+			status.AppliedAt = &appliedMigration.AppliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+func (s *migrationService) lockAndGetApplied(provider service.RepositoryProvider) (map[int]*model.AppliedMigration, error) {
+	migrationRepo := provider.MigrationRepo()
+	if err := migrationRepo.Lock(); err != nil {
+		return nil, err
+	}
+	if err := migrationRepo.CreateSchemaMigrationsTable(); err != nil {
+		return nil, err
+	}
+	migrations, err := migrationRepo.GetAppliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+	applied := make(map[int]*model.AppliedMigration, len(migrations))
+	for _, migration := range migrations {
+		applied[migration.Version] = migration
+	}
+	return applied, nil
+}
+
+func (s *migrationService) findMigration(version int) *model.Migration {
+	for _, migration := range s.migrations {
+		if migration.Version == version {
+			return migration
+		}
+	}
+	return nil
 }
@@ -0,0 +1,191 @@
+package pg
+
+import (
+	"github.com/col3name/lines/pkg/kiddy-line-processor/application/service"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/domain/model"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/domain/repo"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+type mockMigrationRepo struct {
+	applied    map[int]*model.AppliedMigration
+	countLocks int
+	calls      []string
+}
+
+func (m *mockMigrationRepo) Lock() error {
+	m.countLocks++
+	return nil
+}
+
+func (m *mockMigrationRepo) CreateSchemaMigrationsTable() error {
+	return nil
+}
+
+func (m *mockMigrationRepo) GetAppliedMigrations() ([]*model.AppliedMigration, error) {
+	result := make([]*model.AppliedMigration, 0, len(m.applied))
+	for _, migration := range m.applied {
+		result = append(result, migration)
+	}
+	return result, nil
+}
+
+func (m *mockMigrationRepo) Apply(migration *model.Migration) error {
+	m.calls = append(m.calls, "up "+migration.Name)
+	m.applied[migration.Version] = &model.AppliedMigration{Version: migration.Version, Name: migration.Name, AppliedAt: time.Now()}
+	return nil
+}
+
+func (m *mockMigrationRepo) Revert(migration *model.Migration) error {
+	m.calls = append(m.calls, "down "+migration.Name)
+	delete(m.applied, migration.Version)
+	return nil
+}
+
+type mockRepositoryProvider struct {
+	migrationRepo *mockMigrationRepo
+}
+
+func (m *mockRepositoryProvider) MigrationRepo() repo.MigrationRepo {
+	return m.migrationRepo
+}
+
+func (m *mockRepositoryProvider) SportLineRepo() repo.SportLineRepo {
+	return nil
+}
+
+func (m *mockRepositoryProvider) SportLineHistoryRepo() repo.SportLineHistoryRepo {
+	return nil
+}
+
+func (m *mockRepositoryProvider) NotifyRepo() repo.NotifyRepo {
+	return nil
+}
+
+func (m *mockRepositoryProvider) SportRepo() repo.SportRepo {
+	return nil
+}
+
+func (m *mockRepositoryProvider) EventRepo() repo.EventRepo {
+	return nil
+}
+
+type mockUnitOfWork struct {
+	provider *mockRepositoryProvider
+}
+
+func (m *mockUnitOfWork) Execute(fn service.Job) error {
+	return fn(m.provider)
+}
+
+func TestLoadMigrations(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    fstest.MapFS
+		expected []int
+		isError  bool
+	}{
+		{
+			name: "sorted by version and ignores other files",
+			files: fstest.MapFS{
+				"0002_second.up.sql":   {Data: []byte("CREATE TABLE b ();")},
+				"0002_second.down.sql": {Data: []byte("DROP TABLE b;")},
+				"0001_first.up.sql":    {Data: []byte("CREATE TABLE a ();")},
+				"0001_first.down.sql":  {Data: []byte("DROP TABLE a;")},
+				"README.md":            {Data: []byte("docs")},
+			},
+			expected: []int{1, 2},
+		},
+		{
+			name: "missing down file",
+			files: fstest.MapFS{
+				"0001_first.up.sql": {Data: []byte("CREATE TABLE a ();")},
+			},
+			isError: true,
+		},
+		{
+			name: "conflicting names for one version",
+			files: fstest.MapFS{
+				"0001_first.up.sql":   {Data: []byte("CREATE TABLE a ();")},
+				"0001_other.down.sql": {Data: []byte("DROP TABLE a;")},
+			},
+			isError: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			migrations, err := LoadMigrations(test.files)
+			if test.isError {
+				assert.Error(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			versions := make([]int, 0, len(migrations))
+			for _, migration := range migrations {
+				versions = append(versions, migration.Version)
+			}
+			assert.Equal(t, test.expected, versions)
+		})
+	}
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	migrationRepo := &mockMigrationRepo{applied: make(map[int]*model.AppliedMigration)}
+	migrationService, err := NewMigrationService(&mockUnitOfWork{provider: &mockRepositoryProvider{migrationRepo: migrationRepo}})
+	assert.Nil(t, err)
+
+	statuses, err := migrationService.Status()
+	assert.Nil(t, err)
+	assert.Equal(t, 5, len(statuses))
+	for i, status := range statuses {
+		assert.Equal(t, i+1, status.Version)
+		assert.Nil(t, status.AppliedAt)
+	}
+}
+
+func TestUpAndDown(t *testing.T) {
+	migrations := []*model.Migration{
+		{Version: 1, Name: "first", Up: "up 1", Down: "down 1"},
+		{Version: 2, Name: "second", Up: "up 2", Down: "down 2"},
+		{Version: 3, Name: "third", Up: "up 3", Down: "down 3"},
+	}
+	migrationRepo := &mockMigrationRepo{applied: map[int]*model.AppliedMigration{
+		1: {Version: 1, Name: "first"},
+	}}
+	migrationService := &migrationService{
+		uow:        &mockUnitOfWork{provider: &mockRepositoryProvider{migrationRepo: migrationRepo}},
+		migrations: migrations,
+	}
+
+	assert.Nil(t, migrationService.Up())
+	assert.Equal(t, []string{"up second", "up third"}, migrationRepo.calls)
+	assert.Equal(t, len(migrations), migrationRepo.countLocks)
+
+	statuses, err := migrationService.Status()
+	assert.Nil(t, err)
+	for _, status := range statuses {
+		assert.NotNil(t, status.AppliedAt)
+	}
+
+	assert.Nil(t, migrationService.Down())
+	assert.Nil(t, migrationService.Down())
+	assert.Nil(t, migrationService.Down())
+	assert.Equal(t, ErrNoAppliedMigrations, migrationService.Down())
+	assert.Equal(t, []string{"up second", "up third", "down third", "down second", "down first"}, migrationRepo.calls)
+}
+
+func TestDownUnknownMigration(t *testing.T) {
+	migrationRepo := &mockMigrationRepo{applied: map[int]*model.AppliedMigration{
+		7: {Version: 7, Name: "from newer release"},
+	}}
+	migrationService := &migrationService{
+		uow:        &mockUnitOfWork{provider: &mockRepositoryProvider{migrationRepo: migrationRepo}},
+		migrations: []*model.Migration{{Version: 1, Name: "first", Up: "up 1", Down: "down 1"}},
+	}
+
+	assert.Equal(t, ErrUnknownMigration, migrationService.Down())
+	assert.Empty(t, migrationRepo.calls)
+}
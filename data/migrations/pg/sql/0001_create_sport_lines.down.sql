@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS sport_lines;
+DROP TABLE IF EXISTS sports;
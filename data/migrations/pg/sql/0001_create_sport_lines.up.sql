@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS sports
+(
+    name VARCHAR(255) PRIMARY KEY UNIQUE NOT NULL
+);
+
+INSERT INTO sports (name)
+VALUES ('baseball'),
+       ('soccer'),
+       ('football')
+ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS sport_lines
+(
+    id         UUID PRIMARY KEY UNIQUE NOT NULL,
+    sport_type VARCHAR(255)            NOT NULL,
+    score      REAL                    NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS sport_lines_sport_type_uindex ON sport_lines (sport_type);
+
+INSERT INTO sport_lines (id, sport_type, score)
+VALUES ('ce267749-dec9-4d39-ad81-8b4cd8c381d2', 'baseball', 1.0),
+       ('ba9babe8-06d4-450e-8e9a-66b7512b5bd2', 'soccer', 1.0),
+       ('4b9d52e2-1473-4cdb-bba8-c1c1cac933f5', 'football', 1.0)
+ON CONFLICT DO NOTHING;
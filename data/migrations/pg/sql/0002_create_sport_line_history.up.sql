@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS sport_line_history
+(
+    id          BIGSERIAL PRIMARY KEY    NOT NULL,
+    sport_type  VARCHAR(255)             NOT NULL,
+    score       REAL                     NOT NULL,
+    fetched_at  TIMESTAMP WITH TIME ZONE NOT NULL,
+    ingested_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS sport_line_history_sport_type_fetched_at_index
+    ON sport_line_history (sport_type, fetched_at);
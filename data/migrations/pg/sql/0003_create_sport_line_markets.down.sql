@@ -0,0 +1 @@
+DROP TABLE IF EXISTS sport_line_markets;
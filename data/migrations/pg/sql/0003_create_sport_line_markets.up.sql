@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS sport_line_markets
+(
+    sport_type VARCHAR(255) NOT NULL,
+    market     VARCHAR(255) NOT NULL,
+    point      REAL         NOT NULL,
+    selection  VARCHAR(255) NOT NULL,
+    price      REAL         NOT NULL,
+    PRIMARY KEY (sport_type, market, selection)
+);
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS events
+(
+    id         VARCHAR(255) PRIMARY KEY UNIQUE NOT NULL,
+    sport_type VARCHAR(255)                    NOT NULL,
+    home_team  VARCHAR(255)                    NOT NULL,
+    away_team  VARCHAR(255)                    NOT NULL,
+    start_time TIMESTAMP WITH TIME ZONE        NOT NULL,
+    status     VARCHAR(32)                     NOT NULL,
+    line       REAL                            NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS events_sport_type_start_time_index ON events (sport_type, start_time);
@@ -0,0 +1,5 @@
+ALTER TABLE events ALTER COLUMN line TYPE REAL;
+ALTER TABLE sport_line_markets ALTER COLUMN price TYPE REAL;
+ALTER TABLE sport_line_markets ALTER COLUMN point TYPE REAL;
+ALTER TABLE sport_line_history ALTER COLUMN score TYPE REAL;
+ALTER TABLE sport_lines ALTER COLUMN score TYPE REAL;
@@ -0,0 +1,5 @@
+ALTER TABLE sport_lines ALTER COLUMN score TYPE NUMERIC;
+ALTER TABLE sport_line_history ALTER COLUMN score TYPE NUMERIC;
+ALTER TABLE sport_line_markets ALTER COLUMN point TYPE NUMERIC;
+ALTER TABLE sport_line_markets ALTER COLUMN price TYPE NUMERIC;
+ALTER TABLE events ALTER COLUMN line TYPE NUMERIC;
@@ -0,0 +1,83 @@
+package sport_registry
+
+import (
+	"context"
+	"github.com/col3name/lines/pkg/common/application/logger"
+	"github.com/col3name/lines/pkg/common/domain"
+	"github.com/col3name/lines/pkg/common/domain/query"
+	"sync"
+	"time"
+)
+
+const defaultRetryDelay = time.Second
+
+type SportRegistry interface {
+	domain.SportRegistry
+	Refresh() error
+	Run(ctx context.Context)
+}
+
+type sportRegistry struct {
+	queryService  query.SportQueryService
+	refreshPeriod time.Duration
+	retryDelay    time.Duration
+	logger        logger.Logger
+
+	mu     sync.RWMutex
+	sports []domain.SportType
+}
+
+func NewSportRegistry(queryService query.SportQueryService, refreshPeriod time.Duration, logger logger.Logger) *sportRegistry {
+	return &sportRegistry{
+		queryService:  queryService,
+		refreshPeriod: refreshPeriod,
+		retryDelay:    defaultRetryDelay,
+		logger:        logger,
+		sports:        make([]domain.SportType, 0),
+	}
+}
+
+func (r *sportRegistry) IsSupported(sportType domain.SportType) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, sport := range r.sports {
+		if sport == sportType {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *sportRegistry) Sports() []domain.SportType {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	sports := make([]domain.SportType, len(r.sports))
+	copy(sports, r.sports)
+	return sports
+}
+
+func (r *sportRegistry) Refresh() error {
+	sports, err := r.queryService.GetSports()
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	r.sports = sports
+	r.mu.Unlock()
+	return nil
+}
+
+func (r *sportRegistry) Run(ctx context.Context) {
+	for {
+		delay := r.refreshPeriod
+		if err := r.Refresh(); err != nil {
+			r.logger.Error(err)
+			delay = r.retryDelay
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package sport_registry
+
+import (
+	"context"
+	"errors"
+	"github.com/col3name/lines/pkg/common/domain"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/application/fake"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+type mockSportQueryService struct {
+	FakeGetSports func() ([]domain.SportType, error)
+	CountCall     int
+}
+
+func (m *mockSportQueryService) GetSports() ([]domain.SportType, error) {
+	m.CountCall++
+	if m.FakeGetSports == nil {
+		return []domain.SportType{}, nil
+	}
+	return m.FakeGetSports()
+}
+
+type expectedRegistry struct {
+	sports    []domain.SportType
+	supported map[domain.SportType]bool
+}
+
+func TestSportRegistry(t *testing.T) {
+	tests := []struct {
+		name         string
+		queryService *mockSportQueryService
+		expected     *expectedRegistry
+	}{
+		{
+			name: "failed load sports",
+			queryService: &mockSportQueryService{
+				FakeGetSports: func() ([]domain.SportType, error) {
+					return nil, errors.New("fake error")
+				},
+			},
+			expected: &expectedRegistry{
+				sports:    []domain.SportType{},
+				supported: map[domain.SportType]bool{domain.Baseball: false},
+			},
+		},
+		{
+			name: "load sports from catalogue",
+			queryService: &mockSportQueryService{
+				FakeGetSports: func() ([]domain.SportType, error) {
+					return []domain.SportType{domain.Baseball, "hockey"}, nil
+				},
+			},
+			expected: &expectedRegistry{
+				sports: []domain.SportType{domain.Baseball, "hockey"},
+				supported: map[domain.SportType]bool{
+					domain.Baseball: true,
+					"hockey":        true,
+					domain.Soccer:   false,
+				},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			registry := NewSportRegistry(test.queryService, time.Minute, fake.Logger{})
+			expected := test.expected
+			_ = registry.Refresh()
+
+			assert.Equal(t, expected.sports, registry.Sports())
+			for sportType, supported := range expected.supported {
+				assert.Equal(t, supported, registry.IsSupported(sportType))
+			}
+			assert.Equal(t, 1, test.queryService.CountCall)
+		})
+	}
+}
+
+func TestSportRegistryRetriesFailedRefresh(t *testing.T) {
+	calls := make(chan int, 10)
+	count := 0
+	queryService := &mockSportQueryService{
+		FakeGetSports: func() ([]domain.SportType, error) {
+			count++
+			calls <- count
+			if count == 1 {
+				return nil, errors.New("fake error")
+			}
+			return []domain.SportType{domain.Baseball}, nil
+		},
+	}
+	registry := NewSportRegistry(queryService, time.Hour, fake.Logger{})
+	registry.retryDelay = time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go registry.Run(ctx)
+
+	assert.Equal(t, 1, <-calls)
+	assert.Equal(t, 2, <-calls)
+	assert.Eventually(t, func() bool {
+		return registry.IsSupported(domain.Baseball)
+	}, time.Second, time.Millisecond)
+}
+
+func TestSportRegistryPicksUpNewSports(t *testing.T) {
+	sports := []domain.SportType{domain.Baseball}
+	queryService := &mockSportQueryService{
+		FakeGetSports: func() ([]domain.SportType, error) {
+			return sports, nil
+		},
+	}
+	registry := NewSportRegistry(queryService, time.Minute, fake.Logger{})
+	assert.Nil(t, registry.Refresh())
+	assert.False(t, registry.IsSupported("hockey"))
+
+	sports = append(sports, "hockey")
+	assert.False(t, registry.IsSupported("hockey"))
+	assert.Nil(t, registry.Refresh())
+	assert.True(t, registry.IsSupported("hockey"))
+	assert.Equal(t, []domain.SportType{domain.Baseball, "hockey"}, registry.Sports())
+}
@@ -0,0 +1,45 @@
+package domain
+
+import (
+	"errors"
+	"github.com/shopspring/decimal"
+	"strings"
+	"time"
+)
+
+type EventStatus string
+
+const (
+	EventScheduled EventStatus = "scheduled"
+	EventLive      EventStatus = "live"
+	EventFinished  EventStatus = "finished"
+)
+
+var ErrUnsupportedEventStatus = errors.New("unsupported event status")
+
+func (s EventStatus) String() string {
+	return string(s)
+}
+
+func NewEventStatus(status string) (EventStatus, error) {
+	switch strings.ToLower(status) {
+	case EventScheduled.String():
+		return EventScheduled, nil
+	case EventLive.String():
+		return EventLive, nil
+	case EventFinished.String():
+		return EventFinished, nil
+	default:
+		return "", ErrUnsupportedEventStatus
+	}
+}
+
+type Event struct {
+	ID        string
+	SportType SportType
+	HomeTeam  string
+	AwayTeam  string
+	StartTime time.Time
+	Status    EventStatus
+	Line      decimal.Decimal
+}
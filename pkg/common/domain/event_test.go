@@ -0,0 +1,27 @@
+package domain
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestEventStatusFromString(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected EventStatus
+		err      error
+	}{
+		{name: "unsupported event status", input: "postponed", expected: "", err: ErrUnsupportedEventStatus},
+		{name: "success from scheduled", input: "scheduled", expected: EventScheduled, err: nil},
+		{name: "success from live in upper case", input: "LIVE", expected: EventLive, err: nil},
+		{name: "success from finished", input: "finished", expected: EventFinished, err: nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res, err := NewEventStatus(test.input)
+			assert.Equal(t, test.err, err)
+			assert.Equal(t, test.expected, res)
+		})
+	}
+}
@@ -2,7 +2,8 @@ package domain
 
 import (
 	"errors"
-	"strconv"
+	"github.com/shopspring/decimal"
+	"regexp"
 	"strings"
 )
 
@@ -14,8 +15,21 @@ const (
 	Soccer   SportType = "soccer"
 )
 
+type MarketType string
+
+const (
+	Moneyline MarketType = "moneyline"
+	Spread    MarketType = "spread"
+	Totals    MarketType = "totals"
+)
+
+var AllMarketTypes = []MarketType{Moneyline, Spread, Totals}
+
 var (
 	ErrUnsupportedSportType   = errors.New("unsupported sport type")
+	ErrInvalidSportType       = errors.New("invalid sport type")
+	ErrSportAlreadyExists     = errors.New("sport already exists")
+	ErrUnsupportedMarketType  = errors.New("unsupported market type")
 	ErrInvalidScore           = errors.New("invalid score")
 	ErrSportLinesDoesNotExist = errors.New("sport line doesn't exist")
 )
@@ -24,35 +38,71 @@ func (s SportType) String() string {
 	return string(s)
 }
 
-func NewSportType(sport string) (SportType, error) {
-	switch strings.ToLower(sport) {
-	case Baseball.String():
-		return Baseball, nil
-	case Soccer.String():
-		return Soccer, nil
-	case Football.String():
-		return Football, nil
-	default:
+var sportTypeRegexp = regexp.MustCompile(`^[a-z][a-z0-9_-]*$`)
+
+func ParseSportType(sport string) (SportType, error) {
+	sportType := SportType(strings.ToLower(sport))
+	if !sportTypeRegexp.MatchString(sportType.String()) {
+		return "", ErrInvalidSportType
+	}
+	return sportType, nil
+}
+
+func NewSportType(sport string, registry SportRegistry) (SportType, error) {
+	sportType := SportType(strings.ToLower(sport))
+	if registry == nil || !registry.IsSupported(sportType) {
 		return "", ErrUnsupportedSportType
 	}
+	return sportType, nil
+}
+
+var DefaultSports = []SportType{Baseball, Football, Soccer}
+
+type SportRegistry interface {
+	IsSupported(sportType SportType) bool
+	Sports() []SportType
+}
+
+func (m MarketType) String() string {
+	return string(m)
+}
+
+func NewMarketType(market string) (MarketType, error) {
+	switch strings.ToLower(market) {
+	case Moneyline.String():
+		return Moneyline, nil
+	case Spread.String():
+		return Spread, nil
+	case Totals.String():
+		return Totals, nil
+	default:
+		return "", ErrUnsupportedMarketType
+	}
+}
+
+type Selection struct {
+	Name  string
+	Price decimal.Decimal
+	Odds  string
 }
 
-var SupportSports = map[string]SportType{
-	"baseball": Baseball,
-	"football": Football,
-	"soccer":   Soccer,
+type Market struct {
+	Type       MarketType
+	Point      decimal.Decimal
+	Selections []*Selection
 }
 
 type SportLine struct {
-	Type  SportType
-	Score float32
+	Type    SportType
+	Score   decimal.Decimal
+	Markets []*Market
 }
 
 func (s *SportLine) SetScore(score string) error {
-	value, err := strconv.ParseFloat(score, 32)
+	value, err := decimal.NewFromString(score)
 	if err != nil {
 		return ErrInvalidScore
 	}
-	s.Score = float32(value)
+	s.Score = value
 	return nil
 }
@@ -1,11 +1,29 @@
 package domain
 
 import (
+	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
 	"testing"
 )
 
+type stubRegistry []SportType
+
+func (r stubRegistry) IsSupported(sportType SportType) bool {
+	for _, sport := range r {
+		if sport == sportType {
+			return true
+		}
+	}
+	return false
+}
+
+func (r stubRegistry) Sports() []SportType {
+	return r
+}
+
 func TestSportTypeFromString(t *testing.T) {
+	registry := stubRegistry{Baseball, Football, Soccer, "hockey"}
+
 	tests := []struct {
 		name     string
 		input    string
@@ -22,6 +40,14 @@ func TestSportTypeFromString(t *testing.T) {
 				err error
 			}{res: "", err: ErrUnsupportedSportType},
 		},
+		{
+			name:  "registered sport in upper case",
+			input: "HOCKEY",
+			expected: struct {
+				res SportType
+				err error
+			}{res: "hockey", err: nil},
+		},
 		{
 			name:  "success from baseball",
 			input: "baseball",
@@ -50,13 +76,19 @@ func TestSportTypeFromString(t *testing.T) {
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
 			expected := test.expected
-			res, err := NewSportType(test.input)
+			res, err := NewSportType(test.input, registry)
 			assert.Equal(t, expected.err, err)
 			assert.Equal(t, expected.res.String(), res.String())
 		})
 	}
 }
 
+func TestSportTypeWithoutRegistry(t *testing.T) {
+	res, err := NewSportType(Baseball.String(), nil)
+	assert.Equal(t, ErrUnsupportedSportType, err)
+	assert.Equal(t, "", res.String())
+}
+
 type inputSportLine struct {
 	in  SportLine
 	val string
@@ -75,23 +107,34 @@ func TestSportLine(t *testing.T) {
 		{
 			name: "invalid score string",
 			input: inputSportLine{
-				in:  SportLine{Baseball, 0.744},
+				in:  SportLine{Type: Baseball, Score: decimal.RequireFromString("0.744")},
 				val: "hello",
 			},
 			expected: expectedSportLine{
 				err: ErrInvalidScore,
-				res: SportLine{Baseball, 0.744},
+				res: SportLine{Type: Baseball, Score: decimal.RequireFromString("0.744")},
+			},
+		},
+		{
+			name: "exact decimal score string",
+			input: inputSportLine{
+				in:  SportLine{Type: Baseball, Score: decimal.RequireFromString("0.744")},
+				val: "0.30000000000000000001",
+			},
+			expected: expectedSportLine{
+				err: nil,
+				res: SportLine{Type: Baseball, Score: decimal.RequireFromString("0.30000000000000000001")},
 			},
 		},
 		{
 			name: "valid score string",
 			input: inputSportLine{
-				in:  SportLine{Baseball, 0.744},
+				in:  SportLine{Type: Baseball, Score: decimal.RequireFromString("0.744")},
 				val: "1.0",
 			},
 			expected: expectedSportLine{
 				err: nil,
-				res: SportLine{Baseball, 1.0},
+				res: SportLine{Type: Baseball, Score: decimal.RequireFromString("1.0")},
 			},
 		},
 	}
@@ -106,3 +149,46 @@ func TestSportLine(t *testing.T) {
 		})
 	}
 }
+
+func TestMarketTypeFromString(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected MarketType
+		err      error
+	}{
+		{name: "unsupported market type", input: "corners", expected: "", err: ErrUnsupportedMarketType},
+		{name: "success from moneyline", input: "moneyline", expected: Moneyline, err: nil},
+		{name: "success from spread in upper case", input: "SPREAD", expected: Spread, err: nil},
+		{name: "success from totals", input: "totals", expected: Totals, err: nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res, err := NewMarketType(test.input)
+			assert.Equal(t, test.err, err)
+			assert.Equal(t, test.expected, res)
+		})
+	}
+}
+
+func TestParseSportType(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected SportType
+		err      error
+	}{
+		{name: "empty sport", input: "", expected: "", err: ErrInvalidSportType},
+		{name: "sport with spaces", input: "ice hockey", expected: "", err: ErrInvalidSportType},
+		{name: "sport starts with digit", input: "3x3", expected: "", err: ErrInvalidSportType},
+		{name: "sport in upper case", input: "HOCKEY", expected: "hockey", err: nil},
+		{name: "sport with dash", input: "table-tennis", expected: "table-tennis", err: nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res, err := ParseSportType(test.input)
+			assert.Equal(t, test.err, err)
+			assert.Equal(t, test.expected, res)
+		})
+	}
+}
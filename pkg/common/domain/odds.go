@@ -0,0 +1,111 @@
+package domain
+
+import (
+	"errors"
+	"github.com/shopspring/decimal"
+	"strings"
+)
+
+type OddsFormat string
+
+const (
+	OddsDecimal     OddsFormat = "decimal"
+	OddsFractional  OddsFormat = "fractional"
+	OddsAmerican    OddsFormat = "american"
+	OddsProbability OddsFormat = "probability"
+)
+
+const probabilityPlaces = 4
+
+var (
+	ErrUnsupportedOddsFormat = errors.New("unsupported odds format")
+	ErrInvalidOdds           = errors.New("invalid odds")
+)
+
+var (
+	one     = decimal.NewFromInt(1)
+	two     = decimal.NewFromInt(2)
+	hundred = decimal.NewFromInt(100)
+)
+
+func (f OddsFormat) String() string {
+	return string(f)
+}
+
+func NewOddsFormat(format string) (OddsFormat, error) {
+	switch strings.ToLower(format) {
+	case "", OddsDecimal.String():
+		return OddsDecimal, nil
+	case OddsFractional.String():
+		return OddsFractional, nil
+	case OddsAmerican.String():
+		return OddsAmerican, nil
+	case OddsProbability.String():
+		return OddsProbability, nil
+	default:
+		return "", ErrUnsupportedOddsFormat
+	}
+}
+
+func (f OddsFormat) Format(price decimal.Decimal) (string, error) {
+	if f == OddsDecimal || f == "" {
+		return price.String(), nil
+	}
+	if !price.GreaterThan(one) {
+		return "", ErrInvalidOdds
+	}
+	switch f {
+	case OddsFractional:
+		return formatFraction(price.Sub(one)), nil
+	case OddsAmerican:
+		if price.GreaterThanOrEqual(two) {
+			return "+" + price.Sub(one).Mul(hundred).Round(0).String(), nil
+		}
+		return hundred.Neg().Div(price.Sub(one)).Round(0).String(), nil
+	case OddsProbability:
+		return one.DivRound(price, probabilityPlaces).String(), nil
+	default:
+		return "", ErrUnsupportedOddsFormat
+	}
+}
+
+func (f OddsFormat) FormatDelta(current, previous decimal.Decimal) (string, error) {
+	if f == OddsDecimal || f == "" {
+		return current.Sub(previous).String(), nil
+	}
+	if !current.GreaterThan(one) || !previous.GreaterThan(one) {
+		return "", ErrInvalidOdds
+	}
+	switch f {
+	case OddsFractional:
+		return formatFraction(current.Sub(previous)), nil
+	case OddsAmerican:
+		return formatSigned(americanScale(current).Sub(americanScale(previous)).Round(0)), nil
+	case OddsProbability:
+		delta := one.DivRound(current, probabilityPlaces).Sub(one.DivRound(previous, probabilityPlaces))
+		return delta.String(), nil
+	default:
+		return "", ErrUnsupportedOddsFormat
+	}
+}
+
+// americanScale maps American odds onto a continuous scale, so that
+// -101 and +100 stay one point apart instead of 201.
+func americanScale(price decimal.Decimal) decimal.Decimal {
+	if price.GreaterThanOrEqual(two) {
+		return price.Sub(one).Mul(hundred)
+	}
+	return hundred.Mul(two).Sub(hundred.Div(price.Sub(one)))
+}
+
+func formatFraction(value decimal.Decimal) string {
+	rat := value.Rat()
+	return rat.Num().String() + "/" + rat.Denom().String()
+}
+
+func formatSigned(value decimal.Decimal) string {
+	if value.IsPositive() {
+		return "+" + value.String()
+	}
+	return value.String()
+}
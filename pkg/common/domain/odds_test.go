@@ -0,0 +1,82 @@
+package domain
+
+import (
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNewOddsFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected OddsFormat
+		err      error
+	}{
+		{name: "empty defaults to decimal", input: "", expected: OddsDecimal, err: nil},
+		{name: "american in upper case", input: "AMERICAN", expected: OddsAmerican, err: nil},
+		{name: "fractional", input: "fractional", expected: OddsFractional, err: nil},
+		{name: "probability", input: "probability", expected: OddsProbability, err: nil},
+		{name: "unsupported odds format", input: "hongkong", expected: "", err: ErrUnsupportedOddsFormat},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res, err := NewOddsFormat(test.input)
+			assert.Equal(t, test.err, err)
+			assert.Equal(t, test.expected, res)
+		})
+	}
+}
+
+func TestOddsFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		format   OddsFormat
+		price    string
+		expected string
+		err      error
+	}{
+		{name: "decimal", format: OddsDecimal, price: "2.5", expected: "2.5"},
+		{name: "fractional", format: OddsFractional, price: "2.5", expected: "3/2"},
+		{name: "fractional evens", format: OddsFractional, price: "2", expected: "1/1"},
+		{name: "american underdog", format: OddsAmerican, price: "2.5", expected: "+150"},
+		{name: "american favourite", format: OddsAmerican, price: "1.5", expected: "-200"},
+		{name: "probability", format: OddsProbability, price: "1.5", expected: "0.6667"},
+		{name: "price not above one", format: OddsAmerican, price: "1", err: ErrInvalidOdds},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res, err := test.format.Format(decimal.RequireFromString(test.price))
+			assert.Equal(t, test.err, err)
+			assert.Equal(t, test.expected, res)
+		})
+	}
+}
+
+func TestOddsFormatDelta(t *testing.T) {
+	tests := []struct {
+		name     string
+		format   OddsFormat
+		current  string
+		previous string
+		expected string
+		err      error
+	}{
+		{name: "decimal", format: OddsDecimal, current: "1.5", previous: "1.75", expected: "-0.25"},
+		{name: "fractional", format: OddsFractional, current: "1.5", previous: "1.75", expected: "-1/4"},
+		{name: "american", format: OddsAmerican, current: "2.7", previous: "2.5", expected: "+20"},
+		{name: "american across evens", format: OddsAmerican, current: "2", previous: "1.99", expected: "+1"},
+		{name: "american unchanged", format: OddsAmerican, current: "1.5", previous: "1.5", expected: "0"},
+		{name: "probability", format: OddsProbability, current: "2", previous: "2.5", expected: "0.1"},
+		{name: "previous price not above one", format: OddsFractional, current: "2", previous: "0.5", err: ErrInvalidOdds},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			current := decimal.RequireFromString(test.current)
+			previous := decimal.RequireFromString(test.previous)
+			res, err := test.format.FormatDelta(current, previous)
+			assert.Equal(t, test.err, err)
+			assert.Equal(t, test.expected, res)
+		})
+	}
+}
@@ -0,0 +1,7 @@
+package query
+
+import "github.com/col3name/lines/pkg/common/domain"
+
+type SportQueryService interface {
+	GetSports() ([]domain.SportType, error)
+}
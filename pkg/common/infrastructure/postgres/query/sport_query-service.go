@@ -0,0 +1,47 @@
+package query
+
+import (
+	"context"
+	appErr "github.com/col3name/lines/pkg/common/application/errors"
+	"github.com/col3name/lines/pkg/common/application/logger"
+	"github.com/col3name/lines/pkg/common/domain"
+	"github.com/col3name/lines/pkg/common/domain/query"
+	"github.com/col3name/lines/pkg/common/infrastructure"
+	"github.com/col3name/lines/pkg/common/infrastructure/postgres"
+	"strings"
+)
+
+type sportQueryService struct {
+	conn   postgres.PgxPoolIface
+	logger logger.Logger
+}
+
+func NewSportQueryService(conn postgres.PgxPoolIface, logger logger.Logger) query.SportQueryService {
+	return &sportQueryService{conn: conn, logger: logger}
+}
+
+func (s *sportQueryService) GetSports() ([]domain.SportType, error) {
+	const sql = "SELECT name FROM sports ORDER BY name;"
+
+	rows, err := s.conn.Query(context.Background(), sql)
+	if err != nil {
+		if strings.Contains(err.Error(), appErr.TableNotExistMessage) {
+			return nil, appErr.ErrTableNotExist
+		}
+		return nil, infrastructure.InternalError(s.logger, err)
+	}
+	defer rows.Close()
+
+	sports := make([]domain.SportType, 0)
+	for rows.Next() {
+		var sport domain.SportType
+		if err = rows.Scan(&sport); err != nil {
+			return nil, infrastructure.InternalError(s.logger, err)
+		}
+		sports = append(sports, sport)
+	}
+	if rows.Err() != nil {
+		return nil, infrastructure.InternalError(s.logger, rows.Err())
+	}
+	return sports, nil
+}
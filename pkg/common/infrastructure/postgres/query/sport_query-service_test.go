@@ -0,0 +1,100 @@
+package query
+
+import (
+	errBase "errors"
+	"github.com/col3name/lines/pkg/common/application/errors"
+	"github.com/col3name/lines/pkg/common/domain"
+	"github.com/col3name/lines/pkg/common/infrastructure/postgres"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/application/fake"
+	"github.com/pashagolub/pgxmock"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type status int
+
+const (
+	tableNotExist status = iota
+	failedQuery
+	failedRowScan
+	ok
+)
+
+type inputGetSports struct {
+	status   status
+	queryErr error
+}
+
+type expectedGetSports struct {
+	sports []domain.SportType
+	err    error
+}
+
+func TestGetSports(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    *inputGetSports
+		expected *expectedGetSports
+	}{
+		{
+			name: "table not exist error",
+			input: &inputGetSports{
+				status:   tableNotExist,
+				queryErr: errBase.New("ERROR: relation \"sports\" does not exist (SQLSTATE 42P01)"),
+			},
+			expected: &expectedGetSports{err: errors.ErrTableNotExist},
+		},
+		{
+			name:     "failed query",
+			input:    &inputGetSports{status: failedQuery, queryErr: errors.ErrInternal},
+			expected: &expectedGetSports{err: errors.ErrInternal},
+		},
+		{
+			name:     "failed row scan",
+			input:    &inputGetSports{status: failedRowScan},
+			expected: &expectedGetSports{err: errors.ErrInternal},
+		},
+		{
+			name:  "success get sports",
+			input: &inputGetSports{status: ok},
+			expected: &expectedGetSports{
+				sports: []domain.SportType{domain.Baseball, "hockey"},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mock, err := postgres.GetPgxMockPool(t)
+			if err != nil {
+				return
+			}
+			defer mock.Close()
+
+			input := test.input
+			expected := test.expected
+			setupGetSportsUseCases(mock, input, expected)
+
+			sports, err := NewSportQueryService(mock, fake.Logger{}).GetSports()
+			assert.Equal(t, expected.err, err)
+			assert.Equal(t, expected.sports, sports)
+			postgres.CheckExpectationsWereMet(t, mock)
+		})
+	}
+}
+
+func setupGetSportsUseCases(mock pgxmock.PgxPoolIface, input *inputGetSports, expected *expectedGetSports) {
+	const sql = "SELECT name FROM sports"
+
+	switch input.status {
+	case tableNotExist, failedQuery:
+		mock.ExpectQuery(sql).WillReturnError(input.queryErr)
+	case failedRowScan:
+		mock.ExpectQuery(sql).WillReturnRows(pgxmock.NewRows([]string{"name", "extra"}).AddRow("baseball", 1))
+	case ok:
+		rows := pgxmock.NewRows([]string{"name"})
+		for _, sport := range expected.sports {
+			rows.AddRow(sport)
+		}
+		mock.ExpectQuery(sql).WillReturnRows(rows)
+	}
+}
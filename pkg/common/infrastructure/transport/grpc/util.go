@@ -1,6 +1,7 @@
 package grpc
 
 import (
+	"context"
 	loggerInterface "github.com/col3name/lines/pkg/common/application/logger"
 	"google.golang.org/grpc"
 	"net"
@@ -12,7 +13,20 @@ func RunGrpcServer(logger loggerInterface.Logger, url string, srv *grpc.Server)
 		logger.Fatalf("failed to listen: %v", err)
 	}
 	logger.Info("server listening at", lis.Addr().String())
-	if err = srv.Serve(lis); err != nil {
+	if err = srv.Serve(lis); err != nil && err != grpc.ErrServerStopped {
 		logger.Fatal("failed to serve: ", err)
 	}
 }
+
+func StopGrpcServer(ctx context.Context, srv *grpc.Server) {
+	stopped := make(chan struct{})
+	go func() {
+		srv.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		srv.Stop()
+	}
+}
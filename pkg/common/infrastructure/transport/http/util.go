@@ -31,18 +31,22 @@ func RunHttpServer(serverUrl string, handler http.Handler, logger loggerInterfac
 	srv := &http.Server{Addr: serverUrl, Handler: handler}
 	killSignalChan := getKillSignalChan()
 
-	err := srv.ListenAndServe()
-	if err != nil {
-		logger.Fatal(err)
-	}
+	go ServeHttp(srv, logger)
 	<-killSignalChan
-	err = srv.Shutdown(context.Background())
+	err := srv.Shutdown(context.Background())
 	if err != nil {
 		logger.Fatal(err)
 		return
 	}
 }
 
+func ServeHttp(srv *http.Server, logger loggerInterface.Logger) {
+	err := srv.ListenAndServe()
+	if err != nil && err != http.ErrServerClosed {
+		logger.Fatal(err)
+	}
+}
+
 func getKillSignalChan() chan os.Signal {
 	osKillSignalChan := make(chan os.Signal, 1)
 	signal.Notify(osKillSignalChan, os.Interrupt, syscall.SIGTERM)
@@ -1,11 +1,12 @@
 package times
 
 import (
+	"sync"
 	"time"
 )
 
 type Ticker interface {
-	Handle(seconds int32, fn func()) *time.Ticker
+	Handle(interval time.Duration, fn func()) (stop func())
 }
 
 type TimeTicker struct {
@@ -15,12 +16,34 @@ func NewTimeTicker() *TimeTicker {
 	return &TimeTicker{}
 }
 
-func (TimeTicker) Handle(seconds int32, fn func()) *time.Ticker {
-	ticker := time.NewTicker(time.Duration(seconds) * time.Second)
+// Handle calls fn every interval until stop is called. stop ends the goroutine that waits for the ticks
+// and returns only after a call of fn that is already running has finished, so it must not be called from fn.
+func (TimeTicker) Handle(interval time.Duration, fn func()) func() {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+	exited := make(chan struct{})
 	go func() {
-		for range ticker.C {
-			fn()
+		defer close(exited)
+		for {
+			select {
+			case <-ticker.C:
+				select {
+				case <-done:
+					return
+				default:
+					fn()
+				}
+			case <-done:
+				return
+			}
 		}
 	}()
-	return ticker
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			ticker.Stop()
+			close(done)
+		})
+		<-exited
+	}
 }
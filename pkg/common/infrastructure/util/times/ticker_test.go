@@ -2,14 +2,64 @@ package times
 
 import (
 	"fmt"
+	"github.com/stretchr/testify/assert"
+	"runtime"
+	"sync/atomic"
 	"testing"
 	"time"
 )
 
 func TestName(t *testing.T) {
-	ticker := NewTimeTicker().Handle(1, func() {
+	stop := NewTimeTicker().Handle(time.Second, func() {
 		fmt.Println(time.Now().Second())
 	})
 	time.Sleep(10 * time.Second)
-	ticker.Stop()
+	stop()
+}
+
+func TestHandleStopEndsGoroutine(t *testing.T) {
+	goroutines := runtime.NumGoroutine()
+	var calls int32
+	stop := NewTimeTicker().Handle(time.Millisecond, func() {
+		atomic.AddInt32(&calls, 1)
+	})
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&calls) > 0 }, time.Second, time.Millisecond)
+
+	stop()
+	stop()
+	for deadline := time.Now().Add(time.Second); runtime.NumGoroutine() > goroutines && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+	}
+	assert.LessOrEqual(t, runtime.NumGoroutine(), goroutines)
+	stopped := atomic.LoadInt32(&calls)
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, stopped, atomic.LoadInt32(&calls))
+}
+
+func TestStopWaitsForRunningCall(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var running int32
+	stop := NewTimeTicker().Handle(time.Millisecond, func() {
+		if atomic.CompareAndSwapInt32(&running, 0, 1) {
+			close(started)
+			<-release
+			atomic.StoreInt32(&running, 2)
+		}
+	})
+	<-started
+
+	stopped := make(chan struct{})
+	go func() {
+		stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		t.Fatal("stop returned while fn was running")
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(release)
+	<-stopped
+	assert.Equal(t, int32(2), atomic.LoadInt32(&running))
 }
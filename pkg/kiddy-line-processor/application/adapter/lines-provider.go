@@ -4,4 +4,5 @@ import commonDomain "github.com/col3name/lines/pkg/common/domain"
 
 type LinesProviderAdapter interface {
 	GetLineBySport(sportType commonDomain.SportType) (*commonDomain.SportLine, error)
+	GetEventsBySport(sportType commonDomain.SportType) ([]*commonDomain.Event, error)
 }
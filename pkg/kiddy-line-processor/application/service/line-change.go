@@ -0,0 +1,16 @@
+package service
+
+import (
+	"github.com/col3name/lines/pkg/common/domain"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/domain/model"
+)
+
+type LineChangeHandler func(sportType domain.SportType, kind model.ChangeKind)
+
+type LineChangePublisher interface {
+	Publish(sportType domain.SportType, kind model.ChangeKind)
+}
+
+type LineChangeSubscriber interface {
+	Subscribe(handler LineChangeHandler) (unsubscribe func())
+}
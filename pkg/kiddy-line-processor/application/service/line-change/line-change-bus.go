@@ -0,0 +1,48 @@
+package line_change
+
+import (
+	"github.com/col3name/lines/pkg/common/domain"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/application/service"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/domain/model"
+	"sync"
+)
+
+type LineChangeBus struct {
+	mu       sync.RWMutex
+	nextId   int
+	handlers map[int]service.LineChangeHandler
+}
+
+func NewLineChangeBus() *LineChangeBus {
+	return &LineChangeBus{handlers: make(map[int]service.LineChangeHandler)}
+}
+
+func (b *LineChangeBus) Publish(sportType domain.SportType, kind model.ChangeKind) {
+	b.mu.RLock()
+	handlers := make([]service.LineChangeHandler, 0, len(b.handlers))
+	for _, handler := range b.handlers {
+		handlers = append(handlers, handler)
+	}
+	b.mu.RUnlock()
+
+	for _, handler := range handlers {
+		handler(sportType, kind)
+	}
+}
+
+func (b *LineChangeBus) Subscribe(handler service.LineChangeHandler) func() {
+	b.mu.Lock()
+	id := b.nextId
+	b.nextId++
+	b.handlers[id] = handler
+	b.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.handlers, id)
+			b.mu.Unlock()
+		})
+	}
+}
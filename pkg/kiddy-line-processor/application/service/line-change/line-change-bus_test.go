@@ -0,0 +1,31 @@
+package line_change
+
+import (
+	"github.com/col3name/lines/pkg/common/domain"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/domain/model"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestLineChangeBus(t *testing.T) {
+	bus := NewLineChangeBus()
+
+	var first, second []domain.SportType
+	unsubscribeFirst := bus.Subscribe(func(sportType domain.SportType, _ model.ChangeKind) {
+		first = append(first, sportType)
+	})
+	unsubscribeSecond := bus.Subscribe(func(sportType domain.SportType, _ model.ChangeKind) {
+		second = append(second, sportType)
+	})
+
+	bus.Publish(domain.Soccer, model.LineChanged)
+	unsubscribeFirst()
+	unsubscribeFirst()
+	bus.Publish(domain.Baseball, model.LineChanged)
+	unsubscribeSecond()
+	bus.Publish(domain.Football, model.LineChanged)
+
+	assert.Equal(t, []domain.SportType{domain.Soccer}, first)
+	assert.Equal(t, []domain.SportType{domain.Soccer, domain.Baseball}, second)
+	assert.Equal(t, 0, len(bus.handlers))
+}
@@ -1,9 +1,32 @@
 package service
 
 import (
-	"github.com/col3name/lines/pkg/common/domain"
+	"errors"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/domain/model"
+)
+
+var (
+	ErrSnapshotRequired = errors.New("queued updates were coalesced or dropped, snapshot required")
+	ErrSlowConsumer     = errors.New("client does not read updates fast enough")
 )
 
 type ResponseSenderService interface {
-	Send(sports []*domain.SportLine) error
+	Send(update *model.LineUpdate) error
+	Ack(ack *model.SubscriptionAck) error
+	Reject(subscriptionId string, err error) error
+}
+
+type SendStats struct {
+	Queued    int
+	Dropped   uint64
+	Coalesced uint64
+}
+
+type SendStatsProvider interface {
+	Stats() SendStats
+}
+
+// UndeliveredReporter is implemented by senders that can lose updates they have already accepted.
+type UndeliveredReporter interface {
+	Undelivered() []string
 }
@@ -0,0 +1,45 @@
+package sport_line
+
+import (
+	"github.com/col3name/lines/pkg/common/application/errors"
+	commonDomain "github.com/col3name/lines/pkg/common/domain"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/domain/model"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/domain/query"
+	"time"
+)
+
+const MaxCandles = 1000
+
+type CandleService interface {
+	GetCandles(sportType commonDomain.SportType, bucket time.Duration, from, to time.Time) ([]*model.Candle, error)
+}
+
+type candleServiceImpl struct {
+	sportLineQueryService query.SportLineQueryService
+}
+
+func NewCandleService(queryService query.SportLineQueryService) *candleServiceImpl {
+	return &candleServiceImpl{sportLineQueryService: queryService}
+}
+
+func (s *candleServiceImpl) GetCandles(sportType commonDomain.SportType, bucket time.Duration, from, to time.Time) ([]*model.Candle, error) {
+	if !model.IsSupportedCandleBucket(bucket) || !from.Before(to) || to.Sub(from)/bucket > MaxCandles {
+		return nil, errors.ErrInvalidArgument
+	}
+
+	candles := make([]*model.Candle, 0)
+	for {
+		page, err := s.sportLineQueryService.GetCandles(sportType, bucket, from, to, query.MaxCandlePageSize)
+		if err != nil {
+			return nil, err
+		}
+		candles = append(candles, page...)
+		if len(page) < query.MaxCandlePageSize {
+			return candles, nil
+		}
+		from = page[len(page)-1].OpenTime.Add(bucket)
+		if !from.Before(to) {
+			return candles, nil
+		}
+	}
+}
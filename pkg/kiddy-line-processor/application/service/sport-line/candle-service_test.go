@@ -0,0 +1,115 @@
+package sport_line
+
+import (
+	"github.com/col3name/lines/pkg/common/application/errors"
+	commonDomain "github.com/col3name/lines/pkg/common/domain"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/domain/model"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/domain/query"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+type candlesInput struct {
+	bucket time.Duration
+	from   time.Time
+	to     time.Time
+}
+
+type candlesExpected struct {
+	err     error
+	candles []*model.Candle
+	pages   []time.Time
+}
+
+func candle(openTime time.Time, score string) *model.Candle {
+	value := decimal.RequireFromString(score)
+	return &model.Candle{SportType: commonDomain.Soccer, OpenTime: openTime, Open: value, High: value, Low: value, Close: value, Count: 1}
+}
+
+func TestGetCandles(t *testing.T) {
+	from := time.Date(2022, 9, 1, 14, 0, 0, 0, time.UTC)
+	to := from.Add(time.Hour)
+	first := candle(from, "1.5")
+	second := candle(from.Add(3*time.Minute), "2.0")
+
+	var fullPage []*model.Candle
+	for i := 0; i < query.MaxCandlePageSize; i++ {
+		fullPage = append(fullPage, candle(from.Add(time.Duration(i)*time.Second), "1.5"))
+	}
+	last := candle(from.Add(query.MaxCandlePageSize*time.Second), "1.6")
+
+	tests := []struct {
+		name       string
+		fakeResult func(from time.Time) ([]*model.Candle, error)
+		input      *candlesInput
+		expected   *candlesExpected
+	}{
+		{
+			name:     "unsupported bucket",
+			input:    &candlesInput{bucket: 2 * time.Minute, from: from, to: to},
+			expected: &candlesExpected{err: errors.ErrInvalidArgument},
+		},
+		{
+			name:     "from after to",
+			input:    &candlesInput{bucket: time.Minute, from: to, to: from},
+			expected: &candlesExpected{err: errors.ErrInvalidArgument},
+		},
+		{
+			name:     "too many candles",
+			input:    &candlesInput{bucket: time.Second, from: from, to: to},
+			expected: &candlesExpected{err: errors.ErrInvalidArgument},
+		},
+		{
+			name: "failed get candles",
+			fakeResult: func(time.Time) ([]*model.Candle, error) {
+				return nil, errors.ErrInternal
+			},
+			input:    &candlesInput{bucket: time.Minute, from: from, to: to},
+			expected: &candlesExpected{err: errors.ErrInternal, pages: []time.Time{from}},
+		},
+		{
+			name: "single page",
+			fakeResult: func(time.Time) ([]*model.Candle, error) {
+				return []*model.Candle{first, second}, nil
+			},
+			input:    &candlesInput{bucket: time.Minute, from: from, to: to},
+			expected: &candlesExpected{candles: []*model.Candle{first, second}, pages: []time.Time{from}},
+		},
+		{
+			name: "pages continue after the last candle",
+			fakeResult: func(pageFrom time.Time) ([]*model.Candle, error) {
+				if pageFrom.Equal(from) {
+					return fullPage, nil
+				}
+				return []*model.Candle{last}, nil
+			},
+			input: &candlesInput{bucket: time.Second, from: from, to: from.Add(15 * time.Minute)},
+			expected: &candlesExpected{
+				candles: append(append([]*model.Candle{}, fullPage...), last),
+				pages:   []time.Time{from, from.Add(query.MaxCandlePageSize * time.Second)},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var pages []time.Time
+			db := &mockDB{
+				FakeGetCandles: func(pageFrom time.Time, limit int) ([]*model.Candle, error) {
+					assert.Equal(t, query.MaxCandlePageSize, limit)
+					pages = append(pages, pageFrom)
+					return test.fakeResult(pageFrom)
+				},
+			}
+			service := NewCandleService(db)
+			input := test.input
+			candles, err := service.GetCandles(commonDomain.Soccer, input.bucket, input.from, input.to)
+			assert.Equal(t, test.expected.err, err)
+			assert.Equal(t, test.expected.pages, pages)
+			if test.expected.err == nil {
+				assert.Equal(t, test.expected.candles, candles)
+			}
+		})
+	}
+}
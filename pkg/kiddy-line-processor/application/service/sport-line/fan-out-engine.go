@@ -0,0 +1,237 @@
+package sport_line
+
+import (
+	"github.com/col3name/lines/pkg/common/application/errors"
+	"github.com/col3name/lines/pkg/common/application/logger"
+	commonDomain "github.com/col3name/lines/pkg/common/domain"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/application/service"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/domain/model"
+	"sort"
+	"sync"
+)
+
+type LineSource interface {
+	GetLinesBySportTypes(sportTypes []commonDomain.SportType) ([]*commonDomain.SportLine, error)
+	GetMarketsBySportTypes(sportTypes []commonDomain.SportType, marketTypes []commonDomain.MarketType) (map[commonDomain.SportType][]*commonDomain.Market, error)
+}
+
+type EventSource interface {
+	GetEventsByIDs(ids []string) ([]*commonDomain.Event, error)
+}
+
+type FanOutEngine struct {
+	source        LineSource
+	eventSource   EventSource
+	downstream    service.LineChangePublisher
+	logger        logger.Logger
+	mu            sync.RWMutex
+	loadMu        sync.Mutex
+	lines         map[commonDomain.SportType]*commonDomain.SportLine
+	events        map[string]*commonDomain.Event
+	missingEvents map[string]struct{}
+}
+
+func NewFanOutEngine(source LineSource, eventSource EventSource, downstream service.LineChangePublisher, logger logger.Logger) *FanOutEngine {
+	return &FanOutEngine{
+		source:        source,
+		eventSource:   eventSource,
+		downstream:    downstream,
+		logger:        logger,
+		lines:         make(map[commonDomain.SportType]*commonDomain.SportLine),
+		events:        make(map[string]*commonDomain.Event),
+		missingEvents: make(map[string]struct{}),
+	}
+}
+
+func (e *FanOutEngine) Publish(sportType commonDomain.SportType, kind model.ChangeKind) {
+	e.loadMu.Lock()
+	if kind == model.EventsChanged {
+		e.forgetEvents(sportType)
+	} else if err := e.refresh([]commonDomain.SportType{sportType}); err != nil {
+		e.logger.Error("fan-out engine: ", err)
+		e.forget(sportType)
+	}
+	e.loadMu.Unlock()
+	e.downstream.Publish(sportType, kind)
+}
+
+func (e *FanOutEngine) GetLinesBySportTypes(sportTypes []commonDomain.SportType) ([]*commonDomain.SportLine, error) {
+	if len(sportTypes) < 1 {
+		return nil, errors.ErrInvalidArgument
+	}
+	snapshot, err := e.snapshot(sportTypes)
+	if err != nil {
+		return nil, err
+	}
+	lines := make([]*commonDomain.SportLine, 0, len(sportTypes))
+	for _, sportType := range sportTypes {
+		if line, ok := snapshot[sportType]; ok {
+			lines = append(lines, &commonDomain.SportLine{Type: line.Type, Score: line.Score})
+		}
+	}
+	return lines, nil
+}
+
+func (e *FanOutEngine) GetMarketsBySportTypes(sportTypes []commonDomain.SportType, marketTypes []commonDomain.MarketType) (map[commonDomain.SportType][]*commonDomain.Market, error) {
+	if len(sportTypes) < 1 || len(marketTypes) < 1 {
+		return nil, errors.ErrInvalidArgument
+	}
+	snapshot, err := e.snapshot(sportTypes)
+	if err != nil {
+		return nil, err
+	}
+	wanted := make(map[commonDomain.MarketType]struct{}, len(marketTypes))
+	for _, marketType := range marketTypes {
+		wanted[marketType] = struct{}{}
+	}
+	result := make(map[commonDomain.SportType][]*commonDomain.Market)
+	for _, sportType := range sportTypes {
+		line, ok := snapshot[sportType]
+		if !ok {
+			continue
+		}
+		for _, market := range line.Markets {
+			if _, ok = wanted[market.Type]; ok {
+				result[sportType] = append(result[sportType], copyMarket(market))
+			}
+		}
+	}
+	return result, nil
+}
+
+// GetEventsByIDs serves events from the cache. Events missing from it are loaded once and kept until their sport changes.
+func (e *FanOutEngine) GetEventsByIDs(ids []string) ([]*commonDomain.Event, error) {
+	if len(ids) < 1 {
+		return nil, errors.ErrInvalidArgument
+	}
+	events, missing := e.lookupEvents(ids)
+	if len(missing) > 0 {
+		e.loadMu.Lock()
+		if _, missing = e.lookupEvents(missing); len(missing) > 0 {
+			if err := e.loadEvents(missing); err != nil {
+				e.loadMu.Unlock()
+				return nil, err
+			}
+		}
+		e.loadMu.Unlock()
+		events, _ = e.lookupEvents(ids)
+	}
+	sort.Slice(events, func(i, j int) bool {
+		if !events[i].StartTime.Equal(events[j].StartTime) {
+			return events[i].StartTime.Before(events[j].StartTime)
+		}
+		return events[i].ID < events[j].ID
+	})
+	return events, nil
+}
+
+func (e *FanOutEngine) lookupEvents(ids []string) ([]*commonDomain.Event, []string) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	events := make([]*commonDomain.Event, 0, len(ids))
+	var missing []string
+	for _, id := range ids {
+		if event, ok := e.events[id]; ok {
+			copied := *event
+			events = append(events, &copied)
+		} else if _, ok = e.missingEvents[id]; !ok {
+			missing = append(missing, id)
+		}
+	}
+	return events, missing
+}
+
+func (e *FanOutEngine) loadEvents(ids []string) error {
+	events, err := e.eventSource.GetEventsByIDs(ids)
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, id := range ids {
+		e.missingEvents[id] = struct{}{}
+	}
+	for _, event := range events {
+		delete(e.missingEvents, event.ID)
+		e.events[event.ID] = event
+	}
+	return nil
+}
+
+// forgetEvents drops the cached events of the sport. Unknown ids are dropped too, as they may belong to it.
+func (e *FanOutEngine) forgetEvents(sportType commonDomain.SportType) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for id, event := range e.events {
+		if event.SportType == sportType {
+			delete(e.events, id)
+		}
+	}
+	e.missingEvents = make(map[string]struct{})
+}
+
+func (e *FanOutEngine) snapshot(sportTypes []commonDomain.SportType) (map[commonDomain.SportType]*commonDomain.SportLine, error) {
+	snapshot, missing := e.lookup(sportTypes)
+	if len(missing) == 0 {
+		return snapshot, nil
+	}
+
+	e.loadMu.Lock()
+	defer e.loadMu.Unlock()
+	if _, missing = e.lookup(missing); len(missing) > 0 {
+		if err := e.refresh(missing); err != nil {
+			return nil, err
+		}
+	}
+	snapshot, _ = e.lookup(sportTypes)
+	return snapshot, nil
+}
+
+func (e *FanOutEngine) lookup(sportTypes []commonDomain.SportType) (map[commonDomain.SportType]*commonDomain.SportLine, []commonDomain.SportType) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	snapshot := make(map[commonDomain.SportType]*commonDomain.SportLine, len(sportTypes))
+	var missing []commonDomain.SportType
+	for _, sportType := range sportTypes {
+		if line, ok := e.lines[sportType]; ok {
+			snapshot[sportType] = line
+		} else {
+			missing = append(missing, sportType)
+		}
+	}
+	return snapshot, missing
+}
+
+func (e *FanOutEngine) refresh(sportTypes []commonDomain.SportType) error {
+	lines, err := e.source.GetLinesBySportTypes(sportTypes)
+	if err != nil {
+		return err
+	}
+	markets, err := e.source.GetMarketsBySportTypes(sportTypes, commonDomain.AllMarketTypes)
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, line := range lines {
+		line.Markets = markets[line.Type]
+		e.lines[line.Type] = line
+	}
+	return nil
+}
+
+func (e *FanOutEngine) forget(sportType commonDomain.SportType) {
+	e.mu.Lock()
+	delete(e.lines, sportType)
+	e.mu.Unlock()
+}
+
+func copyMarket(market *commonDomain.Market) *commonDomain.Market {
+	result := &commonDomain.Market{Type: market.Type, Point: market.Point, Selections: make([]*commonDomain.Selection, 0, len(market.Selections))}
+	for _, selection := range market.Selections {
+		result.Selections = append(result.Selections, &commonDomain.Selection{Name: selection.Name, Price: selection.Price})
+	}
+	return result
+}
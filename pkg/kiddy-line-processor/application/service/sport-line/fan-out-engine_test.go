@@ -0,0 +1,357 @@
+package sport_line
+
+import (
+	"errors"
+	"fmt"
+	appErr "github.com/col3name/lines/pkg/common/application/errors"
+	commonDomain "github.com/col3name/lines/pkg/common/domain"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/application/fake"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/domain/model"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type countingLineSource struct {
+	mu            sync.Mutex
+	scores        map[commonDomain.SportType]decimal.Decimal
+	err           error
+	lineQueries   int64
+	marketQueries int64
+	latency       time.Duration
+	connections   chan struct{}
+}
+
+func newCountingLineSource(scores map[commonDomain.SportType]decimal.Decimal) *countingLineSource {
+	return &countingLineSource{scores: scores}
+}
+
+func (s *countingLineSource) roundTrip() {
+	if s.connections != nil {
+		s.connections <- struct{}{}
+		defer func() { <-s.connections }()
+	}
+	if s.latency > 0 {
+		time.Sleep(s.latency)
+	}
+}
+
+func (s *countingLineSource) GetLinesBySportTypes(sportTypes []commonDomain.SportType) ([]*commonDomain.SportLine, error) {
+	atomic.AddInt64(&s.lineQueries, 1)
+	s.roundTrip()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return nil, s.err
+	}
+	lines := make([]*commonDomain.SportLine, 0, len(sportTypes))
+	for _, sportType := range sportTypes {
+		if score, ok := s.scores[sportType]; ok {
+			lines = append(lines, &commonDomain.SportLine{Type: sportType, Score: score})
+		}
+	}
+	return lines, nil
+}
+
+func (s *countingLineSource) GetMarketsBySportTypes(sportTypes []commonDomain.SportType, marketTypes []commonDomain.MarketType) (map[commonDomain.SportType][]*commonDomain.Market, error) {
+	atomic.AddInt64(&s.marketQueries, 1)
+	s.roundTrip()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result := make(map[commonDomain.SportType][]*commonDomain.Market)
+	for _, sportType := range sportTypes {
+		for _, marketType := range marketTypes {
+			result[sportType] = append(result[sportType], &commonDomain.Market{
+				Type:       marketType,
+				Selections: []*commonDomain.Selection{{Name: "home", Price: s.scores[sportType].Add(decimal.NewFromInt(1))}},
+			})
+		}
+	}
+	return result, nil
+}
+
+func (s *countingLineSource) setScore(sportType commonDomain.SportType, score string) {
+	s.mu.Lock()
+	s.scores[sportType] = decimal.RequireFromString(score)
+	s.mu.Unlock()
+}
+
+type recordingPublisher struct {
+	FakePublish func(sportType commonDomain.SportType)
+	published   []commonDomain.SportType
+	kinds       []model.ChangeKind
+}
+
+func (p *recordingPublisher) Publish(sportType commonDomain.SportType, kind model.ChangeKind) {
+	if p.FakePublish != nil {
+		p.FakePublish(sportType)
+	}
+	p.published = append(p.published, sportType)
+	p.kinds = append(p.kinds, kind)
+}
+
+func TestFanOutEngineServesSnapshotCopies(t *testing.T) {
+	source := newCountingLineSource(map[commonDomain.SportType]decimal.Decimal{
+		commonDomain.Soccer:   decimal.RequireFromString("1.5"),
+		commonDomain.Baseball: decimal.RequireFromString("2.5"),
+	})
+	engine := NewFanOutEngine(source, nil, &recordingPublisher{}, fake.Logger{})
+
+	lines, err := engine.GetLinesBySportTypes([]commonDomain.SportType{commonDomain.Soccer, commonDomain.Baseball})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(lines))
+	lines[0].Score = decimal.Zero
+
+	lines, err = engine.GetLinesBySportTypes([]commonDomain.SportType{commonDomain.Soccer})
+	assert.Nil(t, err)
+	assert.True(t, decimal.RequireFromString("1.5").Equal(lines[0].Score))
+
+	markets, err := engine.GetMarketsBySportTypes([]commonDomain.SportType{commonDomain.Soccer}, []commonDomain.MarketType{commonDomain.Totals})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(markets[commonDomain.Soccer]))
+	assert.Equal(t, commonDomain.Totals, markets[commonDomain.Soccer][0].Type)
+	markets[commonDomain.Soccer][0].Selections[0].Price = decimal.Zero
+
+	markets, err = engine.GetMarketsBySportTypes([]commonDomain.SportType{commonDomain.Soccer}, []commonDomain.MarketType{commonDomain.Totals})
+	assert.Nil(t, err)
+	assert.True(t, decimal.RequireFromString("2.5").Equal(markets[commonDomain.Soccer][0].Selections[0].Price))
+
+	assert.Equal(t, int64(1), source.lineQueries)
+	assert.Equal(t, int64(1), source.marketQueries)
+
+	_, err = engine.GetLinesBySportTypes(nil)
+	assert.Equal(t, appErr.ErrInvalidArgument, err)
+	_, err = engine.GetMarketsBySportTypes([]commonDomain.SportType{commonDomain.Soccer}, nil)
+	assert.Equal(t, appErr.ErrInvalidArgument, err)
+}
+
+func TestFanOutEngineRefreshesBeforePublishing(t *testing.T) {
+	fakeErr := errors.New("fake error")
+	source := newCountingLineSource(map[commonDomain.SportType]decimal.Decimal{
+		commonDomain.Soccer: decimal.RequireFromString("1.5"),
+	})
+	var engine *FanOutEngine
+	var seen []string
+	downstream := &recordingPublisher{FakePublish: func(sportType commonDomain.SportType) {
+		lines, err := engine.GetLinesBySportTypes([]commonDomain.SportType{sportType})
+		if err != nil {
+			seen = append(seen, err.Error())
+			return
+		}
+		seen = append(seen, lines[0].Score.String())
+	}}
+	engine = NewFanOutEngine(source, nil, downstream, fake.Logger{})
+
+	_, err := engine.GetLinesBySportTypes([]commonDomain.SportType{commonDomain.Soccer})
+	assert.Nil(t, err)
+
+	source.setScore(commonDomain.Soccer, "1.7")
+	engine.Publish(commonDomain.Soccer, model.LineChanged)
+
+	source.setScore(commonDomain.Soccer, "1.9")
+	source.err = fakeErr
+	engine.Publish(commonDomain.Soccer, model.LineChanged)
+
+	source.err = nil
+	lines, err := engine.GetLinesBySportTypes([]commonDomain.SportType{commonDomain.Soccer})
+	assert.Nil(t, err)
+	assert.Equal(t, "1.9", lines[0].Score.String())
+
+	assert.Equal(t, []commonDomain.SportType{commonDomain.Soccer, commonDomain.Soccer}, downstream.published)
+	assert.Equal(t, []string{"1.7", fakeErr.Error()}, seen)
+}
+
+func TestFanOutEngineDoesNotReloadLinesOnEventChange(t *testing.T) {
+	source := newCountingLineSource(map[commonDomain.SportType]decimal.Decimal{
+		commonDomain.Soccer: decimal.RequireFromString("1.5"),
+	})
+	downstream := &recordingPublisher{}
+	engine := NewFanOutEngine(source, nil, downstream, fake.Logger{})
+	_, err := engine.GetLinesBySportTypes([]commonDomain.SportType{commonDomain.Soccer})
+	assert.Nil(t, err)
+
+	engine.Publish(commonDomain.Soccer, model.EventsChanged)
+	assert.Equal(t, int64(1), atomic.LoadInt64(&source.lineQueries))
+	assert.Equal(t, []model.ChangeKind{model.EventsChanged}, downstream.kinds)
+}
+
+type slowLoadSource struct {
+	*countingLineSource
+	loading chan struct{}
+	release chan struct{}
+	calls   int64
+}
+
+func (s *slowLoadSource) GetLinesBySportTypes(sportTypes []commonDomain.SportType) ([]*commonDomain.SportLine, error) {
+	lines, err := s.countingLineSource.GetLinesBySportTypes(sportTypes)
+	if atomic.AddInt64(&s.calls, 1) == 1 {
+		close(s.loading)
+		<-s.release
+	}
+	return lines, err
+}
+
+func TestFanOutEngineKeepsLineNewerThanSlowLoad(t *testing.T) {
+	source := &slowLoadSource{
+		countingLineSource: newCountingLineSource(map[commonDomain.SportType]decimal.Decimal{
+			commonDomain.Soccer: decimal.RequireFromString("1.5"),
+		}),
+		loading: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+	published := make(chan struct{})
+	engine := NewFanOutEngine(source, nil, &recordingPublisher{FakePublish: func(commonDomain.SportType) {
+		close(published)
+	}}, fake.Logger{})
+
+	loaded := make(chan struct{})
+	go func() {
+		_, _ = engine.GetLinesBySportTypes([]commonDomain.SportType{commonDomain.Soccer})
+		close(loaded)
+	}()
+	<-source.loading
+
+	source.setScore(commonDomain.Soccer, "1.7")
+	go engine.Publish(commonDomain.Soccer, model.LineChanged)
+	time.Sleep(10 * time.Millisecond)
+	close(source.release)
+	<-loaded
+	<-published
+
+	lines, err := engine.GetLinesBySportTypes([]commonDomain.SportType{commonDomain.Soccer})
+	assert.Nil(t, err)
+	assert.Equal(t, "1.7", lines[0].Score.String())
+}
+
+type countingEventSource struct {
+	mu      sync.Mutex
+	events  map[string]*commonDomain.Event
+	queries int64
+}
+
+func (s *countingEventSource) GetEventsByIDs(ids []string) ([]*commonDomain.Event, error) {
+	atomic.AddInt64(&s.queries, 1)
+	time.Sleep(time.Millisecond)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	events := make([]*commonDomain.Event, 0, len(ids))
+	for _, id := range ids {
+		if event, ok := s.events[id]; ok {
+			copied := *event
+			events = append(events, &copied)
+		}
+	}
+	return events, nil
+}
+
+func (s *countingEventSource) setLine(id, line string) {
+	s.mu.Lock()
+	s.events[id].Line = decimal.RequireFromString(line)
+	s.mu.Unlock()
+}
+
+func TestFanOutEngineSharesEventQueryBetweenSubscribers(t *testing.T) {
+	const subscribers = 50
+	startTime := time.Date(2022, 5, 1, 18, 0, 0, 0, time.UTC)
+	source := &countingEventSource{events: map[string]*commonDomain.Event{
+		"soccer-1":   {ID: "soccer-1", SportType: commonDomain.Soccer, StartTime: startTime.Add(time.Hour), Line: decimal.RequireFromString("1.5")},
+		"soccer-2":   {ID: "soccer-2", SportType: commonDomain.Soccer, StartTime: startTime, Line: decimal.RequireFromString("2.5")},
+		"baseball-1": {ID: "baseball-1", SportType: commonDomain.Baseball, StartTime: startTime, Line: decimal.RequireFromString("3.5")},
+	}}
+	lines := newCountingLineSource(map[commonDomain.SportType]decimal.Decimal{commonDomain.Soccer: decimal.RequireFromString("1.5")})
+	engine := NewFanOutEngine(lines, source, &recordingPublisher{}, fake.Logger{})
+	service := NewSportLineService(engine, engine, fake.Logger{})
+	ids := []string{"soccer-1", "soccer-2", "baseball-1", "unknown"}
+
+	tick := func() [][]*commonDomain.Event {
+		results := make([][]*commonDomain.Event, subscribers)
+		var wg sync.WaitGroup
+		wg.Add(subscribers)
+		for i := range results {
+			go func(i int) {
+				defer wg.Done()
+				events, err := service.CalculateEvents(ids, false, &model.ClientSubscription{})
+				assert.Nil(t, err)
+				results[i] = events
+			}(i)
+		}
+		wg.Wait()
+		return results
+	}
+
+	results := tick()
+	assert.Equal(t, int64(1), atomic.LoadInt64(&source.queries))
+	for _, events := range results {
+		assert.Equal(t, 3, len(events))
+		assert.Equal(t, []string{"baseball-1", "soccer-2", "soccer-1"}, []string{events[0].ID, events[1].ID, events[2].ID})
+	}
+	tick()
+	assert.Equal(t, int64(1), atomic.LoadInt64(&source.queries))
+
+	source.setLine("soccer-1", "1.7")
+	engine.Publish(commonDomain.Soccer, model.LineChanged)
+	tick()
+	assert.Equal(t, int64(1), atomic.LoadInt64(&source.queries))
+
+	engine.Publish(commonDomain.Soccer, model.EventsChanged)
+	results = tick()
+	assert.Equal(t, int64(2), atomic.LoadInt64(&source.queries))
+	for _, events := range results {
+		assert.Equal(t, "1.7", events[2].Line.String())
+	}
+}
+
+func benchmarkTick(b *testing.B, subscribers int, newSource func(source LineSource) LineSource) {
+	sports := []commonDomain.SportType{commonDomain.Soccer, commonDomain.Baseball}
+	source := newCountingLineSource(map[commonDomain.SportType]decimal.Decimal{
+		commonDomain.Soccer:   decimal.RequireFromString("1.5"),
+		commonDomain.Baseball: decimal.RequireFromString("2.5"),
+	})
+	source.latency = 100 * time.Microsecond
+	source.connections = make(chan struct{}, 4)
+	service := NewSportLineService(newSource(source), nil, fake.Logger{})
+
+	subs := make([]*model.ClientSubscription, subscribers)
+	for i := range subs {
+		subs[i] = &model.ClientSubscription{
+			Sports:  model.SportTypeMap{},
+			Markets: []commonDomain.MarketType{commonDomain.Moneyline},
+		}
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var wg sync.WaitGroup
+		wg.Add(subscribers)
+		for _, sub := range subs {
+			go func(sub *model.ClientSubscription) {
+				defer wg.Done()
+				if _, err := service.Calculate(sports, true, sub); err != nil {
+					b.Error(err)
+				}
+			}(sub)
+		}
+		wg.Wait()
+	}
+	b.StopTimer()
+	queries := atomic.LoadInt64(&source.lineQueries) + atomic.LoadInt64(&source.marketQueries)
+	b.ReportMetric(float64(queries)/float64(b.N), "queries/tick")
+}
+
+func BenchmarkCalculateTick(b *testing.B) {
+	for _, subscribers := range []int{100, 1000, 10000} {
+		b.Run(fmt.Sprintf("query-per-subscriber/subscribers=%d", subscribers), func(b *testing.B) {
+			benchmarkTick(b, subscribers, func(source LineSource) LineSource {
+				return source
+			})
+		})
+		b.Run(fmt.Sprintf("fan-out/subscribers=%d", subscribers), func(b *testing.B) {
+			benchmarkTick(b, subscribers, func(source LineSource) LineSource {
+				return NewFanOutEngine(source, nil, &recordingPublisher{}, fake.Logger{})
+			})
+		})
+	}
+}
@@ -2,51 +2,171 @@ package sport_line
 
 import (
 	"github.com/col3name/lines/pkg/common/application/errors"
+	"github.com/col3name/lines/pkg/common/application/logger"
 	commonDomain "github.com/col3name/lines/pkg/common/domain"
 	"github.com/col3name/lines/pkg/common/infrastructure/util/array"
 	"github.com/col3name/lines/pkg/kiddy-line-processor/domain/model"
-	"github.com/col3name/lines/pkg/kiddy-line-processor/domain/query"
+	"github.com/shopspring/decimal"
 )
 
 type SportLineService interface {
 	Calculate(sports []commonDomain.SportType, isNeedDelta bool, subs *model.ClientSubscription) ([]*commonDomain.SportLine, error)
+	CalculateEvents(eventIDs []string, isNeedDelta bool, subs *model.ClientSubscription) ([]*commonDomain.Event, error)
 	IsSubscriptionChanged(exist bool, subscriptionMap model.SportTypeMap, newValue []commonDomain.SportType) bool
 }
 
 type sportLineServiceImpl struct {
-	sportLineQueryService query.SportLineQueryService
+	lineSource        LineSource
+	eventQueryService EventSource
+	logger            logger.Logger
 }
 
-func NewSportLineService(queryService query.SportLineQueryService) *sportLineServiceImpl {
-	return &sportLineServiceImpl{sportLineQueryService: queryService}
+func NewSportLineService(lineSource LineSource, eventQueryService EventSource, logger logger.Logger) *sportLineServiceImpl {
+	return &sportLineServiceImpl{lineSource: lineSource, eventQueryService: eventQueryService, logger: logger}
 }
 
 func (s *sportLineServiceImpl) Calculate(sports []commonDomain.SportType, isNeedDelta bool, subs *model.ClientSubscription) ([]*commonDomain.SportLine, error) {
 	if subs == nil {
 		return nil, errors.ErrInvalidArgument
 	}
-	sportLines, err := s.sportLineQueryService.GetLinesBySportTypes(sports)
+	sportLines, err := s.lineSource.GetLinesBySportTypes(sports)
 	if err != nil {
 		return nil, err
 	}
-	return s.calculateLineOfSports(sportLines, isNeedDelta, subs), nil
+	if err = s.attachMarkets(sportLines, sports, subs.Markets); err != nil {
+		return nil, err
+	}
+	return s.calculateLineOfSports(sportLines, isNeedDelta, subs)
 }
 
-func (s *sportLineServiceImpl) calculateLineOfSports(lines []*commonDomain.SportLine, isNeedDelta bool, subs *model.ClientSubscription) []*commonDomain.SportLine {
-	for i, line := range lines {
-		s.calculateLine(line, isNeedDelta, subs)
-		lines[i] = line
+func (s *sportLineServiceImpl) attachMarkets(lines []*commonDomain.SportLine, sports []commonDomain.SportType, marketTypes []commonDomain.MarketType) error {
+	if len(marketTypes) == 0 {
+		return nil
+	}
+	markets, err := s.lineSource.GetMarketsBySportTypes(sports, marketTypes)
+	if err != nil {
+		return err
+	}
+	for _, line := range lines {
+		line.Markets = markets[line.Type]
+	}
+	return nil
+}
+
+func (s *sportLineServiceImpl) calculateLineOfSports(lines []*commonDomain.SportLine, isNeedDelta bool, subs *model.ClientSubscription) ([]*commonDomain.SportLine, error) {
+	result := lines[:0]
+	for _, line := range lines {
+		send, err := s.calculateLine(line, isNeedDelta, subs)
+		if err != nil {
+			return nil, err
+		}
+		if send {
+			result = append(result, line)
+		}
 	}
 
-	return lines
+	return result, nil
 }
 
-func (s *sportLineServiceImpl) calculateLine(line *commonDomain.SportLine, isNeedDelta bool, subs *model.ClientSubscription) {
+func (s *sportLineServiceImpl) calculateLine(line *commonDomain.SportLine, isNeedDelta bool, subs *model.ClientSubscription) (bool, error) {
 	sportType := line.Type
+	score := line.Score
+	send := true
 	if isNeedDelta {
-		line.Score = line.Score - subs.Sports[sportType]
+		previous, ok := subs.Sports[sportType]
+		line.Score, send = subs.DeltaPolicy().Apply(score, previous)
+		if ok && subs.SkipsUnchanged() && score.Equal(previous) {
+			send = false
+		}
+	}
+	if send {
+		subs.Sports[sportType] = score
+	} else {
+		line.Score = decimal.Zero
+	}
+
+	markets := line.Markets[:0]
+	for _, market := range line.Markets {
+		marketSend, err := s.calculateMarket(sportType, market, isNeedDelta, subs)
+		if err != nil {
+			return false, err
+		}
+		if marketSend || len(market.Selections) == 0 {
+			markets = append(markets, market)
+		}
+		send = send || marketSend
+	}
+	line.Markets = markets
+	return send, nil
+}
+
+func (s *sportLineServiceImpl) calculateMarket(sportType commonDomain.SportType, market *commonDomain.Market, isNeedDelta bool, subs *model.ClientSubscription) (bool, error) {
+	if subs.Selections == nil {
+		subs.Selections = make(model.SelectionPriceMap)
+	}
+	if len(market.Selections) == 0 {
+		return false, nil
+	}
+	policy := subs.DeltaPolicy()
+	selections := market.Selections[:0]
+	for _, selection := range market.Selections {
+		key := model.SelectionKey{SportType: sportType, MarketType: market.Type, Selection: selection.Name}
+		price := selection.Price
+		previous, ok := subs.Selections[key]
+		var err error
+		if isNeedDelta && ok {
+			if subs.SkipsUnchanged() && price.Equal(previous) {
+				continue
+			}
+			var send bool
+			if selection.Price, send = policy.Apply(price, previous); !send {
+				continue
+			}
+			selection.Odds, err = policy.FormatOdds(subs.OddsFormat, price, previous)
+		} else {
+			selection.Odds, err = subs.OddsFormat.Format(price)
+		}
+		if err == commonDomain.ErrInvalidOdds {
+			s.logger.Warn(err, ": ", sportType, " ", market.Type, " ", selection.Name, " price ", price, " is sent in decimal odds")
+			selection.Odds, err = "", nil
+		}
+		if err != nil {
+			return false, err
+		}
+		subs.Selections[key] = price
+		selections = append(selections, selection)
+	}
+	market.Selections = selections
+	return len(selections) > 0, nil
+}
+
+func (s *sportLineServiceImpl) CalculateEvents(eventIDs []string, isNeedDelta bool, subs *model.ClientSubscription) ([]*commonDomain.Event, error) {
+	if subs == nil {
+		return nil, errors.ErrInvalidArgument
+	}
+	events, err := s.eventQueryService.GetEventsByIDs(eventIDs)
+	if err != nil {
+		return nil, err
+	}
+	if subs.Events == nil {
+		subs.Events = make(model.EventLineMap)
+	}
+	result := events[:0]
+	for _, event := range events {
+		line := event.Line
+		if previous, ok := subs.Events[event.ID]; isNeedDelta && ok {
+			if subs.SkipsUnchanged() && line.Equal(previous) {
+				continue
+			}
+			var send bool
+			if event.Line, send = subs.DeltaPolicy().Apply(line, previous); !send {
+				continue
+			}
+		}
+		subs.Events[event.ID] = line
+		result = append(result, event)
 	}
-	subs.Sports[sportType] = line.Score
+	return result, nil
 }
 
 func (s *sportLineServiceImpl) IsSubscriptionChanged(exist bool, subMap model.SportTypeMap, sports []commonDomain.SportType) bool {
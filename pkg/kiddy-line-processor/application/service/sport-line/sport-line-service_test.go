@@ -3,14 +3,19 @@ package sport_line
 import (
 	"github.com/col3name/lines/pkg/common/application/errors"
 	commonDomain "github.com/col3name/lines/pkg/common/domain"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/application/fake"
 	"github.com/col3name/lines/pkg/kiddy-line-processor/domain/model"
+	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
 	"testing"
+	"time"
 )
 
 type mockDB struct {
 	FakeGetSportLines func([]commonDomain.SportType) ([]*commonDomain.SportLine, error)
 	FakeStore         func(model *commonDomain.SportLine) error
+	FakeGetCandles    func(from time.Time, limit int) ([]*model.Candle, error)
+	FakeGetMarkets    func([]commonDomain.SportType, []commonDomain.MarketType) (map[commonDomain.SportType][]*commonDomain.Market, error)
 }
 
 func (m *mockDB) GetLinesBySportTypes(sportTypes []commonDomain.SportType) ([]*commonDomain.SportLine, error) {
@@ -20,6 +25,28 @@ func (m *mockDB) GetLinesBySportTypes(sportTypes []commonDomain.SportType) ([]*c
 	return m.FakeGetSportLines(sportTypes)
 }
 
+func (m *mockDB) GetMarketsBySportTypes(sportTypes []commonDomain.SportType, marketTypes []commonDomain.MarketType) (map[commonDomain.SportType][]*commonDomain.Market, error) {
+	if m.FakeGetMarkets == nil {
+		return map[commonDomain.SportType][]*commonDomain.Market{}, nil
+	}
+	return m.FakeGetMarkets(sportTypes, marketTypes)
+}
+
+func (m *mockDB) GetLinesAsOf(sportTypes []commonDomain.SportType, _ time.Time) ([]*commonDomain.SportLine, error) {
+	return m.GetLinesBySportTypes(sportTypes)
+}
+
+func (m *mockDB) GetLineHistory(_ commonDomain.SportType, _, _ time.Time, _ int, _ string) (*model.SportLineHistoryPage, error) {
+	return &model.SportLineHistoryPage{}, nil
+}
+
+func (m *mockDB) GetCandles(_ commonDomain.SportType, _ time.Duration, from, _ time.Time, limit int) ([]*model.Candle, error) {
+	if m.FakeGetCandles == nil {
+		return []*model.Candle{}, nil
+	}
+	return m.FakeGetCandles(from, limit)
+}
+
 func (m *mockDB) Store(model *commonDomain.SportLine) error {
 	if m.FakeStore == nil {
 		return nil
@@ -27,6 +54,18 @@ func (m *mockDB) Store(model *commonDomain.SportLine) error {
 	return m.FakeStore(model)
 }
 
+type mockEventDB struct {
+	FakeGetEventsByIDs func(ids []string) ([]*commonDomain.Event, error)
+}
+
+func (m *mockEventDB) GetEventsBySportType(commonDomain.SportType) ([]*commonDomain.Event, error) {
+	return []*commonDomain.Event{}, nil
+}
+
+func (m *mockEventDB) GetEventsByIDs(ids []string) ([]*commonDomain.Event, error) {
+	return m.FakeGetEventsByIDs(ids)
+}
+
 type isChangeInput struct {
 	exist  bool
 	subMap model.SportTypeMap
@@ -41,8 +80,9 @@ func compareSportLines(t *testing.T, expectedSportLines []*commonDomain.SportLin
 	assert.Equal(t, len(expectedSportLines), len(actualSportLines))
 	for i, line := range actualSportLines {
 		expectedLine := expectedSportLines[i]
-		assert.Equal(t, expectedLine.Score, line.Score)
+		assert.True(t, expectedLine.Score.Equal(line.Score), "expected %s, actual %s", expectedLine.Score, line.Score)
 		assert.Equal(t, expectedLine.Type, line.Type)
+		assert.Equal(t, expectedLine.Markets, line.Markets)
 	}
 }
 
@@ -69,8 +109,8 @@ func TestIsChanged(t *testing.T) {
 			mockDB: db,
 			input: &isChangeInput{
 				exist: false,
-				subMap: model.SportTypeMap(map[commonDomain.SportType]float32{
-					commonDomain.Baseball: 1.0,
+				subMap: model.SportTypeMap(map[commonDomain.SportType]decimal.Decimal{
+					commonDomain.Baseball: decimal.RequireFromString("1.0"),
 				}),
 				sports: []commonDomain.SportType{},
 			},
@@ -81,7 +121,7 @@ func TestIsChanged(t *testing.T) {
 			mockDB: db,
 			input: &isChangeInput{
 				exist:  false,
-				subMap: model.SportTypeMap(map[commonDomain.SportType]float32{}),
+				subMap: model.SportTypeMap(map[commonDomain.SportType]decimal.Decimal{}),
 				sports: []commonDomain.SportType{commonDomain.Baseball},
 			},
 			expected: true,
@@ -91,9 +131,9 @@ func TestIsChanged(t *testing.T) {
 			mockDB: db,
 			input: &isChangeInput{
 				exist: true,
-				subMap: model.SportTypeMap(map[commonDomain.SportType]float32{
-					commonDomain.Baseball: 1.0,
-					commonDomain.Soccer:   1.5,
+				subMap: model.SportTypeMap(map[commonDomain.SportType]decimal.Decimal{
+					commonDomain.Baseball: decimal.RequireFromString("1.0"),
+					commonDomain.Soccer:   decimal.RequireFromString("1.5"),
 				}),
 				sports: []commonDomain.SportType{commonDomain.Football},
 			},
@@ -104,8 +144,8 @@ func TestIsChanged(t *testing.T) {
 			mockDB: db,
 			input: &isChangeInput{
 				exist: true,
-				subMap: model.SportTypeMap(map[commonDomain.SportType]float32{
-					commonDomain.Soccer: 1.0,
+				subMap: model.SportTypeMap(map[commonDomain.SportType]decimal.Decimal{
+					commonDomain.Soccer: decimal.RequireFromString("1.0"),
 				}),
 				sports: []commonDomain.SportType{commonDomain.Baseball},
 			},
@@ -116,8 +156,8 @@ func TestIsChanged(t *testing.T) {
 			mockDB: db,
 			input: &isChangeInput{
 				exist: true,
-				subMap: model.SportTypeMap(map[commonDomain.SportType]float32{
-					commonDomain.Baseball: 1.0,
+				subMap: model.SportTypeMap(map[commonDomain.SportType]decimal.Decimal{
+					commonDomain.Baseball: decimal.RequireFromString("1.0"),
 				}),
 				sports: []commonDomain.SportType{commonDomain.Baseball},
 			},
@@ -126,7 +166,7 @@ func TestIsChanged(t *testing.T) {
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			service := NewSportLineService(test.mockDB)
+			service := NewSportLineService(test.mockDB, &mockEventDB{}, fake.Logger{})
 			input := test.input
 			result := service.IsSubscriptionChanged(input.exist, input.subMap, input.sports)
 			assert.Equal(t, test.expected, result)
@@ -143,6 +183,13 @@ type CalculateInput struct {
 type CalculateExpected struct {
 	err        error
 	sportLines []*commonDomain.SportLine
+	baseline   model.SportTypeMap
+	selections model.SelectionPriceMap
+}
+
+func deltaPolicy(mode model.DeltaMode, threshold string) model.DeltaPolicy {
+	policy, _ := model.NewDeltaPolicy(mode, decimal.RequireFromString(threshold))
+	return policy
 }
 
 func TestCalculates(t *testing.T) {
@@ -212,12 +259,12 @@ func TestCalculates(t *testing.T) {
 			},
 			mockDB: &mockDB{
 				FakeGetSportLines: func(types []commonDomain.SportType) ([]*commonDomain.SportLine, error) {
-					return []*commonDomain.SportLine{{Type: commonDomain.Baseball, Score: 1.5}}, nil
+					return []*commonDomain.SportLine{{Type: commonDomain.Baseball, Score: decimal.RequireFromString("1.5")}}, nil
 				},
 			},
 			expected: &CalculateExpected{
 				err:        nil,
-				sportLines: []*commonDomain.SportLine{{Type: commonDomain.Baseball, Score: 1.5}},
+				sportLines: []*commonDomain.SportLine{{Type: commonDomain.Baseball, Score: decimal.RequireFromString("1.5")}},
 			},
 		},
 		{
@@ -226,23 +273,427 @@ func TestCalculates(t *testing.T) {
 				isNeedDelta: true,
 				types:       []commonDomain.SportType{commonDomain.Baseball},
 				subs: &model.ClientSubscription{Sports: model.SportTypeMap{
-					commonDomain.Baseball: 1.5,
+					commonDomain.Baseball: decimal.RequireFromString("1.5"),
+				}},
+			},
+			mockDB: &mockDB{
+				FakeGetSportLines: func(types []commonDomain.SportType) ([]*commonDomain.SportLine, error) {
+					return []*commonDomain.SportLine{{Type: commonDomain.Baseball, Score: decimal.RequireFromString("1.0")}}, nil
+				},
+			},
+			expected: &CalculateExpected{
+				err:        nil,
+				sportLines: []*commonDomain.SportLine{{Type: commonDomain.Baseball, Score: decimal.RequireFromString("-0.5")}},
+				baseline:   model.SportTypeMap{commonDomain.Baseball: decimal.RequireFromString("1.0")},
+			},
+		},
+		{
+			name: "exact decimal delta",
+			input: &CalculateInput{
+				isNeedDelta: true,
+				types:       []commonDomain.SportType{commonDomain.Football},
+				subs: &model.ClientSubscription{Sports: model.SportTypeMap{
+					commonDomain.Football: decimal.RequireFromString("0.1"),
 				}},
 			},
 			mockDB: &mockDB{
 				FakeGetSportLines: func(types []commonDomain.SportType) ([]*commonDomain.SportLine, error) {
-					return []*commonDomain.SportLine{{Type: commonDomain.Baseball, Score: 1.0}}, nil
+					return []*commonDomain.SportLine{{Type: commonDomain.Football, Score: decimal.RequireFromString("0.4")}}, nil
 				},
 			},
 			expected: &CalculateExpected{
 				err:        nil,
-				sportLines: []*commonDomain.SportLine{{Type: commonDomain.Baseball, Score: -0.5}},
+				sportLines: []*commonDomain.SportLine{{Type: commonDomain.Football, Score: decimal.RequireFromString("0.3")}},
+				baseline:   model.SportTypeMap{commonDomain.Football: decimal.RequireFromString("0.4")},
+			},
+		},
+		{
+			name: "absolute delta mode",
+			input: &CalculateInput{
+				isNeedDelta: true,
+				types:       []commonDomain.SportType{commonDomain.Baseball},
+				subs: &model.ClientSubscription{
+					Sports: model.SportTypeMap{commonDomain.Baseball: decimal.RequireFromString("1.5")},
+					Delta:  deltaPolicy(model.DeltaAbsolute, "0"),
+				},
+			},
+			mockDB: &mockDB{
+				FakeGetSportLines: func(types []commonDomain.SportType) ([]*commonDomain.SportLine, error) {
+					return []*commonDomain.SportLine{{Type: commonDomain.Baseball, Score: decimal.RequireFromString("1.0")}}, nil
+				},
+			},
+			expected: &CalculateExpected{
+				sportLines: []*commonDomain.SportLine{{Type: commonDomain.Baseball, Score: decimal.RequireFromString("1.0")}},
+				baseline:   model.SportTypeMap{commonDomain.Baseball: decimal.RequireFromString("1.0")},
+			},
+		},
+		{
+			name: "percent delta mode",
+			input: &CalculateInput{
+				isNeedDelta: true,
+				types:       []commonDomain.SportType{commonDomain.Baseball},
+				subs: &model.ClientSubscription{
+					Sports: model.SportTypeMap{commonDomain.Baseball: decimal.RequireFromString("2.0")},
+					Delta:  deltaPolicy(model.DeltaPercent, "0"),
+				},
+			},
+			mockDB: &mockDB{
+				FakeGetSportLines: func(types []commonDomain.SportType) ([]*commonDomain.SportLine, error) {
+					return []*commonDomain.SportLine{{Type: commonDomain.Baseball, Score: decimal.RequireFromString("2.5")}}, nil
+				},
+			},
+			expected: &CalculateExpected{
+				sportLines: []*commonDomain.SportLine{{Type: commonDomain.Baseball, Score: decimal.RequireFromString("25")}},
+				baseline:   model.SportTypeMap{commonDomain.Baseball: decimal.RequireFromString("2.5")},
+			},
+		},
+		{
+			name: "threshold delta mode below threshold",
+			input: &CalculateInput{
+				isNeedDelta: true,
+				types:       []commonDomain.SportType{commonDomain.Baseball},
+				subs: &model.ClientSubscription{
+					Sports: model.SportTypeMap{commonDomain.Baseball: decimal.RequireFromString("1.0")},
+					Delta:  deltaPolicy(model.DeltaThreshold, "0.5"),
+				},
+			},
+			mockDB: &mockDB{
+				FakeGetSportLines: func(types []commonDomain.SportType) ([]*commonDomain.SportLine, error) {
+					return []*commonDomain.SportLine{{Type: commonDomain.Baseball, Score: decimal.RequireFromString("1.2")}}, nil
+				},
+			},
+			expected: &CalculateExpected{
+				sportLines: []*commonDomain.SportLine{},
+				baseline:   model.SportTypeMap{commonDomain.Baseball: decimal.RequireFromString("1.0")},
+			},
+		},
+		{
+			name: "threshold delta mode above threshold",
+			input: &CalculateInput{
+				isNeedDelta: true,
+				types:       []commonDomain.SportType{commonDomain.Baseball},
+				subs: &model.ClientSubscription{
+					Sports: model.SportTypeMap{commonDomain.Baseball: decimal.RequireFromString("1.0")},
+					Delta:  deltaPolicy(model.DeltaThreshold, "0.5"),
+				},
+			},
+			mockDB: &mockDB{
+				FakeGetSportLines: func(types []commonDomain.SportType) ([]*commonDomain.SportLine, error) {
+					return []*commonDomain.SportLine{{Type: commonDomain.Baseball, Score: decimal.RequireFromString("1.8")}}, nil
+				},
+			},
+			expected: &CalculateExpected{
+				sportLines: []*commonDomain.SportLine{{Type: commonDomain.Baseball, Score: decimal.RequireFromString("0.8")}},
+				baseline:   model.SportTypeMap{commonDomain.Baseball: decimal.RequireFromString("1.8")},
+			},
+		},
+		{
+			name: "threshold delta mode of selections",
+			input: &CalculateInput{
+				isNeedDelta: true,
+				types:       []commonDomain.SportType{commonDomain.Soccer},
+				subs: &model.ClientSubscription{
+					Sports:  model.SportTypeMap{commonDomain.Soccer: decimal.RequireFromString("1.0")},
+					Markets: []commonDomain.MarketType{commonDomain.Moneyline},
+					Selections: model.SelectionPriceMap{
+						{SportType: commonDomain.Soccer, MarketType: commonDomain.Moneyline, Selection: "home"}: decimal.RequireFromString("2.0"),
+						{SportType: commonDomain.Soccer, MarketType: commonDomain.Moneyline, Selection: "away"}: decimal.RequireFromString("1.5"),
+					},
+					Delta: deltaPolicy(model.DeltaThreshold, "0.5"),
+				},
+			},
+			mockDB: &mockDB{
+				FakeGetSportLines: func(types []commonDomain.SportType) ([]*commonDomain.SportLine, error) {
+					return []*commonDomain.SportLine{{Type: commonDomain.Soccer, Score: decimal.RequireFromString("1.1")}}, nil
+				},
+				FakeGetMarkets: func([]commonDomain.SportType, []commonDomain.MarketType) (map[commonDomain.SportType][]*commonDomain.Market, error) {
+					return map[commonDomain.SportType][]*commonDomain.Market{
+						commonDomain.Soccer: {{
+							Type: commonDomain.Moneyline,
+							Selections: []*commonDomain.Selection{
+								{Name: "home", Price: decimal.RequireFromString("2.1")},
+								{Name: "away", Price: decimal.RequireFromString("2.5")},
+							},
+						}},
+					}, nil
+				},
+			},
+			expected: &CalculateExpected{
+				sportLines: []*commonDomain.SportLine{{
+					Type:  commonDomain.Soccer,
+					Score: decimal.Zero,
+					Markets: []*commonDomain.Market{{
+						Type:       commonDomain.Moneyline,
+						Selections: []*commonDomain.Selection{{Name: "away", Price: decimal.RequireFromString("1.0"), Odds: "1"}},
+					}},
+				}},
+				baseline: model.SportTypeMap{commonDomain.Soccer: decimal.RequireFromString("1.0")},
+				selections: model.SelectionPriceMap{
+					{SportType: commonDomain.Soccer, MarketType: commonDomain.Moneyline, Selection: "home"}: decimal.RequireFromString("2.0"),
+					{SportType: commonDomain.Soccer, MarketType: commonDomain.Moneyline, Selection: "away"}: decimal.RequireFromString("2.5"),
+				},
+			},
+		},
+		{
+			name: "on change mode drops unchanged sports and selections",
+			input: &CalculateInput{
+				isNeedDelta: true,
+				types:       []commonDomain.SportType{commonDomain.Soccer, commonDomain.Baseball},
+				subs: &model.ClientSubscription{
+					Sports: model.SportTypeMap{
+						commonDomain.Soccer:   decimal.RequireFromString("1.0"),
+						commonDomain.Baseball: decimal.RequireFromString("2.0"),
+					},
+					Markets: []commonDomain.MarketType{commonDomain.Moneyline},
+					Selections: model.SelectionPriceMap{
+						{SportType: commonDomain.Soccer, MarketType: commonDomain.Moneyline, Selection: "home"}: decimal.RequireFromString("2.0"),
+						{SportType: commonDomain.Soccer, MarketType: commonDomain.Moneyline, Selection: "away"}: decimal.RequireFromString("1.5"),
+					},
+					Mode: model.DeliveryOnChange,
+				},
+			},
+			mockDB: &mockDB{
+				FakeGetSportLines: func(types []commonDomain.SportType) ([]*commonDomain.SportLine, error) {
+					return []*commonDomain.SportLine{
+						{Type: commonDomain.Soccer, Score: decimal.RequireFromString("1.0")},
+						{Type: commonDomain.Baseball, Score: decimal.RequireFromString("2.00")},
+					}, nil
+				},
+				FakeGetMarkets: func([]commonDomain.SportType, []commonDomain.MarketType) (map[commonDomain.SportType][]*commonDomain.Market, error) {
+					return map[commonDomain.SportType][]*commonDomain.Market{
+						commonDomain.Soccer: {{
+							Type: commonDomain.Moneyline,
+							Selections: []*commonDomain.Selection{
+								{Name: "home", Price: decimal.RequireFromString("2.0")},
+								{Name: "away", Price: decimal.RequireFromString("1.7")},
+							},
+						}},
+					}, nil
+				},
+			},
+			expected: &CalculateExpected{
+				sportLines: []*commonDomain.SportLine{{
+					Type:  commonDomain.Soccer,
+					Score: decimal.Zero,
+					Markets: []*commonDomain.Market{{
+						Type:       commonDomain.Moneyline,
+						Selections: []*commonDomain.Selection{{Name: "away", Price: decimal.RequireFromString("0.2"), Odds: "0.2"}},
+					}},
+				}},
+				baseline: model.SportTypeMap{
+					commonDomain.Soccer:   decimal.RequireFromString("1.0"),
+					commonDomain.Baseball: decimal.RequireFromString("2.0"),
+				},
+				selections: model.SelectionPriceMap{
+					{SportType: commonDomain.Soccer, MarketType: commonDomain.Moneyline, Selection: "home"}: decimal.RequireFromString("2.0"),
+					{SportType: commonDomain.Soccer, MarketType: commonDomain.Moneyline, Selection: "away"}: decimal.RequireFromString("1.7"),
+				},
+			},
+		},
+		{
+			name: "percent delta mode of selections",
+			input: &CalculateInput{
+				isNeedDelta: true,
+				types:       []commonDomain.SportType{commonDomain.Soccer},
+				subs: &model.ClientSubscription{
+					Sports:     model.SportTypeMap{commonDomain.Soccer: decimal.RequireFromString("1.0")},
+					Markets:    []commonDomain.MarketType{commonDomain.Moneyline},
+					OddsFormat: commonDomain.OddsFractional,
+					Selections: model.SelectionPriceMap{
+						{SportType: commonDomain.Soccer, MarketType: commonDomain.Moneyline, Selection: "home"}: decimal.RequireFromString("2.0"),
+						{SportType: commonDomain.Soccer, MarketType: commonDomain.Moneyline, Selection: "away"}: decimal.RequireFromString("1.5"),
+					},
+					Delta: deltaPolicy(model.DeltaPercent, "0"),
+				},
+			},
+			mockDB: &mockDB{
+				FakeGetSportLines: func(types []commonDomain.SportType) ([]*commonDomain.SportLine, error) {
+					return []*commonDomain.SportLine{{Type: commonDomain.Soccer, Score: decimal.RequireFromString("1.1")}}, nil
+				},
+				FakeGetMarkets: func([]commonDomain.SportType, []commonDomain.MarketType) (map[commonDomain.SportType][]*commonDomain.Market, error) {
+					return map[commonDomain.SportType][]*commonDomain.Market{
+						commonDomain.Soccer: {{
+							Type: commonDomain.Moneyline,
+							Selections: []*commonDomain.Selection{
+								{Name: "home", Price: decimal.RequireFromString("2.5")},
+								{Name: "away", Price: decimal.RequireFromString("1.2")},
+							},
+						}},
+					}, nil
+				},
+			},
+			expected: &CalculateExpected{
+				sportLines: []*commonDomain.SportLine{{
+					Type:  commonDomain.Soccer,
+					Score: decimal.RequireFromString("10"),
+					Markets: []*commonDomain.Market{{
+						Type: commonDomain.Moneyline,
+						Selections: []*commonDomain.Selection{
+							{Name: "home", Price: decimal.RequireFromString("25.0000")},
+							{Name: "away", Price: decimal.RequireFromString("-20.0000")},
+						},
+					}},
+				}},
+				baseline: model.SportTypeMap{commonDomain.Soccer: decimal.RequireFromString("1.1")},
+				selections: model.SelectionPriceMap{
+					{SportType: commonDomain.Soccer, MarketType: commonDomain.Moneyline, Selection: "home"}: decimal.RequireFromString("2.5"),
+					{SportType: commonDomain.Soccer, MarketType: commonDomain.Moneyline, Selection: "away"}: decimal.RequireFromString("1.2"),
+				},
+			},
+		},
+		{
+			name: "failed get markets",
+			input: &CalculateInput{
+				isNeedDelta: false,
+				types:       []commonDomain.SportType{commonDomain.Soccer},
+				subs: &model.ClientSubscription{
+					Sports:  make(model.SportTypeMap),
+					Markets: []commonDomain.MarketType{commonDomain.Moneyline},
+				},
+			},
+			mockDB: &mockDB{
+				FakeGetSportLines: func(types []commonDomain.SportType) ([]*commonDomain.SportLine, error) {
+					return []*commonDomain.SportLine{{Type: commonDomain.Soccer, Score: decimal.RequireFromString("1.0")}}, nil
+				},
+				FakeGetMarkets: func([]commonDomain.SportType, []commonDomain.MarketType) (map[commonDomain.SportType][]*commonDomain.Market, error) {
+					return nil, errors.ErrInternal
+				},
+			},
+			expected: &CalculateExpected{err: errors.ErrInternal, sportLines: nil},
+		},
+		{
+			name: "need delta of subscribed markets",
+			input: &CalculateInput{
+				isNeedDelta: true,
+				types:       []commonDomain.SportType{commonDomain.Soccer},
+				subs: &model.ClientSubscription{
+					Sports:  model.SportTypeMap{commonDomain.Soccer: decimal.RequireFromString("1.0")},
+					Markets: []commonDomain.MarketType{commonDomain.Moneyline},
+					Selections: model.SelectionPriceMap{
+						{SportType: commonDomain.Soccer, MarketType: commonDomain.Moneyline, Selection: "home"}: decimal.RequireFromString("2.0"),
+					},
+				},
+			},
+			mockDB: &mockDB{
+				FakeGetSportLines: func(types []commonDomain.SportType) ([]*commonDomain.SportLine, error) {
+					return []*commonDomain.SportLine{{Type: commonDomain.Soccer, Score: decimal.RequireFromString("1.5")}}, nil
+				},
+				FakeGetMarkets: func([]commonDomain.SportType, []commonDomain.MarketType) (map[commonDomain.SportType][]*commonDomain.Market, error) {
+					return map[commonDomain.SportType][]*commonDomain.Market{
+						commonDomain.Soccer: {{
+							Type:       commonDomain.Moneyline,
+							Selections: []*commonDomain.Selection{{Name: "home", Price: decimal.RequireFromString("2.5")}},
+						}},
+					}, nil
+				},
+			},
+			expected: &CalculateExpected{
+				err: nil,
+				sportLines: []*commonDomain.SportLine{{
+					Type:  commonDomain.Soccer,
+					Score: decimal.RequireFromString("0.5"),
+					Markets: []*commonDomain.Market{{
+						Type:       commonDomain.Moneyline,
+						Selections: []*commonDomain.Selection{{Name: "home", Price: decimal.RequireFromString("0.5"), Odds: "0.5"}},
+					}},
+				}},
+				baseline: model.SportTypeMap{commonDomain.Soccer: decimal.RequireFromString("1.5")},
+				selections: model.SelectionPriceMap{
+					{SportType: commonDomain.Soccer, MarketType: commonDomain.Moneyline, Selection: "home"}: decimal.RequireFromString("2.5"),
+				},
+			},
+		},
+		{
+			name: "need delta of subscribed markets in american odds",
+			input: &CalculateInput{
+				isNeedDelta: true,
+				types:       []commonDomain.SportType{commonDomain.Soccer},
+				subs: &model.ClientSubscription{
+					Sports:     model.SportTypeMap{commonDomain.Soccer: decimal.RequireFromString("1.0")},
+					Markets:    []commonDomain.MarketType{commonDomain.Moneyline},
+					OddsFormat: commonDomain.OddsAmerican,
+					Selections: model.SelectionPriceMap{
+						{SportType: commonDomain.Soccer, MarketType: commonDomain.Moneyline, Selection: "home"}: decimal.RequireFromString("2.5"),
+					},
+				},
+			},
+			mockDB: &mockDB{
+				FakeGetSportLines: func(types []commonDomain.SportType) ([]*commonDomain.SportLine, error) {
+					return []*commonDomain.SportLine{{Type: commonDomain.Soccer, Score: decimal.RequireFromString("1.0")}}, nil
+				},
+				FakeGetMarkets: func([]commonDomain.SportType, []commonDomain.MarketType) (map[commonDomain.SportType][]*commonDomain.Market, error) {
+					return map[commonDomain.SportType][]*commonDomain.Market{
+						commonDomain.Soccer: {{
+							Type: commonDomain.Moneyline,
+							Selections: []*commonDomain.Selection{
+								{Name: "home", Price: decimal.RequireFromString("2.7")},
+								{Name: "away", Price: decimal.RequireFromString("1.5")},
+							},
+						}},
+					}, nil
+				},
+			},
+			expected: &CalculateExpected{
+				err: nil,
+				sportLines: []*commonDomain.SportLine{{
+					Type:  commonDomain.Soccer,
+					Score: decimal.RequireFromString("0.0"),
+					Markets: []*commonDomain.Market{{
+						Type: commonDomain.Moneyline,
+						Selections: []*commonDomain.Selection{
+							{Name: "home", Price: decimal.RequireFromString("0.2"), Odds: "+20"},
+							{Name: "away", Price: decimal.RequireFromString("1.5"), Odds: "-200"},
+						},
+					}},
+				}},
+			},
+		},
+		{
+			name: "price can not be expressed in odds format",
+			input: &CalculateInput{
+				isNeedDelta: false,
+				types:       []commonDomain.SportType{commonDomain.Soccer},
+				subs: &model.ClientSubscription{
+					Sports:     make(model.SportTypeMap),
+					Markets:    []commonDomain.MarketType{commonDomain.Moneyline},
+					OddsFormat: commonDomain.OddsFractional,
+				},
+			},
+			mockDB: &mockDB{
+				FakeGetSportLines: func(types []commonDomain.SportType) ([]*commonDomain.SportLine, error) {
+					return []*commonDomain.SportLine{{Type: commonDomain.Soccer, Score: decimal.RequireFromString("1.0")}}, nil
+				},
+				FakeGetMarkets: func([]commonDomain.SportType, []commonDomain.MarketType) (map[commonDomain.SportType][]*commonDomain.Market, error) {
+					return map[commonDomain.SportType][]*commonDomain.Market{
+						commonDomain.Soccer: {{
+							Type: commonDomain.Moneyline,
+							Selections: []*commonDomain.Selection{
+								{Name: "home", Price: decimal.RequireFromString("0.9")},
+								{Name: "away", Price: decimal.RequireFromString("2.5")},
+							},
+						}},
+					}, nil
+				},
+			},
+			expected: &CalculateExpected{
+				err: nil,
+				sportLines: []*commonDomain.SportLine{{
+					Type:  commonDomain.Soccer,
+					Score: decimal.RequireFromString("1.0"),
+					Markets: []*commonDomain.Market{{
+						Type: commonDomain.Moneyline,
+						Selections: []*commonDomain.Selection{
+							{Name: "home", Price: decimal.RequireFromString("0.9")},
+							{Name: "away", Price: decimal.RequireFromString("2.5"), Odds: "3/2"},
+						},
+					}},
+				}},
 			},
 		},
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			service := NewSportLineService(test.mockDB)
+			service := NewSportLineService(test.mockDB, &mockEventDB{}, fake.Logger{})
 			input := test.input
 			actualSportLines, err := service.Calculate(input.types, input.isNeedDelta, input.subs)
 			expected := test.expected
@@ -253,6 +704,86 @@ func TestCalculates(t *testing.T) {
 			}
 
 			compareSportLines(t, expected.sportLines, actualSportLines)
+			if expected.baseline != nil {
+				assert.Equal(t, expected.baseline, input.subs.Sports)
+			}
+			if expected.selections != nil {
+				assert.Equal(t, expected.selections, input.subs.Selections)
+			}
+		})
+	}
+}
+
+func TestCalculateEvents(t *testing.T) {
+	tests := []struct {
+		name        string
+		isNeedDelta bool
+		subs        *model.ClientSubscription
+		events      []*commonDomain.Event
+		getErr      error
+		err         error
+		expected    []decimal.Decimal
+		baseline    model.EventLineMap
+	}{
+		{name: "subs nil", subs: nil, err: errors.ErrInvalidArgument},
+		{name: "failed get events", subs: &model.ClientSubscription{}, getErr: errors.ErrInternal, err: errors.ErrInternal},
+		{
+			name:        "does not need delta",
+			isNeedDelta: false,
+			subs:        &model.ClientSubscription{},
+			events:      []*commonDomain.Event{{ID: "soccer-1", Line: decimal.RequireFromString("1.5")}},
+			expected:    []decimal.Decimal{decimal.RequireFromString("1.5")},
+			baseline:    model.EventLineMap{"soccer-1": decimal.RequireFromString("1.5")},
+		},
+		{
+			name:        "need delta",
+			isNeedDelta: true,
+			subs:        &model.ClientSubscription{Events: model.EventLineMap{"soccer-1": decimal.RequireFromString("1.0")}},
+			events:      []*commonDomain.Event{{ID: "soccer-1", Line: decimal.RequireFromString("1.5")}, {ID: "soccer-2", Line: decimal.RequireFromString("2.0")}},
+			expected:    []decimal.Decimal{decimal.RequireFromString("0.5"), decimal.RequireFromString("2.0")},
+			baseline:    model.EventLineMap{"soccer-1": decimal.RequireFromString("1.5"), "soccer-2": decimal.RequireFromString("2.0")},
+		},
+		{
+			name:        "threshold delta mode",
+			isNeedDelta: true,
+			subs: &model.ClientSubscription{
+				Events: model.EventLineMap{"soccer-1": decimal.RequireFromString("1.0"), "soccer-2": decimal.RequireFromString("1.0")},
+				Delta:  deltaPolicy(model.DeltaThreshold, "0.3"),
+			},
+			events:   []*commonDomain.Event{{ID: "soccer-1", Line: decimal.RequireFromString("1.2")}, {ID: "soccer-2", Line: decimal.RequireFromString("1.5")}},
+			expected: []decimal.Decimal{decimal.RequireFromString("0.5")},
+			baseline: model.EventLineMap{"soccer-1": decimal.RequireFromString("1.0"), "soccer-2": decimal.RequireFromString("1.5")},
+		},
+		{
+			name:        "on change mode drops unchanged events",
+			isNeedDelta: true,
+			subs: &model.ClientSubscription{
+				Events: model.EventLineMap{"soccer-1": decimal.RequireFromString("1.5"), "soccer-2": decimal.RequireFromString("1.0")},
+				Mode:   model.DeliveryOnChange,
+			},
+			events:   []*commonDomain.Event{{ID: "soccer-1", Line: decimal.RequireFromString("1.50")}, {ID: "soccer-2", Line: decimal.RequireFromString("1.2")}},
+			expected: []decimal.Decimal{decimal.RequireFromString("0.2")},
+			baseline: model.EventLineMap{"soccer-1": decimal.RequireFromString("1.5"), "soccer-2": decimal.RequireFromString("1.2")},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			eventDB := &mockEventDB{FakeGetEventsByIDs: func(ids []string) ([]*commonDomain.Event, error) {
+				return test.events, test.getErr
+			}}
+			service := NewSportLineService(&mockDB{}, eventDB, fake.Logger{})
+			events, err := service.CalculateEvents([]string{"soccer-1", "soccer-2"}, test.isNeedDelta, test.subs)
+			assert.Equal(t, test.err, err)
+			if test.err != nil {
+				assert.Nil(t, events)
+				return
+			}
+			lines := make([]decimal.Decimal, 0, len(events))
+			for _, event := range events {
+				lines = append(lines, event.Line)
+			}
+			assert.Equal(t, test.expected, lines)
+			assert.Equal(t, test.baseline, test.subs.Events)
 		})
 	}
 }
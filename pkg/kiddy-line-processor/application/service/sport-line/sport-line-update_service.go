@@ -4,23 +4,36 @@ import (
 	commonDomain "github.com/col3name/lines/pkg/common/domain"
 	"github.com/col3name/lines/pkg/kiddy-line-processor/application/adapter"
 	"github.com/col3name/lines/pkg/kiddy-line-processor/application/service"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/domain/model"
+	"sync"
+	"time"
 )
 
 type SportLinesUpdateService interface {
 	Update(sportType commonDomain.SportType) error
+	UpdateEvents(sportType commonDomain.SportType) error
 }
 
 type sportLinesUpdateService struct {
 	updatePeriod         int
 	linesProviderAdapter adapter.LinesProviderAdapter
 	uow                  service.UnitOfWork
+	mu                   sync.Mutex
+	lastLines            map[commonDomain.SportType]*commonDomain.SportLine
+	lastEvents           map[commonDomain.SportType][]*commonDomain.Event
 }
 
-func NewSportLinesUpdateService(updatePeriod int, linesProviderAdapter adapter.LinesProviderAdapter, uow service.UnitOfWork) *sportLinesUpdateService {
+func NewSportLinesUpdateService(
+	updatePeriod int,
+	linesProviderAdapter adapter.LinesProviderAdapter,
+	uow service.UnitOfWork,
+) *sportLinesUpdateService {
 	return &sportLinesUpdateService{
 		updatePeriod:         updatePeriod,
 		linesProviderAdapter: linesProviderAdapter,
 		uow:                  uow,
+		lastLines:            make(map[commonDomain.SportType]*commonDomain.SportLine),
+		lastEvents:           make(map[commonDomain.SportType][]*commonDomain.Event),
 	}
 }
 
@@ -30,10 +43,119 @@ func (s *sportLinesUpdateService) Update(sportType commonDomain.SportType) error
 		return err
 	}
 
+	fetchedAt := time.Now()
+	isChanged := s.isLineChanged(sportType, sportLine)
+
 	job := func(rp service.RepositoryProvider) error {
 		sportLineRepo := rp.SportLineRepo()
-		return sportLineRepo.Store(sportLine)
+		if err = sportLineRepo.Store(sportLine); err != nil {
+			return err
+		}
+		historyRepo := rp.SportLineHistoryRepo()
+		err = historyRepo.Append(&model.SportLineHistoryRecord{
+			Line:       *sportLine,
+			FetchedAt:  fetchedAt,
+			IngestedAt: time.Now(),
+		})
+		if err != nil || !isChanged {
+			return err
+		}
+		return rp.NotifyRepo().NotifyLineChanged(sportType)
+	}
+
+	if err = s.uow.Execute(job); err != nil {
+		return err
+	}
+	s.rememberLine(sportType, sportLine)
+	return nil
+}
+
+func (s *sportLinesUpdateService) UpdateEvents(sportType commonDomain.SportType) error {
+	events, err := s.linesProviderAdapter.GetEventsBySport(sportType)
+	if err != nil {
+		return err
+	}
+	if len(events) == 0 {
+		return nil
+	}
+
+	isChanged := s.isEventsChanged(sportType, events)
+
+	job := func(rp service.RepositoryProvider) error {
+		if err = rp.EventRepo().Store(events); err != nil || !isChanged {
+			return err
+		}
+		return rp.NotifyRepo().NotifyEventsChanged(sportType)
+	}
+
+	if err = s.uow.Execute(job); err != nil {
+		return err
+	}
+	s.rememberEvents(sportType, events)
+	return nil
+}
+
+func (s *sportLinesUpdateService) isLineChanged(sportType commonDomain.SportType, sportLine *commonDomain.SportLine) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	previous, ok := s.lastLines[sportType]
+	return !ok || isSportLineChanged(previous, sportLine)
+}
+
+func (s *sportLinesUpdateService) rememberLine(sportType commonDomain.SportType, sportLine *commonDomain.SportLine) {
+	s.mu.Lock()
+	s.lastLines[sportType] = sportLine
+	s.mu.Unlock()
+}
+
+func (s *sportLinesUpdateService) isEventsChanged(sportType commonDomain.SportType, events []*commonDomain.Event) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	previous, ok := s.lastEvents[sportType]
+	return !ok || isEventsChanged(previous, events)
+}
+
+func (s *sportLinesUpdateService) rememberEvents(sportType commonDomain.SportType, events []*commonDomain.Event) {
+	s.mu.Lock()
+	s.lastEvents[sportType] = events
+	s.mu.Unlock()
+}
+
+func isSportLineChanged(previous, current *commonDomain.SportLine) bool {
+	if !previous.Score.Equal(current.Score) || len(previous.Markets) != len(current.Markets) {
+		return true
+	}
+	for i, market := range current.Markets {
+		if isMarketChanged(previous.Markets[i], market) {
+			return true
+		}
 	}
+	return false
+}
 
-	return s.uow.Execute(job)
+func isMarketChanged(previous, current *commonDomain.Market) bool {
+	if previous.Type != current.Type || !previous.Point.Equal(current.Point) ||
+		len(previous.Selections) != len(current.Selections) {
+		return true
+	}
+	for i, selection := range current.Selections {
+		prevSelection := previous.Selections[i]
+		if prevSelection.Name != selection.Name || !prevSelection.Price.Equal(selection.Price) {
+			return true
+		}
+	}
+	return false
+}
+
+func isEventsChanged(previous, current []*commonDomain.Event) bool {
+	if len(previous) != len(current) {
+		return true
+	}
+	for i, event := range current {
+		prevEvent := previous[i]
+		if prevEvent.ID != event.ID || prevEvent.Status != event.Status || !prevEvent.Line.Equal(event.Line) {
+			return true
+		}
+	}
+	return false
 }
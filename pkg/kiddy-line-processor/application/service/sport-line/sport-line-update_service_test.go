@@ -0,0 +1,318 @@
+package sport_line
+
+import (
+	"errors"
+	commonDomain "github.com/col3name/lines/pkg/common/domain"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/application/service"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/domain/model"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/domain/repo"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type mockLinesProviderAdapter struct {
+	FakeGetLineBySport   func(sportType commonDomain.SportType) (*commonDomain.SportLine, error)
+	FakeGetEventsBySport func(sportType commonDomain.SportType) ([]*commonDomain.Event, error)
+}
+
+func (m *mockLinesProviderAdapter) GetLineBySport(sportType commonDomain.SportType) (*commonDomain.SportLine, error) {
+	return m.FakeGetLineBySport(sportType)
+}
+
+func (m *mockLinesProviderAdapter) GetEventsBySport(sportType commonDomain.SportType) ([]*commonDomain.Event, error) {
+	return m.FakeGetEventsBySport(sportType)
+}
+
+type mockEventRepo struct {
+	FakeStore func(events []*commonDomain.Event) error
+	events    []*commonDomain.Event
+}
+
+func (m *mockEventRepo) Store(events []*commonDomain.Event) error {
+	if m.FakeStore != nil {
+		if err := m.FakeStore(events); err != nil {
+			return err
+		}
+	}
+	m.events = append(m.events, events...)
+	return nil
+}
+
+type mockHistoryRepo struct {
+	FakeAppend func(record *model.SportLineHistoryRecord) error
+	records    []*model.SportLineHistoryRecord
+}
+
+func (m *mockHistoryRepo) Append(record *model.SportLineHistoryRecord) error {
+	m.records = append(m.records, record)
+	if m.FakeAppend == nil {
+		return nil
+	}
+	return m.FakeAppend(record)
+}
+
+type mockRepositoryProvider struct {
+	sportLineRepo *mockDB
+	historyRepo   *mockHistoryRepo
+	eventRepo     *mockEventRepo
+	notifyRepo    *mockNotifyRepo
+}
+
+func (m *mockRepositoryProvider) SportLineRepo() repo.SportLineRepo {
+	return m.sportLineRepo
+}
+
+func (m *mockRepositoryProvider) SportLineHistoryRepo() repo.SportLineHistoryRepo {
+	return m.historyRepo
+}
+
+func (m *mockRepositoryProvider) NotifyRepo() repo.NotifyRepo {
+	return m.notifyRepo
+}
+
+func (m *mockRepositoryProvider) SportRepo() repo.SportRepo {
+	return nil
+}
+
+func (m *mockRepositoryProvider) EventRepo() repo.EventRepo {
+	return m.eventRepo
+}
+
+func (m *mockRepositoryProvider) MigrationRepo() repo.MigrationRepo {
+	return nil
+}
+
+type mockNotifyRepo struct {
+	FakeNotify func(sportType commonDomain.SportType) error
+	notified   []commonDomain.SportType
+	kinds      []model.ChangeKind
+}
+
+func (m *mockNotifyRepo) NotifyLineChanged(sportType commonDomain.SportType) error {
+	return m.notify(sportType, model.LineChanged)
+}
+
+func (m *mockNotifyRepo) NotifyEventsChanged(sportType commonDomain.SportType) error {
+	return m.notify(sportType, model.EventsChanged)
+}
+
+func (m *mockNotifyRepo) notify(sportType commonDomain.SportType, kind model.ChangeKind) error {
+	if m.FakeNotify != nil {
+		if err := m.FakeNotify(sportType); err != nil {
+			return err
+		}
+	}
+	m.notified = append(m.notified, sportType)
+	m.kinds = append(m.kinds, kind)
+	return nil
+}
+
+type mockUnitOfWork struct {
+	provider   *mockRepositoryProvider
+	countCalls int
+}
+
+func (m *mockUnitOfWork) Execute(fn service.Job) error {
+	m.countCalls++
+	return fn(m.provider)
+}
+
+type expectedUpdate struct {
+	err             error
+	countUowCalls   int
+	countHistoryRec int
+}
+
+func TestUpdate(t *testing.T) {
+	fakeErr := errors.New("fake error")
+	tests := []struct {
+		name      string
+		adapter   *mockLinesProviderAdapter
+		fakeStore func(model *commonDomain.SportLine) error
+		expected  *expectedUpdate
+	}{
+		{
+			name: "failed fetch line from provider",
+			adapter: &mockLinesProviderAdapter{
+				FakeGetLineBySport: func(sportType commonDomain.SportType) (*commonDomain.SportLine, error) {
+					return nil, fakeErr
+				},
+			},
+			expected: &expectedUpdate{err: fakeErr, countUowCalls: 0, countHistoryRec: 0},
+		},
+		{
+			name: "failed store line does not write history",
+			adapter: &mockLinesProviderAdapter{
+				FakeGetLineBySport: func(sportType commonDomain.SportType) (*commonDomain.SportLine, error) {
+					return &commonDomain.SportLine{Type: sportType, Score: decimal.RequireFromString("1.5")}, nil
+				},
+			},
+			fakeStore: func(model *commonDomain.SportLine) error {
+				return fakeErr
+			},
+			expected: &expectedUpdate{err: fakeErr, countUowCalls: 1, countHistoryRec: 0},
+		},
+		{
+			name: "success update writes history in same unit of work",
+			adapter: &mockLinesProviderAdapter{
+				FakeGetLineBySport: func(sportType commonDomain.SportType) (*commonDomain.SportLine, error) {
+					return &commonDomain.SportLine{Type: sportType, Score: decimal.RequireFromString("1.5")}, nil
+				},
+			},
+			expected: &expectedUpdate{err: nil, countUowCalls: 1, countHistoryRec: 1},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			historyRepo := &mockHistoryRepo{}
+			uow := &mockUnitOfWork{provider: &mockRepositoryProvider{
+				sportLineRepo: &mockDB{FakeStore: test.fakeStore},
+				historyRepo:   historyRepo,
+				notifyRepo:    &mockNotifyRepo{},
+			}}
+			updateService := NewSportLinesUpdateService(1, test.adapter, uow)
+
+			err := updateService.Update(commonDomain.Soccer)
+			expected := test.expected
+			assert.Equal(t, expected.err, err)
+			assert.Equal(t, expected.countUowCalls, uow.countCalls)
+			assert.Equal(t, expected.countHistoryRec, len(historyRepo.records))
+			for _, record := range historyRepo.records {
+				assert.Equal(t, commonDomain.Soccer, record.Line.Type)
+				assert.Equal(t, decimal.RequireFromString("1.5"), record.Line.Score)
+				assert.False(t, record.FetchedAt.IsZero())
+				assert.False(t, record.IngestedAt.Before(record.FetchedAt))
+			}
+		})
+	}
+}
+
+func TestUpdateEvents(t *testing.T) {
+	fakeErr := errors.New("fake error")
+	tests := []struct {
+		name          string
+		fakeGetEvents func(sportType commonDomain.SportType) ([]*commonDomain.Event, error)
+		fakeStore     func(events []*commonDomain.Event) error
+		err           error
+		countUowCalls int
+		countEvents   int
+	}{
+		{
+			name: "failed fetch events from provider",
+			fakeGetEvents: func(sportType commonDomain.SportType) ([]*commonDomain.Event, error) {
+				return nil, fakeErr
+			},
+			err: fakeErr, countUowCalls: 0, countEvents: 0,
+		},
+		{
+			name: "no events does not open unit of work",
+			fakeGetEvents: func(sportType commonDomain.SportType) ([]*commonDomain.Event, error) {
+				return []*commonDomain.Event{}, nil
+			},
+			err: nil, countUowCalls: 0, countEvents: 0,
+		},
+		{
+			name: "failed store events",
+			fakeGetEvents: func(sportType commonDomain.SportType) ([]*commonDomain.Event, error) {
+				return []*commonDomain.Event{{ID: "soccer-1", SportType: sportType}}, nil
+			},
+			fakeStore: func(events []*commonDomain.Event) error {
+				return fakeErr
+			},
+			err: fakeErr, countUowCalls: 1, countEvents: 0,
+		},
+		{
+			name: "success store events",
+			fakeGetEvents: func(sportType commonDomain.SportType) ([]*commonDomain.Event, error) {
+				return []*commonDomain.Event{{ID: "soccer-1", SportType: sportType}, {ID: "soccer-2", SportType: sportType}}, nil
+			},
+			err: nil, countUowCalls: 1, countEvents: 2,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			eventRepo := &mockEventRepo{FakeStore: test.fakeStore}
+			uow := &mockUnitOfWork{provider: &mockRepositoryProvider{eventRepo: eventRepo, notifyRepo: &mockNotifyRepo{}}}
+			adapter := &mockLinesProviderAdapter{FakeGetEventsBySport: test.fakeGetEvents}
+			updateService := NewSportLinesUpdateService(1, adapter, uow)
+
+			err := updateService.UpdateEvents(commonDomain.Soccer)
+			assert.Equal(t, test.err, err)
+			assert.Equal(t, test.countUowCalls, uow.countCalls)
+			assert.Equal(t, test.countEvents, len(eventRepo.events))
+		})
+	}
+}
+
+func TestUpdateNotifiesOnlyChangedLines(t *testing.T) {
+	fakeErr := errors.New("fake error")
+	lines := []*commonDomain.SportLine{
+		{Type: commonDomain.Soccer, Score: decimal.RequireFromString("1.5")},
+		{Type: commonDomain.Soccer, Score: decimal.RequireFromString("1.50")},
+		{Type: commonDomain.Soccer, Score: decimal.RequireFromString("1.50"), Markets: []*commonDomain.Market{
+			{Type: commonDomain.Moneyline, Selections: []*commonDomain.Selection{{Name: "home", Price: decimal.RequireFromString("1.9")}}},
+		}},
+		{Type: commonDomain.Soccer, Score: decimal.RequireFromString("1.50"), Markets: []*commonDomain.Market{
+			{Type: commonDomain.Moneyline, Selections: []*commonDomain.Selection{{Name: "home", Price: decimal.RequireFromString("2.1")}}},
+		}},
+		{Type: commonDomain.Soccer, Score: decimal.RequireFromString("1.7")},
+		{Type: commonDomain.Soccer, Score: decimal.RequireFromString("1.7")},
+	}
+	notifyErrors := []error{nil, nil, nil, nil, fakeErr, nil}
+	expected := []int{1, 1, 2, 3, 3, 4}
+
+	call := 0
+	adapter := &mockLinesProviderAdapter{
+		FakeGetLineBySport: func(sportType commonDomain.SportType) (*commonDomain.SportLine, error) {
+			return lines[call], nil
+		},
+	}
+	notifyRepo := &mockNotifyRepo{FakeNotify: func(sportType commonDomain.SportType) error {
+		return notifyErrors[call]
+	}}
+	uow := &mockUnitOfWork{provider: &mockRepositoryProvider{
+		sportLineRepo: &mockDB{},
+		historyRepo:   &mockHistoryRepo{},
+		notifyRepo:    notifyRepo,
+	}}
+	updateService := NewSportLinesUpdateService(1, adapter, uow)
+
+	for call = range lines {
+		assert.Equal(t, notifyErrors[call], updateService.Update(commonDomain.Soccer), "update %d", call)
+		assert.Equal(t, expected[call], len(notifyRepo.notified), "update %d", call)
+	}
+	assert.Equal(t, []model.ChangeKind{model.LineChanged, model.LineChanged, model.LineChanged, model.LineChanged}, notifyRepo.kinds)
+}
+
+func TestUpdateEventsNotifiesOnlyChangedEvents(t *testing.T) {
+	fakeErr := errors.New("fake error")
+	batches := [][]*commonDomain.Event{
+		{{ID: "soccer-1", Status: commonDomain.EventScheduled, Line: decimal.RequireFromString("1.5")}},
+		{{ID: "soccer-1", Status: commonDomain.EventScheduled, Line: decimal.RequireFromString("1.5")}},
+		{{ID: "soccer-1", Status: commonDomain.EventLive, Line: decimal.RequireFromString("1.5")}},
+		{{ID: "soccer-1", Status: commonDomain.EventLive, Line: decimal.RequireFromString("1.8")}},
+		{{ID: "soccer-1", Status: commonDomain.EventFinished, Line: decimal.RequireFromString("1.8")}},
+	}
+	storeErrors := []error{nil, nil, nil, nil, fakeErr}
+	expected := []int{1, 1, 2, 3, 3}
+
+	call := 0
+	adapter := &mockLinesProviderAdapter{
+		FakeGetEventsBySport: func(sportType commonDomain.SportType) ([]*commonDomain.Event, error) {
+			return batches[call], nil
+		},
+	}
+	eventRepo := &mockEventRepo{FakeStore: func(events []*commonDomain.Event) error {
+		return storeErrors[call]
+	}}
+	notifyRepo := &mockNotifyRepo{}
+	uow := &mockUnitOfWork{provider: &mockRepositoryProvider{eventRepo: eventRepo, notifyRepo: notifyRepo}}
+	updateService := NewSportLinesUpdateService(1, adapter, uow)
+
+	for call = range batches {
+		assert.Equal(t, storeErrors[call], updateService.UpdateEvents(commonDomain.Soccer), "update %d", call)
+		assert.Equal(t, expected[call], len(notifyRepo.notified), "update %d", call)
+	}
+	assert.Equal(t, []model.ChangeKind{model.EventsChanged, model.EventsChanged, model.EventsChanged}, notifyRepo.kinds)
+}
@@ -0,0 +1,56 @@
+package subscription
+
+import (
+	"github.com/col3name/lines/pkg/kiddy-line-processor/application/service"
+)
+
+const mailboxSize = 16
+
+type clientActor struct {
+	clientId string
+	sender   service.ResponseSenderService
+	mailbox  chan *MessageToSubscribeDTO
+	done     chan struct{}
+	stopped  chan struct{}
+}
+
+func newClientActor(clientId string, sender service.ResponseSenderService) *clientActor {
+	return &clientActor{
+		clientId: clientId,
+		sender:   sender,
+		mailbox:  make(chan *MessageToSubscribeDTO, mailboxSize),
+		done:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+}
+
+func (a *clientActor) run(apply func(sender service.ResponseSenderService, msg *MessageToSubscribeDTO) bool) {
+	defer close(a.stopped)
+	for {
+		select {
+		case msg := <-a.mailbox:
+			apply(a.sender, msg)
+		case <-a.done:
+			return
+		}
+	}
+}
+
+func (a *clientActor) push(msg *MessageToSubscribeDTO) bool {
+	select {
+	case <-a.done:
+		return false
+	default:
+	}
+	select {
+	case a.mailbox <- msg:
+		return true
+	case <-a.done:
+		return false
+	}
+}
+
+func (a *clientActor) stop() {
+	close(a.done)
+	<-a.stopped
+}
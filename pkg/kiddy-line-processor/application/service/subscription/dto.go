@@ -2,10 +2,71 @@ package subscription
 
 import (
 	"github.com/col3name/lines/pkg/common/domain"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/application/service"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/domain/model"
+	"time"
+)
+
+type Operation string
+
+const (
+	OperationSubscribe     Operation = "subscribe"
+	OperationAddSports     Operation = "add_sports"
+	OperationRemoveSports  Operation = "remove_sports"
+	OperationUnsubscribe   Operation = "unsubscribe"
+	OperationResetBaseline Operation = "reset_baseline"
 )
 
 type MessageToSubscribeDTO struct {
-	ClientId             int
-	Sports               []domain.SportType
-	UpdateIntervalSecond int32
+	ClientId       string
+	SubscriptionId string
+	Operation      Operation
+	Sports         []domain.SportType
+	Markets        []domain.MarketType
+	EventIDs       []string
+	OddsFormat     domain.OddsFormat
+	UpdateInterval time.Duration
+	SportIntervals model.SportIntervalMap
+	DeliveryMode   model.DeliveryMode
+	Delta          model.DeltaPolicy
+	Snapshot       bool
+	ReplayFrom     uint64
+}
+
+func (m *MessageToSubscribeDTO) key() subscriptionKey {
+	return subscriptionKey{clientId: m.ClientId, subscriptionId: m.SubscriptionId}
+}
+
+func (m *MessageToSubscribeDTO) intervalOf(sportType domain.SportType) time.Duration {
+	if interval, ok := m.SportIntervals[sportType]; ok && interval > 0 {
+		return interval
+	}
+	return m.UpdateInterval
+}
+
+func (m *MessageToSubscribeDTO) deltaPolicy() model.DeltaPolicy {
+	if m.Delta == nil {
+		return model.DefaultDeltaPolicy
+	}
+	return m.Delta
+}
+
+type SubscriptionDTO struct {
+	ClientId       string
+	SubscriptionId string
+	Sports         []domain.SportType
+	Markets        []domain.MarketType
+	EventIDs       []string
+	OddsFormat     domain.OddsFormat
+	UpdateInterval time.Duration
+	SportIntervals model.SportIntervalMap
+	DeliveryMode   model.DeliveryMode
+	Delta          model.DeltaPolicy
+	Connected      bool
+	SendStats      service.SendStats
+}
+
+type subscriptionKey struct {
+	clientId       string
+	subscriptionId string
 }
@@ -1,6 +1,7 @@
 package subscription
 
 import (
+	"errors"
 	"github.com/col3name/lines/pkg/common/application/logger"
 	commonDomain "github.com/col3name/lines/pkg/common/domain"
 	"github.com/col3name/lines/pkg/common/infrastructure/util/array"
@@ -8,145 +9,809 @@ import (
 	"github.com/col3name/lines/pkg/kiddy-line-processor/application/service"
 	"github.com/col3name/lines/pkg/kiddy-line-processor/application/service/sport-line"
 	"github.com/col3name/lines/pkg/kiddy-line-processor/domain/model"
+	"github.com/shopspring/decimal"
+	"sort"
 	"sync"
+	"time"
+)
+
+var (
+	ErrInvalidResponseSender  = errors.New("invalid response sender")
+	ErrClientAlreadyConnected = errors.New("client already connected")
+	ErrInvalidClientId        = errors.New("invalid client id")
+	ErrUnknownSession         = errors.New("unknown session")
+	ErrUnknownSubscription    = errors.New("unknown subscription")
+	ErrEmptySubscription      = errors.New("subscription has no sports or events")
 )
 
 type Service interface {
-	Subscribe(responseSender service.ResponseSenderService, clientId int) bool
-	PushMessage(dto *MessageToSubscribeDTO)
-	Unsubscribe(clientId int)
+	Connect(clientId string, responseSender service.ResponseSenderService) (resumed bool, err error)
+	Resume(clientId string, responseSender service.ResponseSenderService) error
+	PushMessage(dto *MessageToSubscribeDTO) bool
+	Disconnect(clientId string)
+	Unsubscribe(clientId string)
+	List() []*SubscriptionDTO
 }
 
 type subscriptionServiceImpl struct {
-	subscriptions    map[int]*model.ClientSubscription
-	messageQueue     *MessageQueue
+	subscriptions    map[subscriptionKey]*model.ClientSubscription
+	requests         map[subscriptionKey]*MessageToSubscribeDTO
+	clients          map[string]*clientActor
+	detached         map[string]*time.Timer
+	gracePeriod      time.Duration
 	sportLineService sport_line.SportLineService
+	lineChanges      service.LineChangeSubscriber
 	timesTicker      times.Ticker
 	logger           logger.Logger
 	mu               sync.Mutex
 }
 
-func NewSubscriptionManager(sportLineService sport_line.SportLineService, logger logger.Logger) *subscriptionServiceImpl {
+func NewSubscriptionManager(
+	sportLineService sport_line.SportLineService,
+	lineChanges service.LineChangeSubscriber,
+	gracePeriod time.Duration,
+	logger logger.Logger,
+) *subscriptionServiceImpl {
 	return &subscriptionServiceImpl{
-		subscriptions:    make(map[int]*model.ClientSubscription, 0),
-		messageQueue:     NewMessageQueue(),
+		subscriptions:    make(map[subscriptionKey]*model.ClientSubscription, 0),
+		requests:         make(map[subscriptionKey]*MessageToSubscribeDTO),
+		clients:          make(map[string]*clientActor),
+		detached:         make(map[string]*time.Timer),
+		gracePeriod:      gracePeriod,
 		sportLineService: sportLineService,
+		lineChanges:      lineChanges,
 		logger:           logger,
 		timesTicker:      times.NewTimeTicker(),
 	}
 }
 
-func (s *subscriptionServiceImpl) Subscribe(responseSender service.ResponseSenderService, clientId int) bool {
+func (s *subscriptionServiceImpl) Connect(clientId string, responseSender service.ResponseSenderService) (bool, error) {
+	return s.connect(clientId, responseSender, false)
+}
+
+// Resume reattaches a detached session and fails with ErrUnknownSession if there is none to resume.
+func (s *subscriptionServiceImpl) Resume(clientId string, responseSender service.ResponseSenderService) error {
+	_, err := s.connect(clientId, responseSender, true)
+	return err
+}
+
+func (s *subscriptionServiceImpl) connect(clientId string, responseSender service.ResponseSenderService, resumeOnly bool) (bool, error) {
 	if responseSender == nil {
+		return false, ErrInvalidResponseSender
+	}
+	if len(clientId) == 0 {
+		return false, ErrInvalidClientId
+	}
+	s.mu.Lock()
+	if _, ok := s.clients[clientId]; ok {
+		s.mu.Unlock()
+		return false, ErrClientAlreadyConnected
+	}
+	expiry, resumed := s.detached[clientId]
+	if resumeOnly && !resumed {
+		s.mu.Unlock()
+		return false, ErrUnknownSession
+	}
+	actor := newClientActor(clientId, responseSender)
+	s.clients[clientId] = actor
+	if resumed {
+		expiry.Stop()
+		delete(s.detached, clientId)
+	}
+	var keys []subscriptionKey
+	if resumed {
+		keys = s.clientSubscriptions(clientId)
+	}
+	s.mu.Unlock()
+
+	go func() {
+		for _, key := range keys {
+			s.resumeDelivery(responseSender, key)
+		}
+		actor.run(s.addNotifySubscriberTask)
+	}()
+	return resumed, nil
+}
+
+func (s *subscriptionServiceImpl) resumeDelivery(sender service.ResponseSenderService, key subscriptionKey) {
+	s.mu.Lock()
+	sub, ok := s.subscriptions[key]
+	request := s.requests[key]
+	s.mu.Unlock()
+	if ok && request != nil {
+		s.startDelivery(sender, request, sub, true)
+	}
+}
+
+func (s *subscriptionServiceImpl) PushMessage(dto *MessageToSubscribeDTO) bool {
+	if !s.isValidMessage(dto) {
 		return false
 	}
-	subMsg := s.messageQueue.Peek()
-	if s.isUserAuthorOfMessage(subMsg, clientId) {
+	s.mu.Lock()
+	actor, ok := s.clients[dto.ClientId]
+	s.mu.Unlock()
+	if !ok {
 		return false
 	}
-	return s.addNotifySubscriberTask(responseSender, subMsg)
+	return actor.push(dto)
 }
 
-func (s *subscriptionServiceImpl) PushMessage(dto *MessageToSubscribeDTO) {
-	if !s.isValidMessage(dto) {
+func (s *subscriptionServiceImpl) Disconnect(clientId string) {
+	s.resetUndelivered(clientId, s.stopActor(clientId))
+
+	s.mu.Lock()
+	keys := s.clientSubscriptions(clientId)
+	var stops []func()
+	for _, key := range keys {
+		stops = append(stops, takeTasks(s.subscriptions[key])...)
+	}
+	if len(keys) > 0 {
+		s.detach(clientId)
+	}
+	s.mu.Unlock()
+	runAll(stops)
+}
+
+func (s *subscriptionServiceImpl) detach(clientId string) {
+	if s.gracePeriod <= 0 {
+		s.removeClient(clientId)
 		return
 	}
-	s.messageQueue.Push(dto)
+	var expiry *time.Timer
+	expiry = time.AfterFunc(s.gracePeriod, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.detached[clientId] == expiry {
+			s.removeClient(clientId)
+		}
+	})
+	s.detached[clientId] = expiry
 }
 
-func (s *subscriptionServiceImpl) Unsubscribe(clientId int) {
+func (s *subscriptionServiceImpl) Unsubscribe(clientId string) {
+	s.stopActor(clientId)
+
 	s.mu.Lock()
-	defer s.mu.Unlock()
-	sub, ok := s.subscriptions[clientId]
-	if s.isExistTask(ok, sub) {
+	var stops []func()
+	for _, key := range s.clientSubscriptions(clientId) {
+		stops = append(stops, takeTasks(s.subscriptions[key])...)
+	}
+	s.removeClient(clientId)
+	s.mu.Unlock()
+	runAll(stops)
+}
+
+func (s *subscriptionServiceImpl) stopActor(clientId string) *clientActor {
+	s.mu.Lock()
+	actor, ok := s.clients[clientId]
+	delete(s.clients, clientId)
+	s.mu.Unlock()
+	if ok {
+		actor.stop()
+	}
+	return actor
+}
+
+// resetUndelivered makes a resumed session start with a snapshot of every subscription whose updates were lost with the stream.
+func (s *subscriptionServiceImpl) resetUndelivered(clientId string, actor *clientActor) {
+	if actor == nil {
+		return
+	}
+	reporter, ok := actor.sender.(service.UndeliveredReporter)
+	if !ok {
 		return
 	}
-	sub.Task.Stop()
-	delete(s.subscriptions, clientId)
+	for _, subscriptionId := range reporter.Undelivered() {
+		s.mu.Lock()
+		sub := s.subscriptions[subscriptionKey{clientId: clientId, subscriptionId: subscriptionId}]
+		s.mu.Unlock()
+		if sub != nil {
+			sub.Log.Lock()
+			sub.ResetBaseline()
+			sub.Log.Unlock()
+		}
+	}
+}
+
+func (s *subscriptionServiceImpl) clientSubscriptions(clientId string) []subscriptionKey {
+	var keys []subscriptionKey
+	for key := range s.subscriptions {
+		if key.clientId == clientId {
+			keys = append(keys, key)
+		}
+	}
+	return keys
 }
 
-func (s *subscriptionServiceImpl) isUserAuthorOfMessage(subMsg *MessageToSubscribeDTO, clientId int) bool {
-	return subMsg == nil || (subMsg.ClientId != clientId)
+func (s *subscriptionServiceImpl) removeClient(clientId string) {
+	if expiry, ok := s.detached[clientId]; ok {
+		expiry.Stop()
+		delete(s.detached, clientId)
+	}
+	for _, key := range s.clientSubscriptions(clientId) {
+		delete(s.subscriptions, key)
+		delete(s.requests, key)
+	}
+}
+
+func (s *subscriptionServiceImpl) List() []*SubscriptionDTO {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := make([]*SubscriptionDTO, 0, len(s.subscriptions))
+	for key, sub := range s.subscriptions {
+		dto := &SubscriptionDTO{
+			ClientId:       key.clientId,
+			SubscriptionId: key.subscriptionId,
+			Markets:        append([]commonDomain.MarketType(nil), sub.Markets...),
+			OddsFormat:     sub.OddsFormat,
+			UpdateInterval: sub.Interval,
+			SportIntervals: copySportIntervals(sub.SportIntervals),
+			DeliveryMode:   sub.Mode,
+			Delta:          sub.DeltaPolicy(),
+		}
+		actor, connected := s.clients[key.clientId]
+		dto.Connected = connected
+		if provider, ok := s.statsProvider(actor); ok {
+			dto.SendStats = provider.Stats()
+		}
+		if request := s.requests[key]; request != nil {
+			dto.Sports = append(dto.Sports, request.Sports...)
+			dto.EventIDs = append(dto.EventIDs, request.EventIDs...)
+		}
+		sort.Slice(dto.Sports, func(i, j int) bool { return dto.Sports[i] < dto.Sports[j] })
+		sort.Strings(dto.EventIDs)
+		result = append(result, dto)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].ClientId != result[j].ClientId {
+			return result[i].ClientId < result[j].ClientId
+		}
+		return result[i].SubscriptionId < result[j].SubscriptionId
+	})
+	return result
+}
+
+func (s *subscriptionServiceImpl) statsProvider(actor *clientActor) (service.SendStatsProvider, bool) {
+	if actor == nil {
+		return nil, false
+	}
+	provider, ok := actor.sender.(service.SendStatsProvider)
+	return provider, ok
 }
 
 func (s *subscriptionServiceImpl) isValidMessage(dto *MessageToSubscribeDTO) bool {
-	return !array.EmptyST(dto.Sports) && dto.ClientId >= 0 && dto.UpdateIntervalSecond >= 1
+	if dto == nil || len(dto.ClientId) == 0 {
+		return false
+	}
+	switch dto.Operation {
+	case OperationUnsubscribe, OperationResetBaseline:
+		return true
+	case OperationAddSports, OperationRemoveSports:
+		return !array.EmptyST(dto.Sports)
+	}
+	if !s.hasTopics(dto) {
+		return s.isResyncRequest(dto)
+	}
+	return dto.DeliveryMode == model.DeliveryOnChange || dto.UpdateInterval > 0
+}
+
+func (s *subscriptionServiceImpl) isResyncRequest(dto *MessageToSubscribeDTO) bool {
+	return dto.Snapshot || dto.ReplayFrom > 0
+}
+
+func (s *subscriptionServiceImpl) hasTopics(dto *MessageToSubscribeDTO) bool {
+	return !array.EmptyST(dto.Sports) || !array.Empty(dto.EventIDs)
+}
+
+// stopTask waits for a delivery that is already running, so it must not be called while holding s.mu.
+func (s *subscriptionServiceImpl) stopTask(sub *model.ClientSubscription) {
+	s.mu.Lock()
+	stops := takeTasks(sub)
+	s.mu.Unlock()
+	runAll(stops)
+}
+
+// addTask stops the task right away if the subscription was replaced or removed while it was starting.
+func (s *subscriptionServiceImpl) addTask(key subscriptionKey, sub *model.ClientSubscription, stop func()) {
+	s.mu.Lock()
+	current := s.subscriptions[key] == sub
+	if current {
+		sub.Tasks = append(sub.Tasks, stop)
+	}
+	s.mu.Unlock()
+	if !current {
+		stop()
+	}
+}
+
+func takeTasks(sub *model.ClientSubscription) []func() {
+	if sub == nil {
+		return nil
+	}
+	stops := sub.Tasks
+	sub.Tasks = nil
+	return stops
 }
 
-func (s *subscriptionServiceImpl) isExistTask(ok bool, sub *model.ClientSubscription) bool {
-	return !ok || (ok && sub.Task == nil)
+func runAll(fns []func()) {
+	for _, fn := range fns {
+		fn()
+	}
 }
 
 func (s *subscriptionServiceImpl) addNotifySubscriberTask(responseSender service.ResponseSenderService, subMessage *MessageToSubscribeDTO) bool {
-	clientId := subMessage.ClientId
+	switch subMessage.Operation {
+	case OperationUnsubscribe:
+		s.removeSubscription(responseSender, subMessage)
+		return false
+	case OperationResetBaseline:
+		s.resetBaseline(responseSender, subMessage)
+		return false
+	case OperationAddSports, OperationRemoveSports:
+		request, err := s.changeSports(subMessage)
+		if err != nil {
+			s.reject(responseSender, subMessage.SubscriptionId, err)
+			return false
+		}
+		subMessage = request
+	}
+
+	key := subMessage.key()
 	sports := subMessage.Sports
-	if array.EmptyST(sports) {
-		s.messageQueue.Pop()
+	if !s.hasTopics(subMessage) {
+		s.resync(responseSender, subMessage)
 		return false
 	}
 	s.mu.Lock()
-	sub, isExistSubTask := s.subscriptions[clientId]
+	sub, isExistSubTask := s.subscriptions[key]
 	s.mu.Unlock()
 	if !isExistSubTask {
-		s.addNotifySubscriberPeriodically(responseSender, subMessage)
+		s.addNotifySubscriber(responseSender, subMessage)
 		return true
 	}
-	if s.isSubChanged(clientId, sports) {
-		sub.Task.Stop()
-		s.addNotifySubscriberPeriodically(responseSender, subMessage)
+	if s.isSubChanged(key, sports) || s.isMarketsChanged(key, subMessage.Markets) ||
+		s.isEventsChanged(key, subMessage.EventIDs) || s.isOddsFormatChanged(key, subMessage.OddsFormat) ||
+		s.isDeliveryModeChanged(key, subMessage.DeliveryMode) || s.isIntervalChanged(key, subMessage) ||
+		s.isDeltaChanged(key, subMessage.deltaPolicy()) {
+		s.stopTask(sub)
+		s.addNotifySubscriber(responseSender, subMessage)
 		return true
 	}
-	s.messageQueue.Pop()
+	s.ack(responseSender, subMessage, false)
+	s.resync(responseSender, subMessage)
+	return false
+}
+
+func (s *subscriptionServiceImpl) changeSports(msg *MessageToSubscribeDTO) (*MessageToSubscribeDTO, error) {
+	s.mu.Lock()
+	current, ok := s.requests[msg.key()]
+	s.mu.Unlock()
+	if !ok {
+		return nil, ErrUnknownSubscription
+	}
+	request := *current
+	request.Operation = OperationSubscribe
+	request.Snapshot = false
+	request.ReplayFrom = 0
+	request.SportIntervals = copySportIntervals(current.SportIntervals)
+	if msg.Operation == OperationAddSports {
+		request.Sports = mergeSports(current.Sports, msg.Sports)
+		for sportType, interval := range msg.SportIntervals {
+			request.SportIntervals[sportType] = interval
+		}
+	} else {
+		request.Sports = removeSports(current.Sports, msg.Sports)
+		for _, sportType := range msg.Sports {
+			delete(request.SportIntervals, sportType)
+		}
+	}
+	if !s.hasTopics(&request) {
+		return nil, ErrEmptySubscription
+	}
+	return &request, nil
+}
+
+func (s *subscriptionServiceImpl) removeSubscription(sender service.ResponseSenderService, msg *MessageToSubscribeDTO) {
+	key := msg.key()
+	s.mu.Lock()
+	sub, ok := s.subscriptions[key]
+	delete(s.subscriptions, key)
+	delete(s.requests, key)
+	s.mu.Unlock()
+	if !ok {
+		s.reject(sender, msg.SubscriptionId, ErrUnknownSubscription)
+		return
+	}
+	s.stopTask(sub)
+	s.ack(sender, msg, true)
+}
+
+func (s *subscriptionServiceImpl) resetBaseline(sender service.ResponseSenderService, msg *MessageToSubscribeDTO) {
+	s.mu.Lock()
+	sub, ok := s.subscriptions[msg.key()]
+	request := s.requests[msg.key()]
+	s.mu.Unlock()
+	if !ok || request == nil {
+		s.reject(sender, msg.SubscriptionId, ErrUnknownSubscription)
+		return
+	}
+	sub.Log.Lock()
+	sub.ResetBaseline()
+	sub.Log.Unlock()
+	s.ack(sender, request, false)
+	s.updateSportLineFn(sender, request, nil)(false)
+}
+
+func (s *subscriptionServiceImpl) ack(sender service.ResponseSenderService, msg *MessageToSubscribeDTO, removed bool) {
+	ack := &model.SubscriptionAck{SubscriptionId: msg.SubscriptionId, Removed: removed}
+	if !removed {
+		ack.Sports = msg.Sports
+		ack.Markets = msg.Markets
+		ack.EventIDs = msg.EventIDs
+		ack.OddsFormat = msg.OddsFormat
+		ack.Interval = msg.UpdateInterval
+		ack.SportIntervals = msg.SportIntervals
+		ack.Mode = msg.DeliveryMode
+		ack.Delta = msg.deltaPolicy()
+	}
+	if err := sender.Ack(ack); err != nil {
+		s.logger.Println(err)
+	}
+}
+
+func (s *subscriptionServiceImpl) reject(sender service.ResponseSenderService, subscriptionId string, err error) {
+	if sendErr := sender.Reject(subscriptionId, err); sendErr != nil {
+		s.logger.Println(sendErr)
+	}
+}
+
+func mergeSports(current, added []commonDomain.SportType) []commonDomain.SportType {
+	result := append([]commonDomain.SportType(nil), current...)
+	for _, sportType := range added {
+		if !containsSport(result, sportType) {
+			result = append(result, sportType)
+		}
+	}
+	return result
+}
+
+func removeSports(current, removed []commonDomain.SportType) []commonDomain.SportType {
+	result := make([]commonDomain.SportType, 0, len(current))
+	for _, sportType := range current {
+		if !containsSport(removed, sportType) {
+			result = append(result, sportType)
+		}
+	}
+	return result
+}
+
+func copySportIntervals(intervals model.SportIntervalMap) model.SportIntervalMap {
+	result := make(model.SportIntervalMap, len(intervals))
+	for sportType, interval := range intervals {
+		result[sportType] = interval
+	}
+	return result
+}
+
+func containsSport(sports []commonDomain.SportType, sportType commonDomain.SportType) bool {
+	for _, item := range sports {
+		if item == sportType {
+			return true
+		}
+	}
 	return false
 }
 
-func (s *subscriptionServiceImpl) addNotifySubscriberPeriodically(sender service.ResponseSenderService, subMsg *MessageToSubscribeDTO) {
+func (s *subscriptionServiceImpl) resync(sender service.ResponseSenderService, msg *MessageToSubscribeDTO) {
+	if !s.isResyncRequest(msg) {
+		return
+	}
+	s.mu.Lock()
+	sub, ok := s.subscriptions[msg.key()]
+	request := s.requests[msg.key()]
+	s.mu.Unlock()
+	if !ok || request == nil {
+		s.reject(sender, msg.SubscriptionId, ErrUnknownSubscription)
+		return
+	}
+	sendSnapshot := s.updateSportLineFn(sender, request, nil)
+	if msg.ReplayFrom == 0 {
+		sendSnapshot(false)
+		return
+	}
+	if !s.replay(sender, sub.Log, msg.ReplayFrom) {
+		sendSnapshot(false)
+	}
+}
+
+func (s *subscriptionServiceImpl) replay(sender service.ResponseSenderService, log *model.UpdateLog, from uint64) bool {
+	log.Lock()
+	defer log.Unlock()
+	updates, ok := log.Since(from)
+	if !ok {
+		return false
+	}
+	for _, update := range updates {
+		if err := sender.Send(update); err != nil {
+			s.logger.Println(err)
+			break
+		}
+	}
+	return true
+}
+
+func (s *subscriptionServiceImpl) addNotifySubscriber(sender service.ResponseSenderService, subMsg *MessageToSubscribeDTO) {
 	clientSub := s.initClientSubscription(subMsg)
-	fn := s.updateSportLineFn(sender, subMsg)
-	fn(false)
-	clientSub.Task = s.timesTicker.Handle(subMsg.UpdateIntervalSecond, func() {
-		fn(true)
+	s.ack(sender, subMsg, false)
+	s.startDelivery(sender, subMsg, clientSub, false)
+}
+
+func (s *subscriptionServiceImpl) startDelivery(sender service.ResponseSenderService, subMsg *MessageToSubscribeDTO, clientSub *model.ClientSubscription, isNeedDelta bool) {
+	if subMsg.DeliveryMode == model.DeliveryOnChange {
+		s.addNotifySubscriberOnChange(sender, subMsg, clientSub, isNeedDelta)
+		return
+	}
+	s.addNotifySubscriberPeriodically(sender, subMsg, clientSub, isNeedDelta)
+}
+
+func (s *subscriptionServiceImpl) addNotifySubscriberPeriodically(sender service.ResponseSenderService, subMsg *MessageToSubscribeDTO, clientSub *model.ClientSubscription, isNeedDelta bool) {
+	s.updateSportLineFn(sender, subMsg, nil)(isNeedDelta)
+	for interval, group := range groupByInterval(subMsg) {
+		fn := s.updateSportLineFn(sender, group, nil)
+		s.addTask(subMsg.key(), clientSub, s.timesTicker.Handle(interval, func() {
+			fn(true)
+		}))
+	}
+}
+
+func groupByInterval(msg *MessageToSubscribeDTO) map[time.Duration]*MessageToSubscribeDTO {
+	groups := make(map[time.Duration]*MessageToSubscribeDTO)
+	group := func(interval time.Duration) *MessageToSubscribeDTO {
+		if dto, ok := groups[interval]; ok {
+			return dto
+		}
+		dto := *msg
+		dto.Sports = nil
+		dto.EventIDs = nil
+		groups[interval] = &dto
+		return &dto
+	}
+	for _, sportType := range msg.Sports {
+		dto := group(msg.intervalOf(sportType))
+		dto.Sports = append(dto.Sports, sportType)
+	}
+	if !array.Empty(msg.EventIDs) {
+		group(msg.UpdateInterval).EventIDs = msg.EventIDs
+	}
+	return groups
+}
+
+func (s *subscriptionServiceImpl) addNotifySubscriberOnChange(sender service.ResponseSenderService, subMsg *MessageToSubscribeDTO, clientSub *model.ClientSubscription, isNeedDelta bool) {
+	topics := newTopicSet(subMsg.Sports)
+	changes := newPendingChanges()
+	done := make(chan struct{})
+	exited := make(chan struct{})
+	unsubscribe := s.lineChanges.Subscribe(func(sportType commonDomain.SportType, kind model.ChangeKind) {
+		if topics.has(sportType, kind) {
+			changes.add(sportType, kind)
+		}
+	})
+	s.updateSportLine(sender, subMsg, topics, isNeedDelta)
+	go func() {
+		defer close(exited)
+		for {
+			select {
+			case <-changes.signal:
+				if changed := changes.take(subMsg); changed != nil {
+					s.updateSportLine(sender, changed, topics, true)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	s.addTask(subMsg.key(), clientSub, func() {
+		unsubscribe()
+		close(done)
+		<-exited
 	})
-	s.messageQueue.Pop()
 }
 
-func (s *subscriptionServiceImpl) updateSportLineFn(sender service.ResponseSenderService, subMsg *MessageToSubscribeDTO) func(bool) {
+func (s *subscriptionServiceImpl) updateSportLineFn(sender service.ResponseSenderService, subMsg *MessageToSubscribeDTO, topics *topicSet) func(bool) {
 	return func(isNeedDelta bool) {
-		s.mu.Lock()
-		subscription := s.subscriptions[subMsg.ClientId]
-		s.mu.Unlock()
-		line, err := s.sportLineService.Calculate(subMsg.Sports, isNeedDelta, subscription)
+		s.updateSportLine(sender, subMsg, topics, isNeedDelta)
+	}
+}
+
+func (s *subscriptionServiceImpl) updateSportLine(sender service.ResponseSenderService, subMsg *MessageToSubscribeDTO, topics *topicSet, isNeedDelta bool) {
+	s.mu.Lock()
+	subscription := s.subscriptions[subMsg.key()]
+	request := s.requests[subMsg.key()]
+	s.mu.Unlock()
+	if subscription == nil {
+		return
+	}
+	subscription.Log.Lock()
+	defer subscription.Log.Unlock()
+	err := s.deliver(sender, subMsg, subscription, topics, isNeedDelta && subscription.Delivered)
+	if err == service.ErrSnapshotRequired && request != nil {
+		err = s.deliver(sender, request, subscription, topics, false)
+	}
+	if err != nil {
+		s.logger.Println(err)
+	}
+}
+
+func (s *subscriptionServiceImpl) deliver(
+	sender service.ResponseSenderService,
+	subMsg *MessageToSubscribeDTO,
+	subscription *model.ClientSubscription,
+	topics *topicSet,
+	isNeedDelta bool,
+) error {
+	baseline := subscription.SaveBaseline()
+	var (
+		lines  []*commonDomain.SportLine
+		events []*commonDomain.Event
+		err    error
+	)
+	if !array.EmptyST(subMsg.Sports) {
+		lines, err = s.sportLineService.Calculate(subMsg.Sports, isNeedDelta, subscription)
 		if err != nil {
-			s.logger.Println(err)
-			return
+			subscription.RestoreBaseline(baseline)
+			return err
 		}
-		if err = sender.Send(line); err != nil {
-			s.logger.Println(err)
+	}
+	if !array.Empty(subMsg.EventIDs) {
+		events, err = s.sportLineService.CalculateEvents(subMsg.EventIDs, isNeedDelta, subscription)
+		if err != nil {
+			subscription.RestoreBaseline(baseline)
+			return err
+		}
+		if topics != nil {
+			topics.addEvents(events)
+		}
+	}
+	if isNeedDelta && len(lines) == 0 && len(events) == 0 {
+		return nil
+	}
+	update := subscription.Log.Reserve(subMsg.SubscriptionId, !isNeedDelta, lines, events)
+	if err = sender.Send(update); err != nil {
+		subscription.RestoreBaseline(baseline)
+		return err
+	}
+	subscription.Log.Commit(update)
+	subscription.Delivered = true
+	return nil
+}
+
+func (s *subscriptionServiceImpl) isSubChanged(key subscriptionKey, sports []commonDomain.SportType) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	request, exist := s.requests[key]
+	if !exist {
+		return true
+	}
+	requested := make(model.SportTypeMap, len(request.Sports))
+	for _, sportType := range request.Sports {
+		requested[sportType] = decimal.Zero
+	}
+	return s.sportLineService.IsSubscriptionChanged(exist, requested, sports)
+}
+
+func (s *subscriptionServiceImpl) isMarketsChanged(key subscriptionKey, markets []commonDomain.MarketType) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sub, exist := s.subscriptions[key]
+	if !exist || len(sub.Markets) != len(markets) {
+		return true
+	}
+	for i, market := range markets {
+		if sub.Markets[i] != market {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *subscriptionServiceImpl) isEventsChanged(key subscriptionKey, eventIDs []string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	request, exist := s.requests[key]
+	if !exist {
+		return true
+	}
+	requested := make(map[string]struct{}, len(request.EventIDs))
+	for _, id := range request.EventIDs {
+		requested[id] = struct{}{}
+	}
+	if len(requested) != len(eventIDs) {
+		return true
+	}
+	for _, id := range eventIDs {
+		if _, ok := requested[id]; !ok {
+			return true
 		}
 	}
+	return false
+}
+
+func (s *subscriptionServiceImpl) isOddsFormatChanged(key subscriptionKey, oddsFormat commonDomain.OddsFormat) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sub, exist := s.subscriptions[key]
+
+	return !exist || sub.OddsFormat != oddsFormat
+}
+
+func (s *subscriptionServiceImpl) isDeliveryModeChanged(key subscriptionKey, mode model.DeliveryMode) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sub, exist := s.subscriptions[key]
+
+	return !exist || sub.Mode != mode
 }
 
-func (s *subscriptionServiceImpl) isSubChanged(clientId int, sports []commonDomain.SportType) bool {
+func (s *subscriptionServiceImpl) isIntervalChanged(key subscriptionKey, msg *MessageToSubscribeDTO) bool {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	sub, exist := s.subscriptions[clientId]
+	sub, exist := s.subscriptions[key]
+	if !exist || sub.Interval != msg.UpdateInterval || len(sub.SportIntervals) != len(msg.SportIntervals) {
+		return true
+	}
+	for sportType, interval := range msg.SportIntervals {
+		if sub.SportIntervals[sportType] != interval {
+			return true
+		}
+	}
+	return false
+}
 
-	return !exist || exist && s.sportLineService.IsSubscriptionChanged(exist, sub.Sports, sports)
+func (s *subscriptionServiceImpl) isDeltaChanged(key subscriptionKey, delta model.DeltaPolicy) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sub, exist := s.subscriptions[key]
+	if !exist {
+		return true
+	}
+	current := sub.DeltaPolicy()
+	return current.Mode() != delta.Mode() || !current.Threshold().Equal(delta.Threshold())
 }
 
-const DefaultScore = 1.0
+const updateLogSize = 64
 
 func (s *subscriptionServiceImpl) initClientSubscription(msg *MessageToSubscribeDTO) *model.ClientSubscription {
 	subToSports := make(model.SportTypeMap, 0)
 
 	for _, sportType := range msg.Sports {
-		subToSports[sportType] = DefaultScore
+		subToSports[sportType] = decimal.Zero
 	}
 
-	sub := &model.ClientSubscription{Sports: subToSports, Task: nil}
+	subToEvents := make(model.EventLineMap, len(msg.EventIDs))
+	for _, id := range msg.EventIDs {
+		subToEvents[id] = decimal.Zero
+	}
+
+	sub := &model.ClientSubscription{
+		Sports:         subToSports,
+		Markets:        msg.Markets,
+		Selections:     make(model.SelectionPriceMap),
+		Events:         subToEvents,
+		OddsFormat:     msg.OddsFormat,
+		Interval:       msg.UpdateInterval,
+		SportIntervals: copySportIntervals(msg.SportIntervals),
+		Mode:           msg.DeliveryMode,
+		Delta:          msg.deltaPolicy(),
+		Log:            model.NewUpdateLog(updateLogSize),
+	}
 
 	s.mu.Lock()
-	s.subscriptions[msg.ClientId] = sub
+	if previous, ok := s.subscriptions[msg.key()]; ok && previous.Log != nil {
+		sub.Log = previous.Log
+	}
+	s.subscriptions[msg.key()] = sub
+	s.requests[msg.key()] = msg
 	s.mu.Unlock()
 
 	return sub
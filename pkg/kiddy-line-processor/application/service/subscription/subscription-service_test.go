@@ -5,17 +5,20 @@ import (
 	"github.com/col3name/lines/pkg/common/domain"
 	"github.com/col3name/lines/pkg/kiddy-line-processor/application/fake"
 	"github.com/col3name/lines/pkg/kiddy-line-processor/application/service"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/application/service/line-change"
 	"github.com/col3name/lines/pkg/kiddy-line-processor/application/service/sport-line"
 	"github.com/col3name/lines/pkg/kiddy-line-processor/domain/model"
+	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
 	"reflect"
+	"sync"
 	"testing"
 	"time"
 )
 
 type expectedPushMessage struct {
-	queueSize int
-	msg       *MessageToSubscribeDTO
+	accepted bool
+	msg      *MessageToSubscribeDTO
 }
 
 func getFieldValue(object interface{}, fieldName string) *reflect.Value {
@@ -32,8 +35,9 @@ func getFieldValue(object interface{}, fieldName string) *reflect.Value {
 }
 
 func compareSubscriptionMessageDTO(t *testing.T, lhs, rhs *MessageToSubscribeDTO) {
-	assert.Equal(t, lhs.UpdateIntervalSecond, rhs.UpdateIntervalSecond)
+	assert.Equal(t, lhs.UpdateInterval, rhs.UpdateInterval)
 	assert.Equal(t, lhs.ClientId, rhs.ClientId)
+	assert.Equal(t, lhs.EventIDs, rhs.EventIDs)
 	assert.Equal(t, len(lhs.Sports), len(rhs.Sports))
 	for i, sport := range rhs.Sports {
 		assert.Equal(t, lhs.Sports[i], sport)
@@ -45,7 +49,7 @@ func compareSports(t *testing.T, expected, actual model.SportTypeMap) {
 	for sportType, line := range actual {
 		expectedLine, ok := expected[sportType]
 		assert.True(t, ok)
-		assert.Equal(t, expectedLine, line)
+		assert.True(t, expectedLine.Equal(line), "expected %s, actual %s", expectedLine, line)
 	}
 }
 
@@ -57,60 +61,80 @@ var testsCaseForPushMessage = []struct {
 	{
 		name: "empty sports",
 		input: &MessageToSubscribeDTO{
-			ClientId:             1,
-			Sports:               []domain.SportType{},
-			UpdateIntervalSecond: 1,
+			ClientId:       "1",
+			Sports:         []domain.SportType{},
+			UpdateInterval: 1 * time.Second,
 		},
-		expected: &expectedPushMessage{queueSize: 0},
+		expected: &expectedPushMessage{accepted: false},
 	},
 	{
-		name: "invalid client id",
+		name: "empty client id",
 		input: &MessageToSubscribeDTO{
-			ClientId:             0,
-			Sports:               []domain.SportType{},
-			UpdateIntervalSecond: 1,
+			ClientId:       "",
+			Sports:         []domain.SportType{},
+			UpdateInterval: 1 * time.Second,
 		},
-		expected: &expectedPushMessage{queueSize: 0},
+		expected: &expectedPushMessage{accepted: false},
 	},
 	{
-		name: "negative client id",
+		name: "update interval < 1",
 		input: &MessageToSubscribeDTO{
-			ClientId:             -1,
-			Sports:               []domain.SportType{},
-			UpdateIntervalSecond: 1,
+			ClientId:       "",
+			Sports:         []domain.SportType{},
+			UpdateInterval: 1 * time.Second,
 		},
-		expected: &expectedPushMessage{queueSize: 0},
+		expected: &expectedPushMessage{accepted: false},
 	},
 	{
 		name: "update interval < 1",
 		input: &MessageToSubscribeDTO{
-			ClientId:             -1,
-			Sports:               []domain.SportType{},
-			UpdateIntervalSecond: 1,
+			ClientId:       "",
+			Sports:         []domain.SportType{},
+			UpdateInterval: 1 * time.Second,
 		},
-		expected: &expectedPushMessage{queueSize: 0},
+		expected: &expectedPushMessage{accepted: false},
 	},
 	{
-		name: "update interval < 1",
+		name: "valid sub message with events only",
 		input: &MessageToSubscribeDTO{
-			ClientId:             -1,
-			Sports:               []domain.SportType{},
-			UpdateIntervalSecond: 1,
+			ClientId:       "1",
+			EventIDs:       []string{"soccer-1"},
+			UpdateInterval: 1 * time.Second,
+		},
+		expected: &expectedPushMessage{accepted: true,
+			msg: &MessageToSubscribeDTO{
+				ClientId:       "1",
+				EventIDs:       []string{"soccer-1"},
+				UpdateInterval: 1 * time.Second,
+			},
+		},
+	},
+	{
+		name: "on change sub message without interval",
+		input: &MessageToSubscribeDTO{
+			ClientId:     "1",
+			Sports:       []domain.SportType{domain.Soccer},
+			DeliveryMode: model.DeliveryOnChange,
+		},
+		expected: &expectedPushMessage{accepted: true,
+			msg: &MessageToSubscribeDTO{
+				ClientId: "1",
+				Sports:   []domain.SportType{domain.Soccer},
+			},
 		},
-		expected: &expectedPushMessage{queueSize: 0},
 	},
 	{
 		name: "valid sub message",
 		input: &MessageToSubscribeDTO{
-			ClientId:             1,
-			Sports:               []domain.SportType{domain.Baseball},
-			UpdateIntervalSecond: 1,
+			ClientId:       "1",
+			Sports:         []domain.SportType{domain.Baseball},
+			UpdateInterval: 1 * time.Second,
 		},
-		expected: &expectedPushMessage{queueSize: 1,
+		expected: &expectedPushMessage{accepted: true,
 			msg: &MessageToSubscribeDTO{
-				ClientId:             1,
-				Sports:               []domain.SportType{domain.Baseball},
-				UpdateIntervalSecond: 1,
+				ClientId:       "1",
+				Sports:         []domain.SportType{domain.Baseball},
+				UpdateInterval: 1 * time.Second,
 			},
 		},
 	},
@@ -119,21 +143,61 @@ var testsCaseForPushMessage = []struct {
 func TestPushMessage(t *testing.T) {
 	for _, test := range testsCaseForPushMessage {
 		t.Run(test.name, func(t *testing.T) {
-			manager := NewSubscriptionManager(&MockLinesService{FakeCalculate: nil, FakeIsChanged: nil}, &fake.Logger{})
-			manager.PushMessage(test.input)
-			assert.Equal(t, test.expected.queueSize, manager.messageQueue.Size())
-			if test.expected.queueSize > 0 {
-				peek := manager.messageQueue.Peek()
-				msg := test.expected.msg
-				compareSubscriptionMessageDTO(t, msg, peek)
+			manager := NewSubscriptionManager(&MockLinesService{FakeCalculate: nil, FakeIsChanged: nil}, nil, 0, &fake.Logger{})
+			actor := newClientActor(test.input.ClientId, &mockResponseSender{})
+			manager.clients[test.input.ClientId] = actor
+
+			accepted := manager.PushMessage(test.input)
+			assert.Equal(t, test.expected.accepted, accepted)
+			assert.Equal(t, len(actor.mailbox), map[bool]int{true: 1, false: 0}[accepted])
+			if test.expected.accepted {
+				compareSubscriptionMessageDTO(t, test.expected.msg, <-actor.mailbox)
 			}
 		})
 	}
 }
 
+func TestPushMessageToNotConnectedClient(t *testing.T) {
+	manager := NewSubscriptionManager(&MockLinesService{}, nil, 0, &fake.Logger{})
+	accepted := manager.PushMessage(&MessageToSubscribeDTO{
+		ClientId:       "1",
+		Sports:         []domain.SportType{domain.Baseball},
+		UpdateInterval: 1 * time.Second,
+	})
+	assert.False(t, accepted)
+}
+
+func TestConnect(t *testing.T) {
+	manager := NewSubscriptionManager(&MockLinesService{}, nil, 0, &fake.Logger{})
+
+	_, err := manager.Connect("1", nil)
+	assert.Equal(t, ErrInvalidResponseSender, err)
+	_, err = manager.Connect("", &mockResponseSender{})
+	assert.Equal(t, ErrInvalidClientId, err)
+	resumed, err := manager.Connect("1", &mockResponseSender{})
+	assert.Nil(t, err)
+	assert.False(t, resumed)
+	_, err = manager.Connect("1", &mockResponseSender{})
+	assert.Equal(t, ErrClientAlreadyConnected, err)
+
+	manager.Unsubscribe("1")
+	assert.Equal(t, 0, len(manager.clients))
+	_, err = manager.Connect("1", &mockResponseSender{})
+	assert.Nil(t, err)
+	manager.Unsubscribe("1")
+}
+
 type MockLinesService struct {
-	FakeCalculate func(sports []domain.SportType, isNeedDelta bool, subs *model.ClientSubscription) ([]*domain.SportLine, error)
-	FakeIsChanged func(exist bool, subscriptionMap model.SportTypeMap, newValue []domain.SportType) bool
+	FakeCalculate       func(sports []domain.SportType, isNeedDelta bool, subs *model.ClientSubscription) ([]*domain.SportLine, error)
+	FakeCalculateEvents func(eventIDs []string, isNeedDelta bool, subs *model.ClientSubscription) ([]*domain.Event, error)
+	FakeIsChanged       func(exist bool, subscriptionMap model.SportTypeMap, newValue []domain.SportType) bool
+}
+
+func (m *MockLinesService) CalculateEvents(eventIDs []string, isNeedDelta bool, subs *model.ClientSubscription) ([]*domain.Event, error) {
+	if m.FakeCalculateEvents == nil {
+		return nil, nil
+	}
+	return m.FakeCalculateEvents(eventIDs, isNeedDelta, subs)
 }
 
 func (m *MockLinesService) Calculate(sports []domain.SportType, isNeedDelta bool, subs *model.ClientSubscription) ([]*domain.SportLine, error) {
@@ -151,8 +215,8 @@ func (m *MockLinesService) IsSubscriptionChanged(exist bool, subscriptionMap mod
 }
 
 type inputUnsubscribeClient struct {
-	subscriptions map[int]*model.ClientSubscription
-	clientId      int
+	subscriptions map[string]*model.ClientSubscription
+	clientId      string
 }
 
 type expectedUnsubscribeClient struct {
@@ -162,7 +226,7 @@ type expectedUnsubscribeClient struct {
 }
 
 func compareSubscriptionManager(t *testing.T, expected *expectedUnsubscribeClient, input *inputUnsubscribeClient, manager *subscriptionServiceImpl) {
-	actualSubscription, ok := manager.subscriptions[input.clientId]
+	actualSubscription, ok := manager.subscriptions[subscriptionKey{clientId: input.clientId}]
 
 	assert.Equal(t, expected.exist, ok)
 	assert.Equal(t, expected.subscriptionsSize, len(manager.subscriptions))
@@ -177,7 +241,7 @@ func compareClientSubscription(t *testing.T, expectSubscription, actualSubscript
 	}
 
 	assert.Equal(t, expectSubscription, actualSubscription)
-	assert.Equal(t, expectSubscription.Task != nil, actualSubscription.Task != nil)
+	assert.Equal(t, len(expectSubscription.Tasks), len(actualSubscription.Tasks))
 
 	compareSports(t, expectSubscription.Sports, actualSubscription.Sports)
 }
@@ -190,8 +254,8 @@ var testsCaseForUnsubscribeClient = []struct {
 	{
 		name: "not exist client",
 		input: &inputUnsubscribeClient{
-			subscriptions: map[int]*model.ClientSubscription{},
-			clientId:      1,
+			subscriptions: map[string]*model.ClientSubscription{},
+			clientId:      "1",
 		},
 		expected: &expectedUnsubscribeClient{
 			exist:             false,
@@ -202,17 +266,17 @@ var testsCaseForUnsubscribeClient = []struct {
 	{
 		name: "not exist client",
 		input: &inputUnsubscribeClient{
-			subscriptions: map[int]*model.ClientSubscription{
-				1: {
-					Sports: map[domain.SportType]float32{domain.Baseball: 1.0},
-					Task:   time.NewTicker(1),
+			subscriptions: map[string]*model.ClientSubscription{
+				"1": {
+					Sports: map[domain.SportType]decimal.Decimal{domain.Baseball: decimal.RequireFromString("1.0")},
+					Tasks:  []func(){func() {}},
 				},
-				2: {
-					Sports: map[domain.SportType]float32{domain.Baseball: 1.0},
-					Task:   time.NewTicker(1),
+				"2": {
+					Sports: map[domain.SportType]decimal.Decimal{domain.Baseball: decimal.RequireFromString("1.0")},
+					Tasks:  []func(){func() {}},
 				},
 			},
-			clientId: 1,
+			clientId: "1",
 		},
 		expected: &expectedUnsubscribeClient{
 			exist:             false,
@@ -225,11 +289,13 @@ var testsCaseForUnsubscribeClient = []struct {
 func TestUnsubscribeClient(t *testing.T) {
 	for _, test := range testsCaseForUnsubscribeClient {
 		t.Run(test.name, func(t *testing.T) {
-			manager := NewSubscriptionManager(&MockLinesService{FakeCalculate: nil, FakeIsChanged: nil}, &fake.Logger{})
+			manager := NewSubscriptionManager(&MockLinesService{FakeCalculate: nil, FakeIsChanged: nil}, nil, 0, &fake.Logger{})
 			input := test.input
 			expected := test.expected
 
-			manager.subscriptions = input.subscriptions
+			for clientId, sub := range input.subscriptions {
+				manager.subscriptions[subscriptionKey{clientId: clientId}] = sub
+			}
 			manager.Unsubscribe(input.clientId)
 
 			compareSubscriptionManager(t, expected, input, manager)
@@ -243,87 +309,49 @@ type mockResponseSender struct {
 	CountCall int
 }
 
-func (m *mockResponseSender) Send(sports []*domain.SportLine) error {
+func (m *mockResponseSender) Send(update *model.LineUpdate) error {
 	if m.FakeSend == nil {
 		return nil
 	}
 	m.Called = true
 	m.CountCall++
-	return m.FakeSend(sports)
+	return m.FakeSend(update.Sports)
+}
+
+func (m *mockResponseSender) Ack(_ *model.SubscriptionAck) error {
+	return nil
+}
+
+func (m *mockResponseSender) Reject(_ string, _ error) error {
+	return nil
 }
 
 type inputSubscribe struct {
-	clientId         int
 	responseSender   service.ResponseSenderService
-	messageQueue     *MessageQueue
 	sportLineService sport_line.SportLineService
-	subscriptions    map[int]*model.ClientSubscription
+	messages         []*MessageToSubscribeDTO
+	subscriptions    map[string]*model.ClientSubscription
 }
 
 type expectedSubscribe struct {
-	messageQueueSize        int
 	subscribedOk            bool
-	messageQueue            *MessageQueue
 	responseSenderCalled    bool
-	subscriptions           map[int]*model.ClientSubscription
+	subscriptions           map[string]*model.ClientSubscription
 	responseSenderCountCall int64
 }
 
-func compareDeepSubscriptionManager(t *testing.T, input *inputSubscribe, expected *expectedSubscribe, manager *subscriptionServiceImpl) {
-	compareSubscribeResult(t, manager, input, expected)
-
-	expectedQueue := expected.messageQueue
-	actualQueue := manager.messageQueue
-	compareMessageQueue(t, expected.messageQueueSize, expectedQueue, actualQueue)
-	compareResponseSenderCalled(t, expected, input)
-	compareSubscriptions(t, expected.subscriptions, manager.subscriptions)
-}
-
-func compareSubscribeResult(t *testing.T, manager *subscriptionServiceImpl, input *inputSubscribe, expected *expectedSubscribe) {
-	respSender := input.responseSender
-
-	actualSubscribedOk := manager.Subscribe(respSender, input.clientId)
-	if expected.responseSenderCountCall <= 1 {
-		assert.Equal(t, expected.subscribedOk, actualSubscribedOk)
-		return
-	}
-	actualSubscribedOk = manager.Subscribe(respSender, input.clientId)
-	fieldValue := getFieldValue(input.responseSender, "CountCall")
+func compareResponseSenderCalled(t *testing.T, expected *expectedSubscribe, input *inputSubscribe) {
+	fieldValue := getFieldValue(input.responseSender, "Called")
 	if fieldValue != nil {
-		assert.Equal(t, expected.responseSenderCountCall, fieldValue.Int())
+		assert.Equal(t, expected.responseSenderCalled, fieldValue.Bool())
 	}
-
-	assert.Equal(t, expected.subscribedOk, actualSubscribedOk)
-}
-
-func compareMessageQueue(t *testing.T, expectedSize int, expectedQueue, actualQueue *MessageQueue) {
-	assert.Equal(t, expectedSize, actualQueue.Size())
-	if expectedQueue == nil {
-		return
-	}
-	assert.Equal(t, expectedQueue.Size(), actualQueue.Size())
-	for i := 0; i < expectedQueue.Size(); i++ {
-		expectedQueue.Pop()
-		compareDeepQueueData(t, expectedQueue.data, actualQueue.data)
-	}
-}
-
-func compareDeepQueueData(t *testing.T, expected, actual []*MessageToSubscribeDTO) {
-	for j, expectedDto := range expected {
-		compareSubscriptionMessageDTO(t, expectedDto, actual[j])
-	}
-}
-
-func compareResponseSenderCalled(t *testing.T, expected *expectedSubscribe, input *inputSubscribe) {
-	if expected.responseSenderCalled {
-		fieldValue := getFieldValue(input.responseSender, "Called")
-		if fieldValue != nil {
-			assert.Equal(t, expected.responseSenderCalled, fieldValue.Bool())
-		}
+	if expected.responseSenderCountCall > 0 {
+		fieldValue = getFieldValue(input.responseSender, "CountCall")
+		assert.Equal(t, expected.responseSenderCountCall, fieldValue.Int())
 	}
 }
 
-func compareSubscriptions(t *testing.T, expectedSubsMap, actualSubsMap map[int]*model.ClientSubscription) {
+func compareSubscriptions(t *testing.T, expectedSubsMap, actualSubsMap map[string]*model.ClientSubscription) {
 	if expectedSubsMap == nil {
 		return
 	}
@@ -339,279 +367,135 @@ var testsCaseForSubscribe = []struct {
 	input    *inputSubscribe
 	expected *expectedSubscribe
 }{
-	{
-		name: "response sender nil",
-		input: &inputSubscribe{
-			clientId:         1,
-			responseSender:   nil,
-			sportLineService: nil,
-			messageQueue:     nil,
-		},
-		expected: &expectedSubscribe{
-			messageQueueSize:     0,
-			subscribedOk:         false,
-			responseSenderCalled: false,
-		},
-	},
-	{
-		name: "empty message queue",
-		input: &inputSubscribe{
-			clientId:         1,
-			responseSender:   nil,
-			sportLineService: nil,
-			messageQueue:     nil,
-		},
-		expected: &expectedSubscribe{
-			messageQueueSize:     0,
-			subscribedOk:         false,
-			responseSenderCalled: false,
-		},
-	},
 	{
 		name: "empty sport list for subscribe",
 		input: &inputSubscribe{
-			clientId: 1,
 			responseSender: &mockResponseSender{FakeSend: func(sports []*domain.SportLine) error {
 				return nil
 			}},
-			sportLineService: &MockLinesService{
-				FakeCalculate: nil,
-				FakeIsChanged: nil,
-			},
-			messageQueue: &MessageQueue{
-				data: []*MessageToSubscribeDTO{
-					{ClientId: 1, Sports: []domain.SportType{}, UpdateIntervalSecond: 1},
-				},
+			sportLineService: &MockLinesService{},
+			messages: []*MessageToSubscribeDTO{
+				{ClientId: "1", Sports: []domain.SportType{}, UpdateInterval: 1 * time.Second},
 			},
 		},
 		expected: &expectedSubscribe{
-			messageQueueSize:     0,
 			subscribedOk:         false,
 			responseSenderCalled: false,
-			messageQueue: &MessageQueue{
-				data: []*MessageToSubscribeDTO{},
-			},
-			subscriptions: map[int]*model.ClientSubscription{},
+			subscriptions:        map[string]*model.ClientSubscription{},
 		},
 	},
 	{
-		name: "subscription client id != parameter clientId",
+		name: "subscription exist and not changed",
 		input: &inputSubscribe{
-			clientId: 1,
 			responseSender: &mockResponseSender{FakeSend: func(sports []*domain.SportLine) error {
 				return nil
 			}},
 			sportLineService: &MockLinesService{
-				FakeCalculate: nil,
-				FakeIsChanged: nil,
-			},
-			messageQueue: &MessageQueue{
-				data: []*MessageToSubscribeDTO{
-					{ClientId: 2, Sports: []domain.SportType{domain.Baseball}, UpdateIntervalSecond: 1},
-				},
-			},
-		},
-		expected: &expectedSubscribe{
-			messageQueueSize: 1,
-			subscribedOk:     false,
-			messageQueue: &MessageQueue{
-				data: []*MessageToSubscribeDTO{
-					{ClientId: 2, Sports: []domain.SportType{domain.Baseball}, UpdateIntervalSecond: 1},
-				},
-			},
-			responseSenderCalled: false,
-			subscriptions:        map[int]*model.ClientSubscription{},
-		},
-	},
-	{
-		name: "subscription exist and not changed",
-		input: &inputSubscribe{
-			clientId: 1,
-			responseSender: &mockResponseSender{
-				Called: false,
-				FakeSend: func(sports []*domain.SportLine) error {
-					return nil
-				}},
-			sportLineService: &MockLinesService{
-				FakeCalculate: nil,
 				FakeIsChanged: func(exist bool, r model.SportTypeMap, newValue []domain.SportType) bool {
 					return false
 				},
 			},
-
-			messageQueue: &MessageQueue{
-				data: []*MessageToSubscribeDTO{
-					{ClientId: 1, Sports: []domain.SportType{domain.Baseball}, UpdateIntervalSecond: 1},
-					{ClientId: 2, Sports: []domain.SportType{domain.Soccer}, UpdateIntervalSecond: 1},
-				},
+			messages: []*MessageToSubscribeDTO{
+				{ClientId: "1", Sports: []domain.SportType{domain.Baseball}, UpdateInterval: 1 * time.Second},
 			},
-			subscriptions: map[int]*model.ClientSubscription{
-				1: {Sports: map[domain.SportType]float32{domain.Baseball: 1.0}, Task: time.NewTicker(1)},
-				2: {Sports: map[domain.SportType]float32{domain.Soccer: 1.0}, Task: time.NewTicker(1)},
+			subscriptions: map[string]*model.ClientSubscription{
+				"1": {Sports: map[domain.SportType]decimal.Decimal{domain.Baseball: decimal.RequireFromString("1.0")}, Interval: time.Second, Tasks: []func(){func() {}}},
+				"2": {Sports: map[domain.SportType]decimal.Decimal{domain.Soccer: decimal.RequireFromString("1.0")}, Tasks: []func(){func() {}}},
 			},
 		},
 		expected: &expectedSubscribe{
-			messageQueueSize: 1,
-			subscribedOk:     false,
-			messageQueue: &MessageQueue{
-				data: []*MessageToSubscribeDTO{
-					{ClientId: 2, Sports: []domain.SportType{domain.Soccer}, UpdateIntervalSecond: 1},
-				},
-			},
-			subscriptions: map[int]*model.ClientSubscription{
-				1: {Sports: map[domain.SportType]float32{domain.Baseball: 1.0}, Task: time.NewTicker(1)},
-				2: {Sports: map[domain.SportType]float32{domain.Soccer: 1.0}, Task: time.NewTicker(1)},
+			subscribedOk: false,
+			subscriptions: map[string]*model.ClientSubscription{
+				"1": {Sports: map[domain.SportType]decimal.Decimal{domain.Baseball: decimal.RequireFromString("1.0")}},
+				"2": {Sports: map[domain.SportType]decimal.Decimal{domain.Soccer: decimal.RequireFromString("1.0")}},
 			},
 			responseSenderCalled: false,
 		},
 	},
 	{
-		name: "subscription client id == parameter clientId and client not exist on subscription map",
+		name: "client not exist on subscription map",
 		input: &inputSubscribe{
-			clientId: 1,
-			responseSender: &mockResponseSender{
-				Called: false,
-				FakeSend: func(sports []*domain.SportLine) error {
-					return nil
-				}},
-			sportLineService: &MockLinesService{
-				FakeCalculate: nil,
-				FakeIsChanged: nil,
-			},
-			messageQueue: &MessageQueue{
-				data: []*MessageToSubscribeDTO{
-					{ClientId: 1, Sports: []domain.SportType{domain.Baseball}, UpdateIntervalSecond: 1},
-					{ClientId: 2, Sports: []domain.SportType{domain.Soccer}, UpdateIntervalSecond: 1},
-				},
+			responseSender: &mockResponseSender{FakeSend: func(sports []*domain.SportLine) error {
+				return nil
+			}},
+			sportLineService: &MockLinesService{},
+			messages: []*MessageToSubscribeDTO{
+				{ClientId: "1", Sports: []domain.SportType{domain.Baseball}, UpdateInterval: 1 * time.Second},
 			},
 		},
 		expected: &expectedSubscribe{
-			messageQueueSize: 1,
-			subscribedOk:     true,
-			messageQueue: &MessageQueue{
-				data: []*MessageToSubscribeDTO{
-					{ClientId: 2, Sports: []domain.SportType{domain.Soccer}, UpdateIntervalSecond: 1},
-				},
-			},
+			subscribedOk:         true,
 			responseSenderCalled: true,
-		},
-	},
-	{
-		name: "not have message for process",
-		input: &inputSubscribe{
-			clientId: 1,
-			responseSender: &mockResponseSender{
-				Called: false,
-				FakeSend: func(sports []*domain.SportLine) error {
-					return nil
-				}},
-			sportLineService: &MockLinesService{
-				FakeCalculate: nil,
-				FakeIsChanged: nil,
+			subscriptions: map[string]*model.ClientSubscription{
+				"1": {Sports: map[domain.SportType]decimal.Decimal{domain.Baseball: decimal.Zero}},
 			},
-			messageQueue: &MessageQueue{},
-		},
-		expected: &expectedSubscribe{
-			messageQueueSize:     0,
-			subscribedOk:         false,
-			messageQueue:         &MessageQueue{},
-			responseSenderCalled: false,
 		},
 	},
 	{
-		name: "subscription client id == parameter clientId and exist in subscription map but sub not changed",
+		name: "change subscription",
 		input: &inputSubscribe{
-			clientId: 1,
-			responseSender: &mockResponseSender{
-				Called: false,
-				FakeSend: func(sports []*domain.SportLine) error {
-					return nil
-				}},
+			responseSender: &mockResponseSender{FakeSend: func(sports []*domain.SportLine) error {
+				return nil
+			}},
 			sportLineService: &MockLinesService{
+				FakeCalculate: func(sports []domain.SportType, isNeedDelta bool, subs *model.ClientSubscription) ([]*domain.SportLine, error) {
+					return []*domain.SportLine{
+						{Score: decimal.RequireFromString("1.0"), Type: domain.Baseball},
+						{Score: decimal.RequireFromString("1.5"), Type: domain.Soccer},
+					}, nil
+				},
 				FakeIsChanged: func(exist bool, r model.SportTypeMap, newValue []domain.SportType) bool {
-					return false
+					return true
 				},
 			},
-			messageQueue: &MessageQueue{
-				data: []*MessageToSubscribeDTO{
-					{ClientId: 1, Sports: []domain.SportType{domain.Baseball}, UpdateIntervalSecond: 1},
-					{ClientId: 2, Sports: []domain.SportType{domain.Soccer}, UpdateIntervalSecond: 1},
-				},
+			messages: []*MessageToSubscribeDTO{
+				{ClientId: "1", Sports: []domain.SportType{domain.Baseball}, UpdateInterval: 1 * time.Second},
+				{ClientId: "1", Sports: []domain.SportType{domain.Soccer}, UpdateInterval: 1 * time.Second},
 			},
-			subscriptions: map[int]*model.ClientSubscription{
-				1: {Sports: map[domain.SportType]float32{domain.Baseball: 1.0}, Task: time.NewTicker(1)},
+			subscriptions: map[string]*model.ClientSubscription{
+				"1": {Sports: map[domain.SportType]decimal.Decimal{domain.Baseball: decimal.RequireFromString("1.0")}, Tasks: []func(){func() {}}},
 			},
 		},
 		expected: &expectedSubscribe{
-			messageQueueSize: 1,
-			subscribedOk:     false,
-			messageQueue: &MessageQueue{
-				data: []*MessageToSubscribeDTO{
-					{ClientId: 2, Sports: []domain.SportType{domain.Soccer}, UpdateIntervalSecond: 1},
-				},
-			},
-			responseSenderCalled: false,
-			subscriptions: map[int]*model.ClientSubscription{
-				1: {Sports: map[domain.SportType]float32{domain.Baseball: 1.0}, Task: time.NewTicker(1)},
+			subscribedOk:            true,
+			responseSenderCountCall: 2,
+			responseSenderCalled:    true,
+			subscriptions: map[string]*model.ClientSubscription{
+				"1": {Sports: map[domain.SportType]decimal.Decimal{domain.Soccer: decimal.Zero}},
 			},
 		},
 	},
 	{
-		name: "change subscription",
+		name: "change delivery mode",
 		input: &inputSubscribe{
-			clientId: 1,
-			responseSender: &mockResponseSender{
-				Called:    false,
-				CountCall: 0,
-				FakeSend: func(sports []*domain.SportLine) error {
-					return nil
-				}},
+			responseSender: &mockResponseSender{FakeSend: func(sports []*domain.SportLine) error {
+				return nil
+			}},
 			sportLineService: &MockLinesService{
-				FakeCalculate: func(sports []domain.SportType, isNeedDelta bool, subs *model.ClientSubscription) ([]*domain.SportLine, error) {
-					return []*domain.SportLine{
-						{Score: 1.0, Type: domain.Baseball},
-						{Score: 1.5, Type: domain.Soccer},
-					}, nil
-				},
 				FakeIsChanged: func(exist bool, r model.SportTypeMap, newValue []domain.SportType) bool {
-					return true
-				},
-			},
-			messageQueue: &MessageQueue{
-				data: []*MessageToSubscribeDTO{
-					{ClientId: 1, Sports: []domain.SportType{domain.Baseball}, UpdateIntervalSecond: 1},
-					{ClientId: 1, Sports: []domain.SportType{domain.Soccer}, UpdateIntervalSecond: 1},
-					{ClientId: 2, Sports: []domain.SportType{domain.Soccer}, UpdateIntervalSecond: 1},
+					return false
 				},
 			},
-			subscriptions: map[int]*model.ClientSubscription{
-				1: {Sports: map[domain.SportType]float32{domain.Baseball: 1.0}, Task: time.NewTicker(1)},
+			messages: []*MessageToSubscribeDTO{
+				{ClientId: "1", Sports: []domain.SportType{domain.Baseball}, UpdateInterval: 1 * time.Second},
+				{ClientId: "1", Sports: []domain.SportType{domain.Baseball}, DeliveryMode: model.DeliveryOnChange},
 			},
 		},
 		expected: &expectedSubscribe{
-			messageQueueSize: 1,
-			subscribedOk:     true,
-			messageQueue: &MessageQueue{
-				data: []*MessageToSubscribeDTO{
-					{ClientId: 2, Sports: []domain.SportType{domain.Soccer}, UpdateIntervalSecond: 1},
-				},
-			},
+			subscribedOk:            true,
 			responseSenderCountCall: 2,
 			responseSenderCalled:    true,
-			subscriptions: map[int]*model.ClientSubscription{
-				1: {Sports: map[domain.SportType]float32{domain.Soccer: 1.0}, Task: time.NewTicker(1)},
+			subscriptions: map[string]*model.ClientSubscription{
+				"1": {Sports: map[domain.SportType]decimal.Decimal{domain.Baseball: decimal.Zero}},
 			},
 		},
 	},
 	{
 		name: "failed get data from database",
 		input: &inputSubscribe{
-			clientId: 1,
-			responseSender: &mockResponseSender{
-				Called: false,
-				FakeSend: func(sports []*domain.SportLine) error {
-					return nil
-				}},
+			responseSender: &mockResponseSender{FakeSend: func(sports []*domain.SportLine) error {
+				return nil
+			}},
 			sportLineService: &MockLinesService{
 				FakeCalculate: func(sports []domain.SportType, isNeedDelta bool, subs *model.ClientSubscription) ([]*domain.SportLine, error) {
 					return nil, errors.New("fake error")
@@ -620,73 +504,49 @@ var testsCaseForSubscribe = []struct {
 					return true
 				},
 			},
-
-			messageQueue: &MessageQueue{
-				data: []*MessageToSubscribeDTO{
-					{ClientId: 1, Sports: []domain.SportType{domain.Baseball}, UpdateIntervalSecond: 1},
-					{ClientId: 2, Sports: []domain.SportType{domain.Soccer}, UpdateIntervalSecond: 1},
-				},
+			messages: []*MessageToSubscribeDTO{
+				{ClientId: "1", Sports: []domain.SportType{domain.Baseball}, UpdateInterval: 1 * time.Second},
 			},
-			subscriptions: map[int]*model.ClientSubscription{
-				1: {Sports: map[domain.SportType]float32{domain.Soccer: 1.0}, Task: time.NewTicker(1)},
+			subscriptions: map[string]*model.ClientSubscription{
+				"1": {Sports: map[domain.SportType]decimal.Decimal{domain.Soccer: decimal.RequireFromString("1.0")}, Tasks: []func(){func() {}}},
 			},
 		},
 		expected: &expectedSubscribe{
-			messageQueueSize: 1,
-			subscribedOk:     true,
-			messageQueue: &MessageQueue{
-				data: []*MessageToSubscribeDTO{
-					{ClientId: 2, Sports: []domain.SportType{domain.Soccer}, UpdateIntervalSecond: 1},
-				},
-			},
+			subscribedOk:         true,
 			responseSenderCalled: false,
-			subscriptions: map[int]*model.ClientSubscription{
-				1: {Sports: map[domain.SportType]float32{domain.Baseball: 1.0}, Task: time.NewTicker(1)},
+			subscriptions: map[string]*model.ClientSubscription{
+				"1": {Sports: map[domain.SportType]decimal.Decimal{domain.Baseball: decimal.Zero}},
 			},
 		},
 	},
 	{
 		name: "failed send data to subscriber",
 		input: &inputSubscribe{
-			clientId: 1,
-			responseSender: &mockResponseSender{
-				Called: false,
-				FakeSend: func(sports []*domain.SportLine) error {
-					return errors.New("fake error")
-				}},
+			responseSender: &mockResponseSender{FakeSend: func(sports []*domain.SportLine) error {
+				return errors.New("fake error")
+			}},
 			sportLineService: &MockLinesService{
 				FakeCalculate: func(sports []domain.SportType, isNeedDelta bool, subs *model.ClientSubscription) ([]*domain.SportLine, error) {
 					return []*domain.SportLine{
-						{Score: 1.0, Type: domain.Baseball},
-						{Score: 1.5, Type: domain.Soccer},
+						{Score: decimal.RequireFromString("1.0"), Type: domain.Baseball},
 					}, nil
 				},
 				FakeIsChanged: func(exist bool, r model.SportTypeMap, newValue []domain.SportType) bool {
 					return true
 				},
 			},
-
-			messageQueue: &MessageQueue{
-				data: []*MessageToSubscribeDTO{
-					{ClientId: 1, Sports: []domain.SportType{domain.Baseball}, UpdateIntervalSecond: 1},
-					{ClientId: 2, Sports: []domain.SportType{domain.Soccer}, UpdateIntervalSecond: 1},
-				},
+			messages: []*MessageToSubscribeDTO{
+				{ClientId: "1", Sports: []domain.SportType{domain.Baseball}, UpdateInterval: 1 * time.Second},
 			},
-			subscriptions: map[int]*model.ClientSubscription{
-				1: {Sports: map[domain.SportType]float32{domain.Soccer: 1.0}, Task: time.NewTicker(1)},
+			subscriptions: map[string]*model.ClientSubscription{
+				"1": {Sports: map[domain.SportType]decimal.Decimal{domain.Soccer: decimal.RequireFromString("1.0")}, Tasks: []func(){func() {}}},
 			},
 		},
 		expected: &expectedSubscribe{
-			messageQueueSize: 1,
-			subscribedOk:     true,
-			messageQueue: &MessageQueue{
-				data: []*MessageToSubscribeDTO{
-					{ClientId: 2, Sports: []domain.SportType{domain.Soccer}, UpdateIntervalSecond: 1},
-				},
-			},
-			responseSenderCalled: false,
-			subscriptions: map[int]*model.ClientSubscription{
-				1: {Sports: map[domain.SportType]float32{domain.Baseball: 1.0}, Task: time.NewTicker(1)},
+			subscribedOk:         true,
+			responseSenderCalled: true,
+			subscriptions: map[string]*model.ClientSubscription{
+				"1": {Sports: map[domain.SportType]decimal.Decimal{domain.Baseball: decimal.Zero}},
 			},
 		},
 	},
@@ -698,17 +558,716 @@ func TestSubscribe(t *testing.T) {
 			input := test.input
 			expected := test.expected
 
-			manager := NewSubscriptionManager(input.sportLineService, &fake.Logger{})
-			if input.messageQueue != nil {
-				manager.messageQueue = input.messageQueue
-			}
-			if input.subscriptions != nil {
-				manager.subscriptions = input.subscriptions
+			manager := NewSubscriptionManager(input.sportLineService, line_change.NewLineChangeBus(), 0, &fake.Logger{})
+			for clientId, sub := range input.subscriptions {
+				clientSub := *sub
+				key := subscriptionKey{clientId: clientId}
+				manager.subscriptions[key] = &clientSub
+				request := &MessageToSubscribeDTO{ClientId: clientId}
+				for sportType := range sub.Sports {
+					request.Sports = append(request.Sports, sportType)
+				}
+				manager.requests[key] = request
 			}
 
-			compareDeepSubscriptionManager(t, input, expected, manager)
+			var subscribedOk bool
+			for _, msg := range input.messages {
+				subscribedOk = manager.addNotifySubscriberTask(input.responseSender, msg)
+			}
+			assert.Equal(t, expected.subscribedOk, subscribedOk)
+			compareResponseSenderCalled(t, expected, input)
+			actualSubscriptions := make(map[string]*model.ClientSubscription, len(manager.subscriptions))
+			for key, sub := range manager.subscriptions {
+				actualSubscriptions[key.clientId] = sub
+			}
+			compareSubscriptions(t, expected.subscriptions, actualSubscriptions)
 
-			manager.Unsubscribe(input.clientId)
+			for clientId := range actualSubscriptions {
+				manager.Unsubscribe(clientId)
+			}
 		})
 	}
 }
+
+func TestConnectedClientsApplyIndependently(t *testing.T) {
+	blocked := make(chan struct{})
+	linesService := &MockLinesService{
+		FakeCalculate: func(sports []domain.SportType, isNeedDelta bool, subs *model.ClientSubscription) ([]*domain.SportLine, error) {
+			if sports[0] == domain.Baseball {
+				<-blocked
+			}
+			return []*domain.SportLine{{Type: sports[0], Score: decimal.RequireFromString("1.5")}}, nil
+		},
+	}
+	manager := NewSubscriptionManager(linesService, nil, 0, &fake.Logger{})
+	stalled := &chanResponseSender{sent: make(chan *model.LineUpdate, 1)}
+	healthy := &chanResponseSender{sent: make(chan *model.LineUpdate, 1)}
+	_, err := manager.Connect("1", stalled)
+	assert.Nil(t, err)
+	_, err = manager.Connect("2", healthy)
+	assert.Nil(t, err)
+
+	assert.True(t, manager.PushMessage(&MessageToSubscribeDTO{ClientId: "1", Sports: []domain.SportType{domain.Baseball}, UpdateInterval: 60 * time.Second}))
+	assert.True(t, manager.PushMessage(&MessageToSubscribeDTO{ClientId: "2", Sports: []domain.SportType{domain.Soccer}, UpdateInterval: 60 * time.Second}))
+
+	select {
+	case update := <-healthy.sent:
+		assert.Equal(t, domain.Soccer, update.Sports[0].Type)
+	case <-time.After(time.Second):
+		t.Fatal("subscription of healthy client is blocked by stalled client")
+	}
+
+	close(blocked)
+	select {
+	case update := <-stalled.sent:
+		assert.Equal(t, domain.Baseball, update.Sports[0].Type)
+	case <-time.After(time.Second):
+		t.Fatal("expected message for stalled client after it resumes")
+	}
+
+	manager.Unsubscribe("1")
+	manager.Unsubscribe("2")
+	assert.Equal(t, 0, len(manager.List()))
+	assert.False(t, manager.PushMessage(&MessageToSubscribeDTO{ClientId: "1", Sports: []domain.SportType{domain.Soccer}, UpdateInterval: 1 * time.Second}))
+}
+
+type chanResponseSender struct {
+	sent        chan *model.LineUpdate
+	acks        chan *model.SubscriptionAck
+	rejected    chan error
+	failures    int
+	coalesced   int
+	undelivered []string
+}
+
+func (c *chanResponseSender) Undelivered() []string {
+	return c.undelivered
+}
+
+func (c *chanResponseSender) Send(update *model.LineUpdate) error {
+	if c.failures > 0 {
+		c.failures--
+		return errors.New("stream closed")
+	}
+	if c.coalesced > 0 && !update.Snapshot {
+		c.coalesced--
+		return service.ErrSnapshotRequired
+	}
+	c.sent <- update
+	return nil
+}
+
+func (c *chanResponseSender) Ack(ack *model.SubscriptionAck) error {
+	if c.acks != nil {
+		c.acks <- ack
+	}
+	return nil
+}
+
+func (c *chanResponseSender) Reject(_ string, err error) error {
+	if c.rejected != nil {
+		c.rejected <- err
+	}
+	return nil
+}
+
+func TestSubscribeOnChange(t *testing.T) {
+	bus := line_change.NewLineChangeBus()
+	linesService := &MockLinesService{
+		FakeCalculate: func(sports []domain.SportType, isNeedDelta bool, subs *model.ClientSubscription) ([]*domain.SportLine, error) {
+			return []*domain.SportLine{{Type: domain.Soccer, Score: decimal.RequireFromString("1.5")}}, nil
+		},
+	}
+	manager := NewSubscriptionManager(linesService, bus, 0, &fake.Logger{})
+	sender := &chanResponseSender{sent: make(chan *model.LineUpdate, 10)}
+
+	_, err := manager.Connect("1", sender)
+	assert.Nil(t, err)
+	assert.True(t, manager.PushMessage(&MessageToSubscribeDTO{
+		ClientId:     "1",
+		Sports:       []domain.SportType{domain.Soccer},
+		DeliveryMode: model.DeliveryOnChange,
+	}))
+	select {
+	case <-sender.sent:
+	case <-time.After(time.Second):
+		t.Fatal("expected initial message")
+	}
+
+	bus.Publish(domain.Baseball, model.LineChanged)
+	bus.Publish(domain.Soccer, model.LineChanged)
+	select {
+	case update := <-sender.sent:
+		assert.Equal(t, domain.Soccer, update.Sports[0].Type)
+	case <-time.After(time.Second):
+		t.Fatal("expected message after soccer line change")
+	}
+	select {
+	case <-sender.sent:
+		t.Fatal("unexpected message for unsubscribed sport")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	subs := manager.List()
+	assert.Equal(t, 1, len(subs))
+	assert.Equal(t, model.DeliveryOnChange, subs[0].DeliveryMode)
+
+	manager.Unsubscribe("1")
+	assert.Equal(t, 0, len(manager.List()))
+	bus.Publish(domain.Soccer, model.LineChanged)
+	select {
+	case <-sender.sent:
+		t.Fatal("unexpected message after unsubscribe")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestSubscribeOnChangeRecalculatesOnlyChangedTopics(t *testing.T) {
+	bus := line_change.NewLineChangeBus()
+	calculated := make(chan []domain.SportType, 10)
+	linesService := &MockLinesService{
+		FakeCalculate: func(sports []domain.SportType, isNeedDelta bool, subs *model.ClientSubscription) ([]*domain.SportLine, error) {
+			calculated <- sports
+			lines := make([]*domain.SportLine, 0, len(sports))
+			for _, sportType := range sports {
+				lines = append(lines, &domain.SportLine{Type: sportType, Score: decimal.RequireFromString("1.5")})
+			}
+			return lines, nil
+		},
+	}
+	manager := NewSubscriptionManager(linesService, bus, 0, &fake.Logger{})
+	sender := &chanResponseSender{sent: make(chan *model.LineUpdate, 10)}
+	_, err := manager.Connect("1", sender)
+	assert.Nil(t, err)
+	assert.True(t, manager.PushMessage(&MessageToSubscribeDTO{
+		ClientId:     "1",
+		Sports:       []domain.SportType{domain.Soccer, domain.Baseball},
+		DeliveryMode: model.DeliveryOnChange,
+	}))
+	<-sender.sent
+	assert.Equal(t, []domain.SportType{domain.Soccer, domain.Baseball}, <-calculated)
+
+	bus.Publish(domain.Soccer, model.EventsChanged)
+	bus.Publish(domain.Baseball, model.LineChanged)
+	select {
+	case sports := <-calculated:
+		assert.Equal(t, []domain.SportType{domain.Baseball}, sports)
+	case <-time.After(time.Second):
+		t.Fatal("expected recalculation of baseball")
+	}
+	select {
+	case sports := <-calculated:
+		t.Fatal("unexpected recalculation of ", sports)
+	case <-time.After(50 * time.Millisecond):
+	}
+	manager.Unsubscribe("1")
+}
+
+func TestResumeSessionWithinGracePeriod(t *testing.T) {
+	deltas := make(chan bool, 10)
+	baselines := make(chan *model.ClientSubscription, 10)
+	linesService := &MockLinesService{
+		FakeCalculate: func(sports []domain.SportType, isNeedDelta bool, subs *model.ClientSubscription) ([]*domain.SportLine, error) {
+			deltas <- isNeedDelta
+			baselines <- subs
+			return []*domain.SportLine{{Type: sports[0], Score: decimal.RequireFromString("1.5")}}, nil
+		},
+	}
+	manager := NewSubscriptionManager(linesService, nil, time.Minute, &fake.Logger{})
+	first := &chanResponseSender{sent: make(chan *model.LineUpdate, 10)}
+	_, err := manager.Connect("session", first)
+	assert.Nil(t, err)
+	assert.True(t, manager.PushMessage(&MessageToSubscribeDTO{ClientId: "session", Sports: []domain.SportType{domain.Soccer}, UpdateInterval: 60 * time.Second}))
+	select {
+	case <-first.sent:
+	case <-time.After(time.Second):
+		t.Fatal("expected initial message")
+	}
+	assert.False(t, <-deltas)
+	baseline := <-baselines
+
+	manager.Disconnect("session")
+	subs := manager.List()
+	assert.Equal(t, 1, len(subs))
+	assert.False(t, subs[0].Connected)
+	assert.False(t, manager.PushMessage(&MessageToSubscribeDTO{ClientId: "session", Sports: []domain.SportType{domain.Soccer}, UpdateInterval: 60 * time.Second}))
+
+	second := &chanResponseSender{sent: make(chan *model.LineUpdate, 10)}
+	resumed, err := manager.Connect("session", second)
+	assert.Nil(t, err)
+	assert.True(t, resumed)
+	select {
+	case update := <-second.sent:
+		assert.Equal(t, domain.Soccer, update.Sports[0].Type)
+	case <-time.After(time.Second):
+		t.Fatal("expected resumed message")
+	}
+	assert.True(t, <-deltas)
+	assert.Same(t, baseline, <-baselines)
+	assert.True(t, manager.List()[0].Connected)
+
+	manager.Unsubscribe("session")
+	assert.Equal(t, 0, len(manager.List()))
+}
+
+func TestResumeAfterLostUpdatesStartsWithSnapshot(t *testing.T) {
+	deltas := make(chan bool, 10)
+	linesService := &MockLinesService{
+		FakeCalculate: func(sports []domain.SportType, isNeedDelta bool, subs *model.ClientSubscription) ([]*domain.SportLine, error) {
+			deltas <- isNeedDelta
+			return []*domain.SportLine{{Type: sports[0], Score: decimal.RequireFromString("1.5")}}, nil
+		},
+	}
+	manager := NewSubscriptionManager(linesService, nil, time.Minute, &fake.Logger{})
+	first := &chanResponseSender{sent: make(chan *model.LineUpdate, 10), undelivered: []string{""}}
+	_, err := manager.Connect("session", first)
+	assert.Nil(t, err)
+	assert.True(t, manager.PushMessage(&MessageToSubscribeDTO{ClientId: "session", Sports: []domain.SportType{domain.Soccer}, UpdateInterval: 60 * time.Second}))
+	<-first.sent
+	assert.False(t, <-deltas)
+
+	manager.Disconnect("session")
+	second := &chanResponseSender{sent: make(chan *model.LineUpdate, 10)}
+	resumed, err := manager.Connect("session", second)
+	assert.Nil(t, err)
+	assert.True(t, resumed)
+	select {
+	case update := <-second.sent:
+		assert.True(t, update.Snapshot)
+		assert.Equal(t, uint64(2), update.Sequence)
+	case <-time.After(time.Second):
+		t.Fatal("expected resumed message")
+	}
+	assert.False(t, <-deltas)
+
+	manager.Unsubscribe("session")
+}
+
+func TestResumeOnlyKnownSession(t *testing.T) {
+	linesService := &MockLinesService{
+		FakeCalculate: func(sports []domain.SportType, isNeedDelta bool, subs *model.ClientSubscription) ([]*domain.SportLine, error) {
+			return []*domain.SportLine{{Type: sports[0], Score: decimal.RequireFromString("1.5")}}, nil
+		},
+	}
+	manager := NewSubscriptionManager(linesService, nil, time.Minute, &fake.Logger{})
+	sender := &chanResponseSender{sent: make(chan *model.LineUpdate, 10)}
+	assert.Equal(t, ErrUnknownSession, manager.Resume("session", sender))
+	assert.Equal(t, 0, len(manager.clients))
+
+	_, err := manager.Connect("session", sender)
+	assert.Nil(t, err)
+	assert.True(t, manager.PushMessage(&MessageToSubscribeDTO{ClientId: "session", Sports: []domain.SportType{domain.Soccer}, UpdateInterval: 60 * time.Second}))
+	select {
+	case <-sender.sent:
+	case <-time.After(time.Second):
+		t.Fatal("expected initial message")
+	}
+	assert.Equal(t, ErrClientAlreadyConnected, manager.Resume("session", sender))
+
+	manager.Disconnect("session")
+	assert.Nil(t, manager.Resume("session", sender))
+	assert.True(t, manager.List()[0].Connected)
+	manager.Unsubscribe("session")
+}
+
+func TestSessionExpiresAfterGracePeriod(t *testing.T) {
+	linesService := &MockLinesService{
+		FakeCalculate: func(sports []domain.SportType, isNeedDelta bool, subs *model.ClientSubscription) ([]*domain.SportLine, error) {
+			return []*domain.SportLine{{Type: sports[0], Score: decimal.RequireFromString("1.5")}}, nil
+		},
+	}
+	manager := NewSubscriptionManager(linesService, nil, 10*time.Millisecond, &fake.Logger{})
+	sender := &chanResponseSender{sent: make(chan *model.LineUpdate, 10)}
+	_, err := manager.Connect("session", sender)
+	assert.Nil(t, err)
+	assert.True(t, manager.PushMessage(&MessageToSubscribeDTO{ClientId: "session", Sports: []domain.SportType{domain.Soccer}, UpdateInterval: 60 * time.Second}))
+	select {
+	case <-sender.sent:
+	case <-time.After(time.Second):
+		t.Fatal("expected initial message")
+	}
+
+	manager.Disconnect("session")
+	assert.Eventually(t, func() bool { return len(manager.List()) == 0 }, time.Second, 5*time.Millisecond)
+
+	resumed, err := manager.Connect("session", sender)
+	assert.Nil(t, err)
+	assert.False(t, resumed)
+	manager.Unsubscribe("session")
+}
+
+func TestResyncBySequence(t *testing.T) {
+	linesService := &MockLinesService{
+		FakeCalculate: func(sports []domain.SportType, isNeedDelta bool, subs *model.ClientSubscription) ([]*domain.SportLine, error) {
+			return []*domain.SportLine{{Type: sports[0], Score: decimal.RequireFromString("1.5")}}, nil
+		},
+	}
+	manager := NewSubscriptionManager(linesService, line_change.NewLineChangeBus(), 0, &fake.Logger{})
+	sender := &chanResponseSender{sent: make(chan *model.LineUpdate, 10)}
+	request := &MessageToSubscribeDTO{ClientId: "1", Sports: []domain.SportType{domain.Soccer}, DeliveryMode: model.DeliveryOnChange}
+	assert.True(t, manager.addNotifySubscriberTask(sender, request))
+	fn := manager.updateSportLineFn(sender, request, nil)
+	fn(true)
+	fn(true)
+
+	for i, snapshot := range []bool{true, false, false} {
+		update := <-sender.sent
+		assert.Equal(t, uint64(i+1), update.Sequence)
+		assert.Equal(t, snapshot, update.Snapshot)
+	}
+
+	assert.False(t, manager.addNotifySubscriberTask(sender, &MessageToSubscribeDTO{ClientId: "1", ReplayFrom: 2}))
+	for _, sequence := range []uint64{2, 3} {
+		update := <-sender.sent
+		assert.Equal(t, sequence, update.Sequence)
+		assert.False(t, update.Snapshot)
+	}
+
+	assert.False(t, manager.addNotifySubscriberTask(sender, &MessageToSubscribeDTO{ClientId: "1", ReplayFrom: 10}))
+	update := <-sender.sent
+	assert.Equal(t, uint64(4), update.Sequence)
+	assert.True(t, update.Snapshot)
+
+	request.Snapshot = true
+	assert.False(t, manager.addNotifySubscriberTask(sender, request))
+	update = <-sender.sent
+	assert.Equal(t, uint64(5), update.Sequence)
+	assert.True(t, update.Snapshot)
+
+	assert.True(t, manager.addNotifySubscriberTask(sender, &MessageToSubscribeDTO{ClientId: "1", Sports: []domain.SportType{domain.Soccer}, OddsFormat: domain.OddsAmerican, DeliveryMode: model.DeliveryOnChange}))
+	update = <-sender.sent
+	assert.Equal(t, uint64(6), update.Sequence)
+	assert.True(t, update.Snapshot)
+
+	manager.Unsubscribe("1")
+	assert.Equal(t, 0, len(sender.sent))
+}
+
+func TestNamedSubscriptions(t *testing.T) {
+	linesService := &MockLinesService{
+		FakeCalculate: func(sports []domain.SportType, isNeedDelta bool, subs *model.ClientSubscription) ([]*domain.SportLine, error) {
+			return []*domain.SportLine{{Type: sports[0], Score: decimal.RequireFromString("1.5")}}, nil
+		},
+		FakeIsChanged: sport_line.NewSportLineService(nil, nil, &fake.Logger{}).IsSubscriptionChanged,
+	}
+	manager := NewSubscriptionManager(linesService, line_change.NewLineChangeBus(), 0, &fake.Logger{})
+	sender := &chanResponseSender{
+		sent:     make(chan *model.LineUpdate, 10),
+		acks:     make(chan *model.SubscriptionAck, 10),
+		rejected: make(chan error, 10),
+	}
+	_, err := manager.Connect("1", sender)
+	assert.Nil(t, err)
+
+	expectAck := func(subscriptionId string, sports []domain.SportType, removed bool) {
+		select {
+		case ack := <-sender.acks:
+			assert.Equal(t, subscriptionId, ack.SubscriptionId)
+			assert.Equal(t, sports, ack.Sports)
+			assert.Equal(t, removed, ack.Removed)
+		case <-time.After(time.Second):
+			t.Fatal("expected ack for subscription " + subscriptionId)
+		}
+	}
+	expectUpdate := func(subscriptionId string, sportType domain.SportType) {
+		select {
+		case update := <-sender.sent:
+			assert.Equal(t, subscriptionId, update.SubscriptionId)
+			assert.Equal(t, sportType, update.Sports[0].Type)
+			assert.True(t, update.Snapshot)
+		case <-time.After(time.Second):
+			t.Fatal("expected update for subscription " + subscriptionId)
+		}
+	}
+	expectRejected := func(expected error) {
+		select {
+		case err := <-sender.rejected:
+			assert.Equal(t, expected, err)
+		case <-time.After(time.Second):
+			t.Fatal("expected rejection")
+		}
+	}
+
+	assert.True(t, manager.PushMessage(&MessageToSubscribeDTO{ClientId: "1", SubscriptionId: "fast", Sports: []domain.SportType{domain.Soccer}, UpdateInterval: 60 * time.Second}))
+	expectAck("fast", []domain.SportType{domain.Soccer}, false)
+	expectUpdate("fast", domain.Soccer)
+	assert.True(t, manager.PushMessage(&MessageToSubscribeDTO{ClientId: "1", SubscriptionId: "slow", Sports: []domain.SportType{domain.Baseball}, DeliveryMode: model.DeliveryOnChange}))
+	expectAck("slow", []domain.SportType{domain.Baseball}, false)
+	expectUpdate("slow", domain.Baseball)
+
+	subs := manager.List()
+	assert.Equal(t, 2, len(subs))
+	assert.Equal(t, "fast", subs[0].SubscriptionId)
+	assert.Equal(t, "slow", subs[1].SubscriptionId)
+
+	assert.True(t, manager.PushMessage(&MessageToSubscribeDTO{ClientId: "1", SubscriptionId: "fast", Operation: OperationAddSports, Sports: []domain.SportType{domain.Football}}))
+	expectAck("fast", []domain.SportType{domain.Soccer, domain.Football}, false)
+	expectUpdate("fast", domain.Soccer)
+
+	assert.True(t, manager.PushMessage(&MessageToSubscribeDTO{ClientId: "1", SubscriptionId: "fast", Operation: OperationRemoveSports, Sports: []domain.SportType{domain.Soccer}}))
+	expectAck("fast", []domain.SportType{domain.Football}, false)
+	expectUpdate("fast", domain.Football)
+
+	assert.True(t, manager.PushMessage(&MessageToSubscribeDTO{ClientId: "1", SubscriptionId: "fast", Operation: OperationRemoveSports, Sports: []domain.SportType{domain.Football}}))
+	expectRejected(ErrEmptySubscription)
+	assert.True(t, manager.PushMessage(&MessageToSubscribeDTO{ClientId: "1", SubscriptionId: "missing", Operation: OperationAddSports, Sports: []domain.SportType{domain.Football}}))
+	expectRejected(ErrUnknownSubscription)
+	assert.False(t, manager.PushMessage(&MessageToSubscribeDTO{ClientId: "1", SubscriptionId: "fast", Operation: OperationAddSports}))
+
+	assert.True(t, manager.PushMessage(&MessageToSubscribeDTO{ClientId: "1", SubscriptionId: "slow", Operation: OperationUnsubscribe}))
+	expectAck("slow", nil, true)
+	subs = manager.List()
+	assert.Equal(t, 1, len(subs))
+	assert.Equal(t, "fast", subs[0].SubscriptionId)
+	assert.Equal(t, []domain.SportType{domain.Football}, subs[0].Sports)
+
+	manager.Unsubscribe("1")
+	assert.Equal(t, 0, len(manager.List()))
+}
+
+func TestGroupByInterval(t *testing.T) {
+	msg := &MessageToSubscribeDTO{
+		ClientId:       "1",
+		SubscriptionId: "live",
+		Sports:         []domain.SportType{domain.Soccer, domain.Baseball, domain.Football},
+		EventIDs:       []string{"soccer-1"},
+		UpdateInterval: time.Second,
+		SportIntervals: model.SportIntervalMap{domain.Soccer: 200 * time.Millisecond, domain.Football: 200 * time.Millisecond},
+	}
+
+	groups := groupByInterval(msg)
+
+	assert.Len(t, groups, 2)
+	assert.Equal(t, []domain.SportType{domain.Soccer, domain.Football}, groups[200*time.Millisecond].Sports)
+	assert.Empty(t, groups[200*time.Millisecond].EventIDs)
+	assert.Equal(t, []domain.SportType{domain.Baseball}, groups[time.Second].Sports)
+	assert.Equal(t, []string{"soccer-1"}, groups[time.Second].EventIDs)
+	for _, group := range groups {
+		assert.Equal(t, msg.key(), group.key())
+	}
+}
+
+func TestChangeSportsKeepsSportIntervals(t *testing.T) {
+	manager := NewSubscriptionManager(&MockLinesService{}, line_change.NewLineChangeBus(), 0, &fake.Logger{})
+	current := &MessageToSubscribeDTO{
+		ClientId:       "1",
+		SubscriptionId: "live",
+		Sports:         []domain.SportType{domain.Soccer, domain.Baseball},
+		UpdateInterval: time.Second,
+		SportIntervals: model.SportIntervalMap{domain.Soccer: 200 * time.Millisecond},
+	}
+	manager.requests[current.key()] = current
+
+	added, err := manager.changeSports(&MessageToSubscribeDTO{
+		ClientId:       "1",
+		SubscriptionId: "live",
+		Operation:      OperationAddSports,
+		Sports:         []domain.SportType{domain.Football},
+		SportIntervals: model.SportIntervalMap{domain.Football: 500 * time.Millisecond},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, model.SportIntervalMap{domain.Soccer: 200 * time.Millisecond, domain.Football: 500 * time.Millisecond}, added.SportIntervals)
+
+	removed, err := manager.changeSports(&MessageToSubscribeDTO{
+		ClientId:       "1",
+		SubscriptionId: "live",
+		Operation:      OperationRemoveSports,
+		Sports:         []domain.SportType{domain.Soccer},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []domain.SportType{domain.Baseball}, removed.Sports)
+	assert.Empty(t, removed.SportIntervals)
+	assert.Equal(t, model.SportIntervalMap{domain.Soccer: 200 * time.Millisecond}, current.SportIntervals)
+}
+
+func TestDeltaPolicyOfSubscription(t *testing.T) {
+	linesService := &MockLinesService{
+		FakeCalculate: func(sports []domain.SportType, isNeedDelta bool, subs *model.ClientSubscription) ([]*domain.SportLine, error) {
+			score := decimal.RequireFromString("1.2")
+			if _, send := subs.DeltaPolicy().Apply(score, subs.Sports[sports[0]]); isNeedDelta && !send {
+				return []*domain.SportLine{}, nil
+			}
+			subs.Sports[sports[0]] = score
+			return []*domain.SportLine{{Type: sports[0], Score: score}}, nil
+		},
+	}
+	manager := NewSubscriptionManager(linesService, line_change.NewLineChangeBus(), 0, &fake.Logger{})
+	sender := &chanResponseSender{sent: make(chan *model.LineUpdate, 10)}
+	threshold, err := model.NewDeltaPolicy(model.DeltaThreshold, decimal.RequireFromString("0.5"))
+	assert.Nil(t, err)
+	request := &MessageToSubscribeDTO{ClientId: "1", Sports: []domain.SportType{domain.Soccer}, DeliveryMode: model.DeliveryOnChange, Delta: threshold}
+	assert.True(t, manager.addNotifySubscriberTask(sender, request))
+	manager.updateSportLineFn(sender, request, nil)(true)
+
+	update := <-sender.sent
+	assert.Equal(t, uint64(1), update.Sequence)
+	assert.Equal(t, 0, len(sender.sent))
+
+	assert.False(t, manager.addNotifySubscriberTask(sender, request))
+	assert.True(t, manager.addNotifySubscriberTask(sender, &MessageToSubscribeDTO{ClientId: "1", Sports: []domain.SportType{domain.Soccer}, DeliveryMode: model.DeliveryOnChange}))
+	update = <-sender.sent
+	assert.Equal(t, uint64(2), update.Sequence)
+	assert.True(t, update.Snapshot)
+
+	manager.Unsubscribe("1")
+}
+
+func TestBaselineFollowsDeliveredMessages(t *testing.T) {
+	scores := []string{"1.5", "1.7", "2.0", "2.4"}
+	linesService := &MockLinesService{
+		FakeCalculate: func(sports []domain.SportType, isNeedDelta bool, subs *model.ClientSubscription) ([]*domain.SportLine, error) {
+			score := decimal.RequireFromString(scores[0])
+			scores = scores[1:]
+			line := &domain.SportLine{Type: sports[0], Score: score}
+			if isNeedDelta {
+				line.Score = score.Sub(subs.Sports[sports[0]])
+			}
+			subs.Sports[sports[0]] = score
+			return []*domain.SportLine{line}, nil
+		},
+	}
+	manager := NewSubscriptionManager(linesService, line_change.NewLineChangeBus(), 0, &fake.Logger{})
+	sender := &chanResponseSender{
+		sent:     make(chan *model.LineUpdate, 10),
+		acks:     make(chan *model.SubscriptionAck, 10),
+		rejected: make(chan error, 10),
+		failures: 1,
+	}
+	request := &MessageToSubscribeDTO{ClientId: "1", Sports: []domain.SportType{domain.Soccer}, DeliveryMode: model.DeliveryOnChange}
+	assert.True(t, manager.addNotifySubscriberTask(sender, request))
+	<-sender.acks
+	fn := manager.updateSportLineFn(sender, request, nil)
+
+	fn(true)
+	update := <-sender.sent
+	assert.Equal(t, uint64(1), update.Sequence)
+	assert.True(t, update.Snapshot)
+	assert.Equal(t, "1.7", update.Sports[0].Score.String())
+
+	fn(true)
+	update = <-sender.sent
+	assert.Equal(t, uint64(2), update.Sequence)
+	assert.False(t, update.Snapshot)
+	assert.Equal(t, "0.3", update.Sports[0].Score.String())
+
+	assert.False(t, manager.addNotifySubscriberTask(sender, &MessageToSubscribeDTO{ClientId: "1", Operation: OperationResetBaseline}))
+	<-sender.acks
+	update = <-sender.sent
+	assert.Equal(t, uint64(3), update.Sequence)
+	assert.True(t, update.Snapshot)
+	assert.Equal(t, "2.4", update.Sports[0].Score.String())
+
+	assert.False(t, manager.addNotifySubscriberTask(sender, &MessageToSubscribeDTO{ClientId: "1", SubscriptionId: "missing", Operation: OperationResetBaseline}))
+	assert.Equal(t, ErrUnknownSubscription, <-sender.rejected)
+
+	manager.Unsubscribe("1")
+}
+
+func TestCoalescedUpdateResendsSnapshot(t *testing.T) {
+	scores := []string{"1.5", "1.7", "2.0"}
+	linesService := &MockLinesService{
+		FakeCalculate: func(sports []domain.SportType, isNeedDelta bool, subs *model.ClientSubscription) ([]*domain.SportLine, error) {
+			score := decimal.RequireFromString(scores[0])
+			scores = scores[1:]
+			line := &domain.SportLine{Type: sports[0], Score: score}
+			if isNeedDelta {
+				line.Score = score.Sub(subs.Sports[sports[0]])
+			}
+			subs.Sports[sports[0]] = score
+			return []*domain.SportLine{line}, nil
+		},
+	}
+	manager := NewSubscriptionManager(linesService, line_change.NewLineChangeBus(), 0, &fake.Logger{})
+	sender := &chanResponseSender{
+		sent:      make(chan *model.LineUpdate, 10),
+		acks:      make(chan *model.SubscriptionAck, 10),
+		coalesced: 1,
+	}
+	request := &MessageToSubscribeDTO{ClientId: "1", Sports: []domain.SportType{domain.Soccer}, DeliveryMode: model.DeliveryOnChange}
+	assert.True(t, manager.addNotifySubscriberTask(sender, request))
+	<-sender.acks
+	update := <-sender.sent
+	assert.Equal(t, uint64(1), update.Sequence)
+	assert.True(t, update.Snapshot)
+
+	manager.updateSportLineFn(sender, request, nil)(true)
+	update = <-sender.sent
+	assert.Equal(t, uint64(2), update.Sequence)
+	assert.True(t, update.Snapshot)
+	assert.Equal(t, "2", update.Sports[0].Score.String())
+
+	manager.Unsubscribe("1")
+}
+
+func TestListWhileSubscriptionTicks(t *testing.T) {
+	linesService := &MockLinesService{
+		FakeCalculate: func(sports []domain.SportType, isNeedDelta bool, subs *model.ClientSubscription) ([]*domain.SportLine, error) {
+			for _, sportType := range sports {
+				subs.Sports[sportType] = subs.Sports[sportType].Add(decimal.NewFromInt(1))
+			}
+			return []*domain.SportLine{{Type: sports[0], Score: subs.Sports[sports[0]]}}, nil
+		},
+	}
+	manager := NewSubscriptionManager(linesService, line_change.NewLineChangeBus(), 0, &fake.Logger{})
+	sender := &chanResponseSender{sent: make(chan *model.LineUpdate, 10), acks: make(chan *model.SubscriptionAck, 10)}
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-sender.sent:
+			case <-done:
+				return
+			}
+		}
+	}()
+	request := &MessageToSubscribeDTO{ClientId: "1", Sports: []domain.SportType{domain.Soccer, domain.Baseball}, UpdateInterval: time.Millisecond}
+	assert.True(t, manager.addNotifySubscriberTask(sender, request))
+	<-sender.acks
+
+	for i := 0; i < 50; i++ {
+		list := manager.List()
+		assert.Equal(t, 1, len(list))
+		assert.Equal(t, []domain.SportType{domain.Baseball, domain.Soccer}, list[0].Sports)
+		assert.False(t, manager.isSubChanged(request.key(), request.Sports))
+		time.Sleep(time.Millisecond)
+	}
+	manager.Unsubscribe("1")
+	close(done)
+}
+
+func TestResubscribeWaitsForRunningTick(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var once sync.Once
+	linesService := &MockLinesService{
+		FakeCalculate: func(sports []domain.SportType, isNeedDelta bool, subs *model.ClientSubscription) ([]*domain.SportLine, error) {
+			if isNeedDelta && sports[0] == domain.Soccer {
+				once.Do(func() {
+					close(started)
+					<-release
+				})
+			}
+			return []*domain.SportLine{{Type: sports[0], Score: decimal.NewFromInt(1)}}, nil
+		},
+	}
+	manager := NewSubscriptionManager(linesService, line_change.NewLineChangeBus(), 0, &fake.Logger{})
+	sender := &chanResponseSender{sent: make(chan *model.LineUpdate, 10), acks: make(chan *model.SubscriptionAck, 10)}
+	assert.True(t, manager.addNotifySubscriberTask(sender, &MessageToSubscribeDTO{ClientId: "1", Sports: []domain.SportType{domain.Soccer}, UpdateInterval: time.Millisecond}))
+	<-sender.acks
+	assert.Equal(t, domain.Soccer, (<-sender.sent).Sports[0].Type)
+	<-started
+
+	go manager.addNotifySubscriberTask(sender, &MessageToSubscribeDTO{ClientId: "1", Sports: []domain.SportType{domain.Baseball}, UpdateInterval: 60 * time.Second})
+	select {
+	case <-sender.acks:
+		t.Fatal("subscription replaced while its tick was running")
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(release)
+	<-sender.acks
+	assert.Equal(t, domain.Soccer, (<-sender.sent).Sports[0].Type)
+	assert.Equal(t, domain.Baseball, (<-sender.sent).Sports[0].Type)
+
+	manager.Unsubscribe("1")
+	assert.Equal(t, 0, len(sender.sent))
+}
@@ -0,0 +1,93 @@
+package subscription
+
+import (
+	"github.com/col3name/lines/pkg/common/domain"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/domain/model"
+	"sync"
+)
+
+type topicSet struct {
+	mu          sync.RWMutex
+	sports      map[domain.SportType]struct{}
+	eventSports map[domain.SportType]struct{}
+}
+
+func newTopicSet(sports []domain.SportType) *topicSet {
+	set := &topicSet{
+		sports:      make(map[domain.SportType]struct{}, len(sports)),
+		eventSports: make(map[domain.SportType]struct{}),
+	}
+	for _, sportType := range sports {
+		set.sports[sportType] = struct{}{}
+	}
+	return set
+}
+
+func (t *topicSet) addEvents(events []*domain.Event) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, event := range events {
+		t.eventSports[event.SportType] = struct{}{}
+	}
+}
+
+func (t *topicSet) has(sportType domain.SportType, kind model.ChangeKind) bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	var ok bool
+	if kind == model.EventsChanged {
+		_, ok = t.eventSports[sportType]
+	} else {
+		_, ok = t.sports[sportType]
+	}
+	return ok
+}
+
+// pendingChanges collects the changes that arrive while an on-change subscription is being delivered.
+type pendingChanges struct {
+	mu     sync.Mutex
+	sports map[domain.SportType]struct{}
+	events bool
+	signal chan struct{}
+}
+
+func newPendingChanges() *pendingChanges {
+	return &pendingChanges{sports: make(map[domain.SportType]struct{}), signal: make(chan struct{}, 1)}
+}
+
+func (p *pendingChanges) add(sportType domain.SportType, kind model.ChangeKind) {
+	p.mu.Lock()
+	if kind == model.EventsChanged {
+		p.events = true
+	} else {
+		p.sports[sportType] = struct{}{}
+	}
+	p.mu.Unlock()
+	select {
+	case p.signal <- struct{}{}:
+	default:
+	}
+}
+
+// take returns the subscribed topics that changed since the last call, or nil if none did.
+func (p *pendingChanges) take(msg *MessageToSubscribeDTO) *MessageToSubscribeDTO {
+	p.mu.Lock()
+	sports, events := p.sports, p.events
+	p.sports, p.events = make(map[domain.SportType]struct{}), false
+	p.mu.Unlock()
+
+	changed := *msg
+	changed.Sports = nil
+	for _, sportType := range msg.Sports {
+		if _, ok := sports[sportType]; ok {
+			changed.Sports = append(changed.Sports, sportType)
+		}
+	}
+	if !events {
+		changed.EventIDs = nil
+	}
+	if len(changed.Sports) == 0 && len(changed.EventIDs) == 0 {
+		return nil
+	}
+	return &changed
+}
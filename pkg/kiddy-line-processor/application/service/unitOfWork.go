@@ -8,6 +8,10 @@ type Job func(RepositoryProvider) error
 
 type RepositoryProvider interface {
 	SportLineRepo() repo.SportLineRepo
+	SportLineHistoryRepo() repo.SportLineHistoryRepo
+	EventRepo() repo.EventRepo
+	SportRepo() repo.SportRepo
+	NotifyRepo() repo.NotifyRepo
 	MigrationRepo() repo.MigrationRepo
 }
 
@@ -0,0 +1,152 @@
+package model
+
+import (
+	"errors"
+	commonDomain "github.com/col3name/lines/pkg/common/domain"
+	"github.com/shopspring/decimal"
+	"strings"
+)
+
+type DeltaMode string
+
+const (
+	DeltaAbsolute  DeltaMode = "absolute"
+	DeltaChange    DeltaMode = "delta"
+	DeltaPercent   DeltaMode = "percent"
+	DeltaThreshold DeltaMode = "threshold"
+)
+
+var (
+	ErrUnsupportedDeltaMode  = errors.New("unsupported delta mode")
+	ErrInvalidDeltaThreshold = errors.New("delta threshold must be positive number")
+)
+
+const percentPrecision = 4
+
+var (
+	hundred            = decimal.NewFromInt(100)
+	DefaultDeltaPolicy = DeltaPolicy(changePolicy{})
+)
+
+func (m DeltaMode) String() string {
+	return string(m)
+}
+
+func NewDeltaMode(mode string) (DeltaMode, error) {
+	switch strings.ToLower(mode) {
+	case "", DeltaChange.String():
+		return DeltaChange, nil
+	case DeltaAbsolute.String():
+		return DeltaAbsolute, nil
+	case DeltaPercent.String():
+		return DeltaPercent, nil
+	case DeltaThreshold.String():
+		return DeltaThreshold, nil
+	default:
+		return "", ErrUnsupportedDeltaMode
+	}
+}
+
+type DeltaPolicy interface {
+	Mode() DeltaMode
+	Threshold() decimal.Decimal
+	Apply(current, previous decimal.Decimal) (value decimal.Decimal, send bool)
+	FormatOdds(format commonDomain.OddsFormat, current, previous decimal.Decimal) (string, error)
+}
+
+func NewDeltaPolicy(mode DeltaMode, threshold decimal.Decimal) (DeltaPolicy, error) {
+	switch mode {
+	case "", DeltaChange:
+		return changePolicy{}, nil
+	case DeltaAbsolute:
+		return absolutePolicy{}, nil
+	case DeltaPercent:
+		return percentPolicy{}, nil
+	case DeltaThreshold:
+		if !threshold.IsPositive() {
+			return nil, ErrInvalidDeltaThreshold
+		}
+		return thresholdPolicy{threshold: threshold}, nil
+	default:
+		return nil, ErrUnsupportedDeltaMode
+	}
+}
+
+type absolutePolicy struct{}
+
+func (absolutePolicy) Mode() DeltaMode {
+	return DeltaAbsolute
+}
+
+func (absolutePolicy) Threshold() decimal.Decimal {
+	return decimal.Zero
+}
+
+func (absolutePolicy) Apply(current, _ decimal.Decimal) (decimal.Decimal, bool) {
+	return current, true
+}
+
+func (absolutePolicy) FormatOdds(format commonDomain.OddsFormat, current, _ decimal.Decimal) (string, error) {
+	return format.Format(current)
+}
+
+type changePolicy struct{}
+
+func (changePolicy) Mode() DeltaMode {
+	return DeltaChange
+}
+
+func (changePolicy) Threshold() decimal.Decimal {
+	return decimal.Zero
+}
+
+func (changePolicy) Apply(current, previous decimal.Decimal) (decimal.Decimal, bool) {
+	return current.Sub(previous), true
+}
+
+func (changePolicy) FormatOdds(format commonDomain.OddsFormat, current, previous decimal.Decimal) (string, error) {
+	return format.FormatDelta(current, previous)
+}
+
+type percentPolicy struct{}
+
+func (percentPolicy) Mode() DeltaMode {
+	return DeltaPercent
+}
+
+func (percentPolicy) Threshold() decimal.Decimal {
+	return decimal.Zero
+}
+
+func (percentPolicy) Apply(current, previous decimal.Decimal) (decimal.Decimal, bool) {
+	if previous.IsZero() {
+		return decimal.Zero, true
+	}
+	return current.Sub(previous).Div(previous).Mul(hundred).Round(percentPrecision), true
+}
+
+// FormatOdds leaves the odds empty: a percentage has no odds form and the price already carries it.
+func (percentPolicy) FormatOdds(commonDomain.OddsFormat, decimal.Decimal, decimal.Decimal) (string, error) {
+	return "", nil
+}
+
+type thresholdPolicy struct {
+	threshold decimal.Decimal
+}
+
+func (thresholdPolicy) Mode() DeltaMode {
+	return DeltaThreshold
+}
+
+func (p thresholdPolicy) Threshold() decimal.Decimal {
+	return p.threshold
+}
+
+func (p thresholdPolicy) Apply(current, previous decimal.Decimal) (decimal.Decimal, bool) {
+	delta := current.Sub(previous)
+	return delta, delta.Abs().GreaterThan(p.threshold)
+}
+
+func (thresholdPolicy) FormatOdds(format commonDomain.OddsFormat, current, previous decimal.Decimal) (string, error) {
+	return format.FormatDelta(current, previous)
+}
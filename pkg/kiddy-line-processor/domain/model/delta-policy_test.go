@@ -0,0 +1,42 @@
+package model
+
+import (
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestDeltaPolicyApply(t *testing.T) {
+	tests := []struct {
+		name      string
+		mode      DeltaMode
+		threshold string
+		current   string
+		previous  string
+		value     string
+		send      bool
+		err       error
+	}{
+		{name: "default mode", mode: "", threshold: "0", current: "2.5", previous: "2.0", value: "0.5", send: true},
+		{name: "absolute", mode: DeltaAbsolute, threshold: "0", current: "2.5", previous: "2.0", value: "2.5", send: true},
+		{name: "delta", mode: DeltaChange, threshold: "0", current: "1.5", previous: "2.0", value: "-0.5", send: true},
+		{name: "percent", mode: DeltaPercent, threshold: "0", current: "1.5", previous: "2.0", value: "-25", send: true},
+		{name: "percent of zero baseline", mode: DeltaPercent, threshold: "0", current: "1.5", previous: "0", value: "0", send: true},
+		{name: "threshold not exceeded", mode: DeltaThreshold, threshold: "0.5", current: "1.5", previous: "2.0", value: "-0.5", send: false},
+		{name: "threshold exceeded", mode: DeltaThreshold, threshold: "0.5", current: "1.4", previous: "2.0", value: "-0.6", send: true},
+		{name: "threshold not positive", mode: DeltaThreshold, threshold: "0", err: ErrInvalidDeltaThreshold},
+		{name: "unsupported mode", mode: "ratio", threshold: "0", err: ErrUnsupportedDeltaMode},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			policy, err := NewDeltaPolicy(test.mode, decimal.RequireFromString(test.threshold))
+			assert.Equal(t, test.err, err)
+			if err != nil {
+				return
+			}
+			value, send := policy.Apply(decimal.RequireFromString(test.current), decimal.RequireFromString(test.previous))
+			assert.True(t, decimal.RequireFromString(test.value).Equal(value), "expected %s, actual %s", test.value, value)
+			assert.Equal(t, test.send, send)
+		})
+	}
+}
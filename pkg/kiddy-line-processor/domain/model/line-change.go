@@ -0,0 +1,53 @@
+package model
+
+import (
+	"errors"
+	commonDomain "github.com/col3name/lines/pkg/common/domain"
+	"strings"
+)
+
+type ChangeKind string
+
+const (
+	LineChanged   ChangeKind = "line"
+	EventsChanged ChangeKind = "events"
+)
+
+var ErrUnsupportedChangeKind = errors.New("unsupported change kind")
+
+func (k ChangeKind) String() string {
+	return string(k)
+}
+
+func NewChangeKind(kind string) (ChangeKind, error) {
+	switch strings.ToLower(kind) {
+	case LineChanged.String():
+		return LineChanged, nil
+	case EventsChanged.String():
+		return EventsChanged, nil
+	default:
+		return "", ErrUnsupportedChangeKind
+	}
+}
+
+// ChangeNotification is the payload of a change notification: "<kind>:<sport>".
+// A bare sport is a line change, as sent before the kinds were introduced.
+func ChangeNotification(sportType commonDomain.SportType, kind ChangeKind) string {
+	return kind.String() + ":" + sportType.String()
+}
+
+func ParseChangeNotification(payload string) (commonDomain.SportType, ChangeKind, error) {
+	kind := LineChanged
+	if i := strings.IndexByte(payload, ':'); i >= 0 {
+		var err error
+		if kind, err = NewChangeKind(payload[:i]); err != nil {
+			return "", "", err
+		}
+		payload = payload[i+1:]
+	}
+	sportType, err := commonDomain.ParseSportType(payload)
+	if err != nil {
+		return "", "", err
+	}
+	return sportType, kind, nil
+}
@@ -0,0 +1,32 @@
+package model
+
+import (
+	commonDomain "github.com/col3name/lines/pkg/common/domain"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseChangeNotification(t *testing.T) {
+	tests := []struct {
+		name      string
+		payload   string
+		sportType commonDomain.SportType
+		kind      ChangeKind
+		err       error
+	}{
+		{name: "line change", payload: "line:soccer", sportType: commonDomain.Soccer, kind: LineChanged},
+		{name: "events change", payload: "events:soccer", sportType: commonDomain.Soccer, kind: EventsChanged},
+		{name: "bare sport is line change", payload: "soccer", sportType: commonDomain.Soccer, kind: LineChanged},
+		{name: "unsupported kind", payload: "odds:soccer", err: ErrUnsupportedChangeKind},
+		{name: "invalid sport", payload: "line:not a sport!", err: commonDomain.ErrInvalidSportType},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sportType, kind, err := ParseChangeNotification(test.payload)
+			assert.Equal(t, test.err, err)
+			assert.Equal(t, test.sportType, sportType)
+			assert.Equal(t, test.kind, kind)
+		})
+	}
+	assert.Equal(t, "events:baseball", ChangeNotification(commonDomain.Baseball, EventsChanged))
+}
@@ -0,0 +1,72 @@
+package model
+
+import (
+	commonDomain "github.com/col3name/lines/pkg/common/domain"
+	"sync"
+	"time"
+)
+
+type LineUpdate struct {
+	SubscriptionId string
+	Sequence       uint64
+	Snapshot       bool
+	Sports         []*commonDomain.SportLine
+	Events         []*commonDomain.Event
+}
+
+type SubscriptionAck struct {
+	SubscriptionId string
+	Sports         []commonDomain.SportType
+	Markets        []commonDomain.MarketType
+	EventIDs       []string
+	OddsFormat     commonDomain.OddsFormat
+	Interval       time.Duration
+	SportIntervals SportIntervalMap
+	Mode           DeliveryMode
+	Delta          DeltaPolicy
+	Removed        bool
+}
+
+type UpdateLog struct {
+	sync.Mutex
+	sequence uint64
+	history  []*LineUpdate
+	size     int
+}
+
+func NewUpdateLog(size int) *UpdateLog {
+	return &UpdateLog{history: make([]*LineUpdate, 0, size), size: size}
+}
+
+// Reserve builds the next update without taking its sequence. The sequence is only taken by Commit,
+// so an update that could not be sent leaves no gap and evicts nothing from the log.
+func (l *UpdateLog) Reserve(subscriptionId string, snapshot bool, sports []*commonDomain.SportLine, events []*commonDomain.Event) *LineUpdate {
+	return &LineUpdate{SubscriptionId: subscriptionId, Sequence: l.sequence + 1, Snapshot: snapshot, Sports: sports, Events: events}
+}
+
+// Commit records a reserved update once it was sent. An update reserved before another commit is ignored.
+func (l *UpdateLog) Commit(update *LineUpdate) {
+	if update.Sequence != l.sequence+1 {
+		return
+	}
+	l.sequence = update.Sequence
+	if len(l.history) == l.size {
+		copy(l.history, l.history[1:])
+		l.history = l.history[:l.size-1]
+	}
+	l.history = append(l.history, update)
+}
+
+func (l *UpdateLog) Since(sequence uint64) ([]*LineUpdate, bool) {
+	if sequence == 0 || sequence > l.sequence+1 {
+		return nil, false
+	}
+	if sequence == l.sequence+1 {
+		return nil, true
+	}
+	if len(l.history) == 0 || l.history[0].Sequence > sequence {
+		return nil, false
+	}
+	offset := int(sequence - l.history[0].Sequence)
+	return append([]*LineUpdate(nil), l.history[offset:]...), true
+}
@@ -0,0 +1,79 @@
+package model
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestUpdateLogSince(t *testing.T) {
+	log := NewUpdateLog(3)
+	for i := 0; i < 5; i++ {
+		log.Commit(log.Reserve("", i == 0, nil, nil))
+	}
+
+	tests := []struct {
+		name      string
+		from      uint64
+		sequences []uint64
+		ok        bool
+	}{
+		{name: "zero sequence", from: 0, ok: false},
+		{name: "evicted sequence", from: 2, ok: false},
+		{name: "oldest kept sequence", from: 3, sequences: []uint64{3, 4, 5}, ok: true},
+		{name: "latest sequence", from: 5, sequences: []uint64{5}, ok: true},
+		{name: "next sequence", from: 6, ok: true},
+		{name: "future sequence", from: 7, ok: false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			updates, ok := log.Since(test.from)
+			assert.Equal(t, test.ok, ok)
+			var sequences []uint64
+			for _, update := range updates {
+				sequences = append(sequences, update.Sequence)
+			}
+			assert.Equal(t, test.sequences, sequences)
+		})
+	}
+}
+
+func TestUpdateLogCommit(t *testing.T) {
+	log := NewUpdateLog(3)
+	first := log.Reserve("", true, nil, nil)
+	log.Commit(first)
+	unsent := log.Reserve("", false, nil, nil)
+	second := log.Reserve("", false, nil, nil)
+	log.Commit(second)
+	log.Commit(unsent)
+
+	assert.Equal(t, uint64(2), second.Sequence)
+	updates, ok := log.Since(1)
+	assert.True(t, ok)
+	assert.Equal(t, []*LineUpdate{first, second}, updates)
+}
+
+func TestUpdateLogFullKeepsEntriesOfUnsentUpdate(t *testing.T) {
+	log := NewUpdateLog(2)
+	first := log.Reserve("", true, nil, nil)
+	log.Commit(first)
+	second := log.Reserve("", false, nil, nil)
+	log.Commit(second)
+
+	unsent := log.Reserve("", false, nil, nil)
+	assert.Equal(t, uint64(3), unsent.Sequence)
+	updates, ok := log.Since(1)
+	assert.True(t, ok)
+	assert.Equal(t, []*LineUpdate{first, second}, updates)
+
+	third := log.Reserve("", false, nil, nil)
+	log.Commit(third)
+	assert.Equal(t, uint64(3), third.Sequence)
+	_, ok = log.Since(1)
+	assert.False(t, ok)
+	updates, ok = log.Since(2)
+	assert.True(t, ok)
+	assert.Equal(t, []*LineUpdate{second, third}, updates)
+	updates, ok = log.Since(4)
+	assert.True(t, ok)
+	assert.Empty(t, updates)
+}
@@ -1,13 +1,181 @@
 package model
 
 import (
+	"errors"
 	commonDomain "github.com/col3name/lines/pkg/common/domain"
+	"github.com/shopspring/decimal"
+	"strings"
 	"time"
 )
 
-type SportTypeMap map[commonDomain.SportType]float32
+type DeliveryMode string
+
+const (
+	DeliveryInterval DeliveryMode = "interval"
+	DeliveryOnChange DeliveryMode = "on_change"
+)
+
+var ErrUnsupportedDeliveryMode = errors.New("unsupported delivery mode")
+
+func (m DeliveryMode) String() string {
+	return string(m)
+}
+
+func NewDeliveryMode(mode string) (DeliveryMode, error) {
+	switch strings.ToLower(mode) {
+	case "", DeliveryInterval.String():
+		return DeliveryInterval, nil
+	case DeliveryOnChange.String():
+		return DeliveryOnChange, nil
+	default:
+		return "", ErrUnsupportedDeliveryMode
+	}
+}
+
+type SportTypeMap map[commonDomain.SportType]decimal.Decimal
+
+type SelectionKey struct {
+	SportType  commonDomain.SportType
+	MarketType commonDomain.MarketType
+	Selection  string
+}
+
+type SelectionPriceMap map[SelectionKey]decimal.Decimal
+
+type EventLineMap map[string]decimal.Decimal
+
+type SportIntervalMap map[commonDomain.SportType]time.Duration
 
 type ClientSubscription struct {
-	Sports SportTypeMap
-	Task   *time.Ticker
+	Sports         SportTypeMap
+	Markets        []commonDomain.MarketType
+	Selections     SelectionPriceMap
+	Events         EventLineMap
+	OddsFormat     commonDomain.OddsFormat
+	Interval       time.Duration
+	SportIntervals SportIntervalMap
+	Mode           DeliveryMode
+	Delta          DeltaPolicy
+	Delivered      bool
+	Tasks          []func()
+	Log            *UpdateLog
+}
+
+// SkipsUnchanged reports whether deltas leave out values that equal the baseline.
+// An on-change subscription is only woken by changes, so a zero delta carries nothing.
+func (s *ClientSubscription) SkipsUnchanged() bool {
+	return s.Mode == DeliveryOnChange
+}
+
+func (s *ClientSubscription) DeltaPolicy() DeltaPolicy {
+	if s.Delta == nil {
+		return DefaultDeltaPolicy
+	}
+	return s.Delta
+}
+
+type Baseline struct {
+	sports     SportTypeMap
+	selections SelectionPriceMap
+	events     EventLineMap
+	delivered  bool
+}
+
+func (s *ClientSubscription) SaveBaseline() *Baseline {
+	baseline := &Baseline{
+		sports:     make(SportTypeMap, len(s.Sports)),
+		selections: make(SelectionPriceMap, len(s.Selections)),
+		events:     make(EventLineMap, len(s.Events)),
+		delivered:  s.Delivered,
+	}
+	for sportType, score := range s.Sports {
+		baseline.sports[sportType] = score
+	}
+	for key, price := range s.Selections {
+		baseline.selections[key] = price
+	}
+	for id, line := range s.Events {
+		baseline.events[id] = line
+	}
+	return baseline
+}
+
+func (s *ClientSubscription) RestoreBaseline(baseline *Baseline) {
+	for sportType, score := range baseline.sports {
+		s.Sports[sportType] = score
+	}
+	for key := range s.Selections {
+		if _, ok := baseline.selections[key]; !ok {
+			delete(s.Selections, key)
+		}
+	}
+	for key, price := range baseline.selections {
+		s.Selections[key] = price
+	}
+	for id := range s.Events {
+		if _, ok := baseline.events[id]; !ok {
+			delete(s.Events, id)
+		}
+	}
+	for id, line := range baseline.events {
+		s.Events[id] = line
+	}
+	s.Delivered = baseline.delivered
+}
+
+func (s *ClientSubscription) ResetBaseline() {
+	s.Delivered = false
+}
+
+type SportLineHistoryRecord struct {
+	ID         int64
+	Line       commonDomain.SportLine
+	FetchedAt  time.Time
+	IngestedAt time.Time
+}
+
+type SportLineHistoryPage struct {
+	Records    []*SportLineHistoryRecord
+	NextCursor string
+}
+
+type Candle struct {
+	SportType commonDomain.SportType
+	OpenTime  time.Time
+
+	Open  decimal.Decimal
+	High  decimal.Decimal
+	Low   decimal.Decimal
+	Close decimal.Decimal
+	Count int
+}
+
+var CandleBuckets = []time.Duration{time.Second, time.Minute, 5 * time.Minute, time.Hour}
+
+func IsSupportedCandleBucket(bucket time.Duration) bool {
+	for _, supported := range CandleBuckets {
+		if supported == bucket {
+			return true
+		}
+	}
+	return false
+}
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+type AppliedMigration struct {
+	Version   int
+	Name      string
+	AppliedAt time.Time
+}
+
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	AppliedAt *time.Time
 }
@@ -0,0 +1,31 @@
+package model
+
+import (
+	commonDomain "github.com/col3name/lines/pkg/common/domain"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestRestoreBaseline(t *testing.T) {
+	homeKey := SelectionKey{SportType: commonDomain.Soccer, MarketType: commonDomain.Moneyline, Selection: "home"}
+	awayKey := SelectionKey{SportType: commonDomain.Soccer, MarketType: commonDomain.Moneyline, Selection: "away"}
+	sub := &ClientSubscription{
+		Sports:     SportTypeMap{commonDomain.Soccer: decimal.RequireFromString("1.0")},
+		Selections: SelectionPriceMap{homeKey: decimal.RequireFromString("2.0")},
+		Events:     EventLineMap{},
+	}
+	baseline := sub.SaveBaseline()
+
+	sub.Sports[commonDomain.Soccer] = decimal.RequireFromString("1.5")
+	sub.Selections[homeKey] = decimal.RequireFromString("2.5")
+	sub.Selections[awayKey] = decimal.RequireFromString("1.5")
+	sub.Events["soccer-1"] = decimal.RequireFromString("3.0")
+	sub.Delivered = true
+	sub.RestoreBaseline(baseline)
+
+	assert.Equal(t, SportTypeMap{commonDomain.Soccer: decimal.RequireFromString("1.0")}, sub.Sports)
+	assert.Equal(t, SelectionPriceMap{homeKey: decimal.RequireFromString("2.0")}, sub.Selections)
+	assert.Equal(t, EventLineMap{}, sub.Events)
+	assert.False(t, sub.Delivered)
+}
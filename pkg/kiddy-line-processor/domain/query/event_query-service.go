@@ -0,0 +1,8 @@
+package query
+
+import "github.com/col3name/lines/pkg/common/domain"
+
+type EventQueryService interface {
+	GetEventsBySportType(sportType domain.SportType) ([]*domain.Event, error)
+	GetEventsByIDs(ids []string) ([]*domain.Event, error)
+}
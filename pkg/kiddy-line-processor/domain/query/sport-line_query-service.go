@@ -1,7 +1,20 @@
 package query
 
-import "github.com/col3name/lines/pkg/common/domain"
+import (
+	"github.com/col3name/lines/pkg/common/domain"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/domain/model"
+	"time"
+)
+
+const (
+	MaxHistoryPageSize = 1000
+	MaxCandlePageSize  = 500
+)
 
 type SportLineQueryService interface {
 	GetLinesBySportTypes(sportTypes []domain.SportType) ([]*domain.SportLine, error)
+	GetMarketsBySportTypes(sportTypes []domain.SportType, marketTypes []domain.MarketType) (map[domain.SportType][]*domain.Market, error)
+	GetLinesAsOf(sportTypes []domain.SportType, asOf time.Time) ([]*domain.SportLine, error)
+	GetLineHistory(sportType domain.SportType, from, to time.Time, limit int, cursor string) (*model.SportLineHistoryPage, error)
+	GetCandles(sportType domain.SportType, bucket time.Duration, from, to time.Time, limit int) ([]*model.Candle, error)
 }
@@ -0,0 +1,9 @@
+package repo
+
+import (
+	"github.com/col3name/lines/pkg/common/domain"
+)
+
+type EventRepo interface {
+	Store(events []*domain.Event) error
+}
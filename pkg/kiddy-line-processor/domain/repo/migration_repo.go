@@ -1,5 +1,11 @@
 package repo
 
+import "github.com/col3name/lines/pkg/kiddy-line-processor/domain/model"
+
 type MigrationRepo interface {
-	Migrate() error
+	Lock() error
+	CreateSchemaMigrationsTable() error
+	GetAppliedMigrations() ([]*model.AppliedMigration, error)
+	Apply(migration *model.Migration) error
+	Revert(migration *model.Migration) error
 }
@@ -0,0 +1,8 @@
+package repo
+
+import "github.com/col3name/lines/pkg/common/domain"
+
+type NotifyRepo interface {
+	NotifyLineChanged(sportType domain.SportType) error
+	NotifyEventsChanged(sportType domain.SportType) error
+}
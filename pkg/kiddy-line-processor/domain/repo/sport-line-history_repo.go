@@ -0,0 +1,9 @@
+package repo
+
+import (
+	"github.com/col3name/lines/pkg/kiddy-line-processor/domain/model"
+)
+
+type SportLineHistoryRepo interface {
+	Append(record *model.SportLineHistoryRecord) error
+}
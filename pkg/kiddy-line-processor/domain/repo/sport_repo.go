@@ -0,0 +1,8 @@
+package repo
+
+import "github.com/col3name/lines/pkg/common/domain"
+
+type SportRepo interface {
+	Add(sportType domain.SportType) error
+	Remove(sportType domain.SportType) error
+}
@@ -0,0 +1,239 @@
+package adapter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	commonDomain "github.com/col3name/lines/pkg/common/domain"
+	"github.com/shopspring/decimal"
+	"sort"
+	"strings"
+	"time"
+)
+
+type MissingLineError struct {
+	SportType commonDomain.SportType
+}
+
+func (e *MissingLineError) Error() string {
+	return fmt.Sprintf("lines response does not contain %s line", e.SportType)
+}
+
+type UnexpectedLinesError struct {
+	SportType commonDomain.SportType
+	Keys      []string
+}
+
+func (e *UnexpectedLinesError) Error() string {
+	return fmt.Sprintf("lines response for %s contains unexpected keys: %s", e.SportType, strings.Join(e.Keys, ", "))
+}
+
+type UnknownMarketsError struct {
+	SportType commonDomain.SportType
+	Types     []string
+}
+
+func (e *UnknownMarketsError) Error() string {
+	return fmt.Sprintf("lines response for %s contains unknown market types: %s", e.SportType, strings.Join(e.Types, ", "))
+}
+
+func IsLinesSchemaError(err error) bool {
+	var missingLineErr *MissingLineError
+	var unexpectedLinesErr *UnexpectedLinesError
+	return errors.As(err, &missingLineErr) || errors.As(err, &unexpectedLinesErr)
+}
+
+type linesResponse struct {
+	Lines   map[string]json.RawMessage  `json:"lines"`
+	Markets map[string][]marketResponse `json:"markets"`
+}
+
+type marketResponse struct {
+	Type       string              `json:"type"`
+	Point      json.RawMessage     `json:"point"`
+	Selections []selectionResponse `json:"selections"`
+}
+
+type selectionResponse struct {
+	Name  string          `json:"name"`
+	Price json.RawMessage `json:"price"`
+}
+
+func decodeSportLine(bytes []byte, sportType commonDomain.SportType) (*commonDomain.SportLine, []string, error) {
+	var resp linesResponse
+	if err := json.Unmarshal(bytes, &resp); err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		rawScore      json.RawMessage
+		isFound       bool
+		unexpectedKey []string
+	)
+	for key, value := range resp.Lines {
+		if isFound || !strings.EqualFold(key, sportType.String()) {
+			unexpectedKey = append(unexpectedKey, key)
+			continue
+		}
+		rawScore = value
+		isFound = true
+	}
+	if !isFound {
+		return nil, nil, &MissingLineError{SportType: sportType}
+	}
+	if len(unexpectedKey) > 0 {
+		sort.Strings(unexpectedKey)
+		return nil, nil, &UnexpectedLinesError{SportType: sportType, Keys: unexpectedKey}
+	}
+
+	score, err := decodeScore(rawScore)
+	if err != nil {
+		return nil, nil, err
+	}
+	sport := commonDomain.SportLine{Type: sportType}
+	if err = sport.SetScore(score); err != nil {
+		return nil, nil, err
+	}
+	var unknownMarkets []string
+	sport.Markets, unknownMarkets, err = decodeMarkets(resp.Markets, sportType)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &sport, unknownMarkets, nil
+}
+
+type eventsResponse struct {
+	Events []eventResponse `json:"events"`
+}
+
+type eventResponse struct {
+	ID        string          `json:"id"`
+	HomeTeam  string          `json:"homeTeam"`
+	AwayTeam  string          `json:"awayTeam"`
+	StartTime string          `json:"startTime"`
+	Status    string          `json:"status"`
+	Line      json.RawMessage `json:"line"`
+}
+
+func decodeEvents(bytes []byte, sportType commonDomain.SportType) ([]*commonDomain.Event, error) {
+	var resp eventsResponse
+	if err := json.Unmarshal(bytes, &resp); err != nil {
+		return nil, err
+	}
+
+	events := make([]*commonDomain.Event, 0, len(resp.Events))
+	for _, item := range resp.Events {
+		event, err := decodeEvent(item, sportType)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+func decodeEvent(resp eventResponse, sportType commonDomain.SportType) (*commonDomain.Event, error) {
+	if len(resp.ID) == 0 {
+		return nil, errors.New("event without id")
+	}
+	startTime, err := time.Parse(time.RFC3339, resp.StartTime)
+	if err != nil {
+		return nil, err
+	}
+	status, err := commonDomain.NewEventStatus(resp.Status)
+	if err != nil {
+		return nil, err
+	}
+	line, err := decodeDecimal(resp.Line)
+	if err != nil {
+		return nil, err
+	}
+	return &commonDomain.Event{
+		ID:        resp.ID,
+		SportType: sportType,
+		HomeTeam:  resp.HomeTeam,
+		AwayTeam:  resp.AwayTeam,
+		StartTime: startTime,
+		Status:    status,
+		Line:      line,
+	}, nil
+}
+
+func decodeMarkets(markets map[string][]marketResponse, sportType commonDomain.SportType) ([]*commonDomain.Market, []string, error) {
+	var (
+		sportMarkets  []marketResponse
+		unexpectedKey []string
+	)
+	for key, value := range markets {
+		if !strings.EqualFold(key, sportType.String()) {
+			unexpectedKey = append(unexpectedKey, key)
+			continue
+		}
+		sportMarkets = value
+	}
+	if len(unexpectedKey) > 0 {
+		sort.Strings(unexpectedKey)
+		return nil, nil, &UnexpectedLinesError{SportType: sportType, Keys: unexpectedKey}
+	}
+
+	var (
+		result  []*commonDomain.Market
+		unknown []string
+	)
+	for _, resp := range sportMarkets {
+		marketType, err := commonDomain.NewMarketType(resp.Type)
+		if err != nil {
+			unknown = append(unknown, resp.Type)
+			continue
+		}
+		market, err := decodeMarket(marketType, resp)
+		if err != nil {
+			return nil, nil, err
+		}
+		result = append(result, market)
+	}
+	sort.Strings(unknown)
+	return result, unknown, nil
+}
+
+func decodeMarket(marketType commonDomain.MarketType, resp marketResponse) (*commonDomain.Market, error) {
+	market := &commonDomain.Market{Type: marketType}
+	var err error
+	if len(resp.Point) > 0 {
+		if market.Point, err = decodeDecimal(resp.Point); err != nil {
+			return nil, err
+		}
+	}
+	for _, selection := range resp.Selections {
+		price, err := decodeDecimal(selection.Price)
+		if err != nil {
+			return nil, err
+		}
+		market.Selections = append(market.Selections, &commonDomain.Selection{Name: selection.Name, Price: price})
+	}
+	return market, nil
+}
+
+func decodeDecimal(raw json.RawMessage) (decimal.Decimal, error) {
+	value, err := decodeScore(raw)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	number, err := decimal.NewFromString(value)
+	if err != nil {
+		return decimal.Zero, commonDomain.ErrInvalidScore
+	}
+	return number, nil
+}
+
+func decodeScore(raw json.RawMessage) (string, error) {
+	var score string
+	if err := json.Unmarshal(raw, &score); err == nil {
+		return score, nil
+	}
+	var number json.Number
+	if err := json.Unmarshal(raw, &number); err != nil {
+		return "", commonDomain.ErrInvalidScore
+	}
+	return number.String(), nil
+}
@@ -1,7 +1,6 @@
 package adapter
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/col3name/lines/pkg/common/application/logger"
@@ -12,30 +11,6 @@ import (
 	"net/http"
 )
 
-type BaseSport struct {
-}
-
-type BaseballResp struct {
-	BaseSport
-	Lines struct {
-		Score string `json:"BASEBALL"`
-	} `json:"lines"`
-}
-
-type FootballResp struct {
-	BaseSport
-	Lines struct {
-		Score string `json:"FOOTBALL"`
-	} `json:"lines"`
-}
-
-type SoccerResp struct {
-	BaseSport
-	Lines struct {
-		Score string `json:"SOCCER"`
-	} `json:"lines"`
-}
-
 type linesProviderAdapter struct {
 	linesProviderUrl string
 	logger           logger.Logger
@@ -54,11 +29,45 @@ func (s linesProviderAdapter) GetLineBySport(sportType commonDomain.SportType) (
 	return s.parseResp(resp, sportType)
 }
 
+func (s linesProviderAdapter) GetEventsBySport(sportType commonDomain.SportType) ([]*commonDomain.Event, error) {
+	url := s.getLinesURL(sportType) + "/events"
+	resp, err := http2.Get(url)
+	if err != nil {
+		return nil, infrastructure.ExternalError(s.logger, err)
+	}
+	bytes, err := s.readBody(resp, sportType)
+	if err != nil {
+		return nil, err
+	}
+	events, err := decodeEvents(bytes, sportType)
+	if err != nil {
+		return nil, infrastructure.InternalError(s.logger, err)
+	}
+	return events, nil
+}
+
 func (s linesProviderAdapter) getLinesURL(sportType commonDomain.SportType) string {
 	return fmt.Sprintf("%s/api/v1/lines/%s", s.linesProviderUrl, sportType)
 }
 
 func (s *linesProviderAdapter) parseResp(resp *http.Response, sportType commonDomain.SportType) (*commonDomain.SportLine, error) {
+	bytes, err := s.readBody(resp, sportType)
+	if err != nil {
+		return nil, err
+	}
+
+	sportLine, err := s.parseGetLinesResponse(bytes, sportType)
+	if err != nil {
+		if IsLinesSchemaError(err) {
+			s.logger.Error(err)
+			return nil, err
+		}
+		return nil, infrastructure.InternalError(s.logger, err)
+	}
+	return sportLine, nil
+}
+
+func (s *linesProviderAdapter) readBody(resp *http.Response, sportType commonDomain.SportType) ([]byte, error) {
 	if resp.StatusCode != http.StatusOK {
 		err := s.failedGetSportError(sportType, nil)
 		return nil, infrastructure.ExternalError(s.logger, err)
@@ -69,12 +78,7 @@ func (s *linesProviderAdapter) parseResp(resp *http.Response, sportType commonDo
 		return nil, infrastructure.InternalError(s.logger, err)
 	}
 	defer resp.Body.Close()
-
-	sportLine, err := s.parseGetLinesResponse(bytes, sportType)
-	if err != nil {
-		return nil, infrastructure.InternalError(s.logger, err)
-	}
-	return sportLine, nil
+	return bytes, nil
 }
 
 func (s *linesProviderAdapter) failedGetSportError(sportType commonDomain.SportType, err error) error {
@@ -86,38 +90,12 @@ func (s *linesProviderAdapter) failedGetSportError(sportType commonDomain.SportT
 }
 
 func (s *linesProviderAdapter) parseGetLinesResponse(bytes []byte, sportType commonDomain.SportType) (*commonDomain.SportLine, error) {
-	var (
-		sport commonDomain.SportLine
-		score string
-		err   error
-	)
-	switch sportType {
-	case commonDomain.Baseball:
-		var model BaseballResp
-		err = json.Unmarshal(bytes, &model)
-		if err != nil {
-			return nil, err
-		}
-		sport.Type = commonDomain.Baseball
-		score = model.Lines.Score
-	case commonDomain.Soccer:
-		var model SoccerResp
-		err = json.Unmarshal(bytes, &model)
-		if err != nil {
-			return nil, err
-		}
-		sport.Type = commonDomain.Soccer
-		score = model.Lines.Score
-	case commonDomain.Football:
-		var model FootballResp
-		err = json.Unmarshal(bytes, &model)
-		if err != nil {
-			return nil, err
-		}
-		sport.Type = commonDomain.Football
-		score = model.Lines.Score
+	sportLine, unknownMarkets, err := decodeSportLine(bytes, sportType)
+	if err != nil {
+		return nil, err
 	}
-	err = sport.SetScore(score)
-
-	return &sport, err
+	if len(unknownMarkets) > 0 {
+		s.logger.Warn(&UnknownMarketsError{SportType: sportType, Types: unknownMarkets})
+	}
+	return sportLine, nil
 }
@@ -6,11 +6,13 @@ import (
 	"github.com/col3name/lines/pkg/common/domain"
 	http2 "github.com/col3name/lines/pkg/common/infrastructure/transport/http"
 	"github.com/col3name/lines/pkg/kiddy-line-processor/application/fake"
+	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
 	"io"
 	"net/http"
 	"strings"
 	"testing"
+	"time"
 )
 
 type MockClient struct {
@@ -166,7 +168,7 @@ func TestGetLines(t *testing.T) {
 				sportType: domain.Soccer,
 			},
 			expected: &expectedTestCase{
-				err:       appErr.ErrInternal,
+				err:       &MissingLineError{SportType: domain.Soccer},
 				sportLine: nil,
 			},
 		},
@@ -181,6 +183,38 @@ func TestGetLines(t *testing.T) {
 				},
 				sportType: domain.Soccer,
 			},
+			expected: &expectedTestCase{
+				err:       &MissingLineError{SportType: domain.Soccer},
+				sportLine: nil,
+			},
+		},
+		{
+			name: "unexpected keys in lines",
+			input: &inputTestCase{
+				doFunc: func(req *http.Request) (*http.Response, error) {
+					return &http.Response{
+						StatusCode: http.StatusOK,
+						Body:       io.NopCloser(strings.NewReader("{\"lines\":{\"SOCCER\":\"0.774\",\"FOOTBALL\":\"1.1\"}}")),
+					}, nil
+				},
+				sportType: domain.Soccer,
+			},
+			expected: &expectedTestCase{
+				err:       &UnexpectedLinesError{SportType: domain.Soccer, Keys: []string{"FOOTBALL"}},
+				sportLine: nil,
+			},
+		},
+		{
+			name: "invalid score value",
+			input: &inputTestCase{
+				doFunc: func(req *http.Request) (*http.Response, error) {
+					return &http.Response{
+						StatusCode: http.StatusOK,
+						Body:       io.NopCloser(strings.NewReader("{\"lines\":{\"SOCCER\":\"high\"}}")),
+					}, nil
+				},
+				sportType: domain.Soccer,
+			},
 			expected: &expectedTestCase{
 				err:       appErr.ErrInternal,
 				sportLine: nil,
@@ -201,7 +235,7 @@ func TestGetLines(t *testing.T) {
 				err: nil,
 				sportLine: &domain.SportLine{
 					Type:  domain.Baseball,
-					Score: 0.774,
+					Score: decimal.RequireFromString("0.774"),
 				},
 			},
 		},
@@ -220,7 +254,71 @@ func TestGetLines(t *testing.T) {
 				err: nil,
 				sportLine: &domain.SportLine{
 					Type:  domain.Football,
-					Score: 0.774,
+					Score: decimal.RequireFromString("0.774"),
+				},
+			},
+		},
+		{
+			name: "valid soccer with markets",
+			input: &inputTestCase{
+				doFunc: func(req *http.Request) (*http.Response, error) {
+					body := `{"lines":{"SOCCER":"1.5"},"markets":{"SOCCER":[
+						{"type":"moneyline","selections":[{"name":"home","price":"1.85"},{"name":"away","price":"2.1"}]},
+						{"type":"corners","selections":[{"name":"over","price":"1.9"}]},
+						{"type":"totals","point":"2.5","selections":[{"name":"over","price":1.95},{"name":"under","price":"1.9"}]}
+					]}}`
+					return &http.Response{
+						StatusCode: http.StatusOK,
+						Body:       io.NopCloser(strings.NewReader(body)),
+					}, nil
+				},
+				sportType: domain.Soccer,
+			},
+			expected: &expectedTestCase{
+				err: nil,
+				sportLine: &domain.SportLine{
+					Type:  domain.Soccer,
+					Score: decimal.RequireFromString("1.5"),
+					Markets: []*domain.Market{
+						{Type: domain.Moneyline, Selections: []*domain.Selection{{Name: "home", Price: decimal.RequireFromString("1.85")}, {Name: "away", Price: decimal.RequireFromString("2.1")}}},
+						{Type: domain.Totals, Point: decimal.RequireFromString("2.5"), Selections: []*domain.Selection{{Name: "over", Price: decimal.RequireFromString("1.95")}, {Name: "under", Price: decimal.RequireFromString("1.9")}}},
+					},
+				},
+			},
+		},
+		{
+			name: "markets for another sport",
+			input: &inputTestCase{
+				doFunc: func(req *http.Request) (*http.Response, error) {
+					body := `{"lines":{"SOCCER":"1.5"},"markets":{"FOOTBALL":[]}}`
+					return &http.Response{
+						StatusCode: http.StatusOK,
+						Body:       io.NopCloser(strings.NewReader(body)),
+					}, nil
+				},
+				sportType: domain.Soccer,
+			},
+			expected: &expectedTestCase{
+				err:       &UnexpectedLinesError{SportType: domain.Soccer, Keys: []string{"FOOTBALL"}},
+				sportLine: nil,
+			},
+		},
+		{
+			name: "valid sport registered at runtime in lower case",
+			input: &inputTestCase{
+				doFunc: func(req *http.Request) (*http.Response, error) {
+					return &http.Response{
+						StatusCode: http.StatusOK,
+						Body:       io.NopCloser(strings.NewReader("{\"lines\":{\"hockey\":1.25}}")),
+					}, nil
+				},
+				sportType: "hockey",
+			},
+			expected: &expectedTestCase{
+				err: nil,
+				sportLine: &domain.SportLine{
+					Type:  "hockey",
+					Score: decimal.RequireFromString("1.25"),
 				},
 			},
 		},
@@ -238,7 +336,89 @@ func TestGetLines(t *testing.T) {
 			} else {
 				assert.Equal(t, expected.sportLine.Type, line.Type)
 				assert.Equal(t, expected.sportLine.Score, line.Score)
+				assert.Equal(t, expected.sportLine.Markets, line.Markets)
 			}
 		})
 	}
 }
+
+func TestDecodeSportLineReportsUnknownMarkets(t *testing.T) {
+	body := `{"lines":{"SOCCER":"1.5"},"markets":{"SOCCER":[
+		{"type":"moneyline","selections":[{"name":"home","price":"1.85"}]},
+		{"type":"corners","selections":[{"name":"over","price":"1.9"}]},
+		{"type":"cards","selections":[{"name":"over","price":"2.2"}]}
+	]}}`
+	line, unknownMarkets, err := decodeSportLine([]byte(body), domain.Soccer)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"cards", "corners"}, unknownMarkets)
+	assert.Equal(t, 1, len(line.Markets))
+	assert.Equal(t, "lines response for soccer contains unknown market types: cards, corners",
+		(&UnknownMarketsError{SportType: domain.Soccer, Types: unknownMarkets}).Error())
+}
+
+func TestGetEvents(t *testing.T) {
+	startTime := time.Date(2022, 5, 1, 18, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		doFunc   func(req *http.Request) (*http.Response, error)
+		err      error
+		expected []*domain.Event
+	}{
+		{
+			name: "response http status != 200",
+			doFunc: func(req *http.Request) (*http.Response, error) {
+				return &http.Response{StatusCode: http.StatusNotFound}, nil
+			},
+			err:      appErr.ErrExternal,
+			expected: nil,
+		},
+		{
+			name: "invalid event status",
+			doFunc: func(req *http.Request) (*http.Response, error) {
+				body := `{"events":[{"id":"soccer-1","homeTeam":"Lions","awayTeam":"Tigers","startTime":"2022-05-01T18:00:00Z","status":"postponed","line":"1.5"}]}`
+				return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body))}, nil
+			},
+			err:      appErr.ErrInternal,
+			expected: nil,
+		},
+		{
+			name: "event without id",
+			doFunc: func(req *http.Request) (*http.Response, error) {
+				body := `{"events":[{"homeTeam":"Lions","awayTeam":"Tigers","startTime":"2022-05-01T18:00:00Z","status":"live","line":"1.5"}]}`
+				return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body))}, nil
+			},
+			err:      appErr.ErrInternal,
+			expected: nil,
+		},
+		{
+			name: "valid events",
+			doFunc: func(req *http.Request) (*http.Response, error) {
+				if req.URL.Path != "/api/v1/lines/soccer/events" {
+					return &http.Response{StatusCode: http.StatusNotFound}, nil
+				}
+				body := `{"events":[{"id":"soccer-1","homeTeam":"Lions","awayTeam":"Tigers","startTime":"2022-05-01T18:00:00Z","status":"live","line":"1.5"}]}`
+				return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body))}, nil
+			},
+			err: nil,
+			expected: []*domain.Event{{
+				ID:        "soccer-1",
+				SportType: domain.Soccer,
+				HomeTeam:  "Lions",
+				AwayTeam:  "Tigers",
+				StartTime: startTime,
+				Status:    domain.EventLive,
+				Line:      decimal.RequireFromString("1.5"),
+			}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			http2.Client = &MockClient{DoFunc: test.doFunc}
+			adapter := NewLinesProviderAdapter("http://localhost:8000", fake.Logger{})
+			events, err := adapter.GetEventsBySport(domain.Soccer)
+			assert.Equal(t, test.err, err)
+			assert.Equal(t, test.expected, events)
+		})
+	}
+}
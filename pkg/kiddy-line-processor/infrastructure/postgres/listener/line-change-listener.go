@@ -0,0 +1,117 @@
+package listener
+
+import (
+	"context"
+	"github.com/col3name/lines/pkg/common/application/logger"
+	commonDomain "github.com/col3name/lines/pkg/common/domain"
+	"github.com/col3name/lines/pkg/common/infrastructure/postgres"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/application/service"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/domain/model"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/infrastructure/postgres/repo"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"time"
+)
+
+const defaultReconnectDelay = time.Second
+
+type NotificationConn interface {
+	Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error)
+	WaitForNotification(ctx context.Context) (*pgconn.Notification, error)
+	Release()
+}
+
+type Connector func(ctx context.Context) (NotificationConn, error)
+
+type LineChangeListener struct {
+	connect        Connector
+	reconnectDelay time.Duration
+	publisher      service.LineChangePublisher
+	sportRegistry  commonDomain.SportRegistry
+	logger         logger.Logger
+}
+
+func NewLineChangeListener(
+	connect Connector,
+	publisher service.LineChangePublisher,
+	sportRegistry commonDomain.SportRegistry,
+	logger logger.Logger,
+) *LineChangeListener {
+	return &LineChangeListener{
+		connect:        connect,
+		reconnectDelay: defaultReconnectDelay,
+		publisher:      publisher,
+		sportRegistry:  sportRegistry,
+		logger:         logger,
+	}
+}
+
+func PoolConnector(pool postgres.PgxPoolIface) Connector {
+	return func(ctx context.Context) (NotificationConn, error) {
+		conn, err := pool.Acquire(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return &poolConn{Conn: conn}, nil
+	}
+}
+
+type poolConn struct {
+	*pgxpool.Conn
+}
+
+func (c *poolConn) WaitForNotification(ctx context.Context) (*pgconn.Notification, error) {
+	return c.Conn.Conn().WaitForNotification(ctx)
+}
+
+func (l *LineChangeListener) Run(ctx context.Context) {
+	for ctx.Err() == nil {
+		if err := l.listen(ctx); err != nil && ctx.Err() == nil {
+			l.logger.Error("line change listener: ", err)
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(l.reconnectDelay):
+		}
+	}
+}
+
+func (l *LineChangeListener) listen(ctx context.Context) error {
+	conn, err := l.connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	if _, err = conn.Exec(ctx, "LISTEN "+repo.LineChangesChannel+";"); err != nil {
+		return err
+	}
+	l.resync()
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		l.handle(notification)
+	}
+}
+
+func (l *LineChangeListener) resync() {
+	for _, sportType := range l.sportRegistry.Sports() {
+		l.publisher.Publish(sportType, model.LineChanged)
+		l.publisher.Publish(sportType, model.EventsChanged)
+	}
+}
+
+func (l *LineChangeListener) handle(notification *pgconn.Notification) {
+	if notification.Channel != repo.LineChangesChannel {
+		return
+	}
+	sportType, kind, err := model.ParseChangeNotification(notification.Payload)
+	if err != nil {
+		l.logger.Error("line change listener: ", err, " payload: ", notification.Payload)
+		return
+	}
+	l.publisher.Publish(sportType, kind)
+}
@@ -0,0 +1,124 @@
+package listener
+
+import (
+	"context"
+	"errors"
+	"github.com/col3name/lines/pkg/common/domain"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/application/fake"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/domain/model"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/infrastructure/postgres/repo"
+	"github.com/jackc/pgconn"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeConn struct {
+	notifications chan *pgconn.Notification
+	executed      []string
+	released      bool
+}
+
+func (c *fakeConn) Exec(_ context.Context, sql string, _ ...interface{}) (pgconn.CommandTag, error) {
+	c.executed = append(c.executed, sql)
+	return pgconn.CommandTag("LISTEN"), nil
+}
+
+func (c *fakeConn) WaitForNotification(ctx context.Context) (*pgconn.Notification, error) {
+	select {
+	case notification, ok := <-c.notifications:
+		if !ok {
+			return nil, errors.New("connection closed")
+		}
+		return notification, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (c *fakeConn) Release() {
+	c.released = true
+}
+
+type fakeRegistry struct {
+	sports []domain.SportType
+}
+
+func (r *fakeRegistry) IsSupported(sportType domain.SportType) bool {
+	return true
+}
+
+func (r *fakeRegistry) Sports() []domain.SportType {
+	return r.sports
+}
+
+type recordingPublisher struct {
+	mu        sync.Mutex
+	published []string
+}
+
+func (p *recordingPublisher) Publish(sportType domain.SportType, kind model.ChangeKind) {
+	p.mu.Lock()
+	p.published = append(p.published, model.ChangeNotification(sportType, kind))
+	p.mu.Unlock()
+}
+
+func (p *recordingPublisher) snapshot() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.published...)
+}
+
+func TestLineChangeListener(t *testing.T) {
+	first := &fakeConn{notifications: make(chan *pgconn.Notification, 5)}
+	first.notifications <- &pgconn.Notification{Channel: repo.LineChangesChannel, Payload: "soccer"}
+	first.notifications <- &pgconn.Notification{Channel: repo.LineChangesChannel, Payload: "events:soccer"}
+	first.notifications <- &pgconn.Notification{Channel: repo.LineChangesChannel, Payload: "odds:soccer"}
+	first.notifications <- &pgconn.Notification{Channel: repo.LineChangesChannel, Payload: "not a sport!"}
+	first.notifications <- &pgconn.Notification{Channel: "other", Payload: "football"}
+	close(first.notifications)
+	second := &fakeConn{notifications: make(chan *pgconn.Notification, 1)}
+	second.notifications <- &pgconn.Notification{Channel: repo.LineChangesChannel, Payload: "line:football"}
+
+	var mu sync.Mutex
+	attempts := 0
+	connect := func(ctx context.Context) (NotificationConn, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts++
+		switch attempts {
+		case 1:
+			return nil, errors.New("connection refused")
+		case 2:
+			return first, nil
+		default:
+			return second, nil
+		}
+	}
+	publisher := &recordingPublisher{}
+	listener := NewLineChangeListener(connect, publisher, &fakeRegistry{sports: []domain.SportType{domain.Baseball}}, fake.Logger{})
+	listener.reconnectDelay = time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		listener.Run(ctx)
+		close(done)
+	}()
+
+	expected := []string{
+		"line:baseball", "events:baseball", "line:soccer", "events:soccer",
+		"line:baseball", "events:baseball", "line:football",
+	}
+	assert.Eventually(t, func() bool {
+		return len(publisher.snapshot()) == len(expected)
+	}, time.Second, time.Millisecond)
+	cancel()
+	<-done
+
+	assert.Equal(t, expected, publisher.snapshot())
+	assert.Equal(t, []string{"LISTEN " + repo.LineChangesChannel + ";"}, first.executed)
+	assert.True(t, first.released)
+	assert.True(t, second.released)
+}
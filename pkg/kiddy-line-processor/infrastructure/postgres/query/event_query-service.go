@@ -0,0 +1,68 @@
+package query
+
+import (
+	"context"
+	appErr "github.com/col3name/lines/pkg/common/application/errors"
+	"github.com/col3name/lines/pkg/common/application/logger"
+	"github.com/col3name/lines/pkg/common/domain"
+	"github.com/col3name/lines/pkg/common/infrastructure"
+	"github.com/col3name/lines/pkg/common/infrastructure/postgres"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/domain/query"
+	"github.com/jackc/pgx/v4"
+	"strings"
+)
+
+const selectEventsSql = "SELECT id, sport_type, home_team, away_team, start_time, status, line FROM events "
+
+type eventQueryService struct {
+	conn   postgres.PgxPoolIface
+	logger logger.Logger
+}
+
+func NewEventQueryService(conn postgres.PgxPoolIface, logger logger.Logger) query.EventQueryService {
+	return &eventQueryService{conn: conn, logger: logger}
+}
+
+func (s *eventQueryService) GetEventsBySportType(sportType domain.SportType) ([]*domain.Event, error) {
+	const sql = selectEventsSql + "WHERE sport_type = $1 ORDER BY start_time, id;"
+
+	return s.query(sql, sportType)
+}
+
+func (s *eventQueryService) GetEventsByIDs(ids []string) ([]*domain.Event, error) {
+	const sql = selectEventsSql + "WHERE id = ANY ($1) ORDER BY start_time, id;"
+
+	if len(ids) < 1 {
+		return nil, appErr.ErrInvalidArgument
+	}
+	return s.query(sql, ids)
+}
+
+func (s *eventQueryService) query(sql string, args ...interface{}) ([]*domain.Event, error) {
+	rows, err := s.conn.Query(context.Background(), sql, args...)
+	if err != nil {
+		if strings.Contains(err.Error(), appErr.TableNotExistMessage) {
+			return nil, appErr.ErrTableNotExist
+		}
+		return nil, infrastructure.InternalError(s.logger, err)
+	}
+	defer rows.Close()
+
+	return s.scanEvents(rows)
+}
+
+func (s *eventQueryService) scanEvents(rows pgx.Rows) ([]*domain.Event, error) {
+	events := make([]*domain.Event, 0)
+	for rows.Next() {
+		var event domain.Event
+		err := rows.Scan(&event.ID, &event.SportType, &event.HomeTeam, &event.AwayTeam, &event.StartTime, &event.Status, &event.Line)
+		if err != nil {
+			return nil, infrastructure.InternalError(s.logger, err)
+		}
+		events = append(events, &event)
+	}
+	if rows.Err() != nil {
+		return nil, infrastructure.InternalError(s.logger, rows.Err())
+	}
+	return events, nil
+}
@@ -0,0 +1,96 @@
+package query
+
+import (
+	"errors"
+	appErr "github.com/col3name/lines/pkg/common/application/errors"
+	"github.com/col3name/lines/pkg/common/domain"
+	"github.com/col3name/lines/pkg/common/infrastructure/postgres"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/application/fake"
+	"github.com/pashagolub/pgxmock"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+var eventColumns = []string{"id", "sport_type", "home_team", "away_team", "start_time", "status", "line"}
+
+func TestGetEventsByIDs(t *testing.T) {
+	const sql = "SELECT id, sport_type, home_team, away_team, start_time, status, line FROM events WHERE id = ANY"
+	startTime := time.Date(2022, 5, 1, 18, 0, 0, 0, time.UTC)
+	event := &domain.Event{
+		ID:        "soccer-1",
+		SportType: domain.Soccer,
+		HomeTeam:  "Lions",
+		AwayTeam:  "Tigers",
+		StartTime: startTime,
+		Status:    domain.EventLive,
+		Line:      decimal.RequireFromString("1.5"),
+	}
+
+	tests := []struct {
+		name     string
+		ids      []string
+		status   status
+		expected []*domain.Event
+		err      error
+	}{
+		{name: "empty id list", ids: nil, status: skip, err: appErr.ErrInvalidArgument},
+		{name: "table not exist", ids: []string{"soccer-1"}, status: tableNotExist, err: appErr.ErrTableNotExist},
+		{name: "failed query", ids: []string{"soccer-1"}, status: failedQuery, err: appErr.ErrInternal},
+		{name: "failed row scan", ids: []string{"soccer-1"}, status: failedRowScan, err: appErr.ErrInternal},
+		{name: "success", ids: []string{"soccer-1"}, status: ok, expected: []*domain.Event{event}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mock, err := postgres.GetPgxMockPool(t)
+			if err != nil {
+				return
+			}
+			defer mock.Close()
+
+			switch test.status {
+			case tableNotExist:
+				mock.ExpectQuery(sql).WithArgs(test.ids).WillReturnError(errors.New(appErr.TableNotExistMessage))
+			case failedQuery:
+				mock.ExpectQuery(sql).WithArgs(test.ids).WillReturnError(appErr.ErrInternal)
+			case failedRowScan:
+				mock.ExpectQuery(sql).WithArgs(test.ids).WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow("soccer-1"))
+			case ok:
+				rows := pgxmock.NewRows(eventColumns).
+					AddRow(event.ID, event.SportType, event.HomeTeam, event.AwayTeam, event.StartTime, event.Status, event.Line)
+				mock.ExpectQuery(sql).WithArgs(test.ids).WillReturnRows(rows)
+			}
+
+			queryService := NewEventQueryService(mock, fake.Logger{})
+			events, err := queryService.GetEventsByIDs(test.ids)
+			assert.Equal(t, test.err, err)
+			assert.Equal(t, test.expected, events)
+			postgres.CheckExpectationsWereMet(t, mock)
+		})
+	}
+}
+
+func TestGetEventsBySportType(t *testing.T) {
+	const sql = "SELECT id, sport_type, home_team, away_team, start_time, status, line FROM events WHERE sport_type"
+	startTime := time.Date(2022, 5, 1, 18, 0, 0, 0, time.UTC)
+
+	mock, err := postgres.GetPgxMockPool(t)
+	if err != nil {
+		return
+	}
+	defer mock.Close()
+
+	rows := pgxmock.NewRows(eventColumns).
+		AddRow("football-1", domain.Football, "Lions", "Tigers", startTime, domain.EventFinished, decimal.RequireFromString("1.1")).
+		AddRow("football-2", domain.Football, "Bears", "Wolves", startTime.Add(time.Hour), domain.EventScheduled, decimal.RequireFromString("2.2"))
+	mock.ExpectQuery(sql).WithArgs(domain.Football).WillReturnRows(rows)
+
+	queryService := NewEventQueryService(mock, fake.Logger{})
+	events, err := queryService.GetEventsBySportType(domain.Football)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(events))
+	assert.Equal(t, "football-1", events[0].ID)
+	assert.Equal(t, domain.EventScheduled, events[1].Status)
+	postgres.CheckExpectationsWereMet(t, mock)
+}
@@ -2,15 +2,19 @@ package query
 
 import (
 	"context"
+	"encoding/base64"
 	"fmt"
 	appErr "github.com/col3name/lines/pkg/common/application/errors"
 	"github.com/col3name/lines/pkg/common/application/logger"
 	"github.com/col3name/lines/pkg/common/domain"
 	"github.com/col3name/lines/pkg/common/infrastructure"
 	"github.com/col3name/lines/pkg/common/infrastructure/postgres"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/domain/model"
 	"github.com/col3name/lines/pkg/kiddy-line-processor/domain/query"
 	"github.com/jackc/pgx/v4"
+	"strconv"
 	"strings"
+	"time"
 )
 
 type SportLineQueryServiceImpl struct {
@@ -30,10 +34,7 @@ func (r *SportLineQueryServiceImpl) GetLinesBySportTypes(sportTypes []domain.Spo
 	sql, data := r.getSqlQueryAndData(sportTypes, countSportTypes)
 	rows, err := r.conn.Query(context.Background(), sql, data...)
 	if err != nil {
-		if r.isTableNotExistError(err) {
-			return nil, appErr.ErrTableNotExist
-		}
-		return nil, infrastructure.InternalError(r.logger, err)
+		return nil, r.queryError(err)
 	}
 	if rows.Err() != nil {
 		return nil, err
@@ -68,10 +69,10 @@ func (r *SportLineQueryServiceImpl) isTableNotExistError(err error) bool {
 }
 
 func (r *SportLineQueryServiceImpl) scanSportLines(rows pgx.Rows) ([]*domain.SportLine, error) {
-	var sport domain.SportLine
 	var sports []*domain.SportLine
 	var err error
 	for rows.Next() {
+		var sport domain.SportLine
 		err = rows.Scan(&sport.Score, &sport.Type)
 		if err != nil {
 			return sports, infrastructure.InternalError(r.logger, err)
@@ -80,3 +81,175 @@ func (r *SportLineQueryServiceImpl) scanSportLines(rows pgx.Rows) ([]*domain.Spo
 	}
 	return sports, nil
 }
+
+func (r *SportLineQueryServiceImpl) GetMarketsBySportTypes(sportTypes []domain.SportType, marketTypes []domain.MarketType) (map[domain.SportType][]*domain.Market, error) {
+	const sql = `SELECT sport_type, market, point, selection, price FROM sport_line_markets
+				WHERE sport_type = ANY ($1) AND market = ANY ($2)
+				ORDER BY sport_type, market, selection;`
+
+	if len(sportTypes) < 1 || len(marketTypes) < 1 {
+		return nil, appErr.ErrInvalidArgument
+	}
+	types := make([]string, 0, len(sportTypes))
+	for _, sportType := range sportTypes {
+		types = append(types, sportType.String())
+	}
+	markets := make([]string, 0, len(marketTypes))
+	for _, marketType := range marketTypes {
+		markets = append(markets, marketType.String())
+	}
+	rows, err := r.conn.Query(context.Background(), sql, types, markets)
+	if err != nil {
+		return nil, r.queryError(err)
+	}
+	defer rows.Close()
+
+	return r.scanMarkets(rows)
+}
+
+func (r *SportLineQueryServiceImpl) scanMarkets(rows pgx.Rows) (map[domain.SportType][]*domain.Market, error) {
+	result := make(map[domain.SportType][]*domain.Market)
+	for rows.Next() {
+		var (
+			sportType domain.SportType
+			market    domain.Market
+			selection domain.Selection
+		)
+		err := rows.Scan(&sportType, &market.Type, &market.Point, &selection.Name, &selection.Price)
+		if err != nil {
+			return nil, infrastructure.InternalError(r.logger, err)
+		}
+		markets := result[sportType]
+		if len(markets) == 0 || markets[len(markets)-1].Type != market.Type {
+			markets = append(markets, &market)
+			result[sportType] = markets
+		}
+		last := markets[len(markets)-1]
+		last.Selections = append(last.Selections, &selection)
+	}
+	if rows.Err() != nil {
+		return nil, infrastructure.InternalError(r.logger, rows.Err())
+	}
+	return result, nil
+}
+
+func (r *SportLineQueryServiceImpl) GetLinesAsOf(sportTypes []domain.SportType, asOf time.Time) ([]*domain.SportLine, error) {
+	const sql = `SELECT DISTINCT ON (sport_type) score, sport_type FROM sport_line_history
+				WHERE sport_type = ANY ($1) AND fetched_at <= $2
+				ORDER BY sport_type, fetched_at DESC, id DESC;`
+
+	if len(sportTypes) < 1 {
+		return nil, appErr.ErrInvalidArgument
+	}
+	types := make([]string, 0, len(sportTypes))
+	for _, sportType := range sportTypes {
+		types = append(types, sportType.String())
+	}
+	rows, err := r.conn.Query(context.Background(), sql, types, asOf)
+	if err != nil {
+		return nil, r.queryError(err)
+	}
+	defer rows.Close()
+
+	return r.scanSportLines(rows)
+}
+
+func (r *SportLineQueryServiceImpl) GetLineHistory(sportType domain.SportType, from, to time.Time, limit int, cursor string) (*model.SportLineHistoryPage, error) {
+	const sql = `SELECT id, score, sport_type, fetched_at, ingested_at FROM sport_line_history
+				WHERE sport_type = $1 AND fetched_at >= $2 AND fetched_at < $3 AND id > $4
+				ORDER BY id LIMIT $5;`
+
+	if limit < 1 || limit > query.MaxHistoryPageSize || !from.Before(to) {
+		return nil, appErr.ErrInvalidArgument
+	}
+	afterID, err := decodeHistoryCursor(cursor)
+	if err != nil {
+		return nil, appErr.ErrInvalidArgument
+	}
+	rows, err := r.conn.Query(context.Background(), sql, sportType, from, to, afterID, limit+1)
+	if err != nil {
+		return nil, r.queryError(err)
+	}
+	defer rows.Close()
+
+	records, err := r.scanHistoryRecords(rows)
+	if err != nil {
+		return nil, err
+	}
+	page := &model.SportLineHistoryPage{Records: records}
+	if len(records) > limit {
+		page.Records = records[:limit]
+		page.NextCursor = encodeHistoryCursor(page.Records[limit-1].ID)
+	}
+	return page, nil
+}
+
+func (r *SportLineQueryServiceImpl) scanHistoryRecords(rows pgx.Rows) ([]*model.SportLineHistoryRecord, error) {
+	records := make([]*model.SportLineHistoryRecord, 0)
+	for rows.Next() {
+		var record model.SportLineHistoryRecord
+		err := rows.Scan(&record.ID, &record.Line.Score, &record.Line.Type, &record.FetchedAt, &record.IngestedAt)
+		if err != nil {
+			return nil, infrastructure.InternalError(r.logger, err)
+		}
+		records = append(records, &record)
+	}
+	if rows.Err() != nil {
+		return nil, infrastructure.InternalError(r.logger, rows.Err())
+	}
+	return records, nil
+}
+
+func (r *SportLineQueryServiceImpl) GetCandles(sportType domain.SportType, bucket time.Duration, from, to time.Time, limit int) ([]*model.Candle, error) {
+	const sql = `SELECT to_timestamp(floor(extract(epoch FROM fetched_at) / $2) * $2) AS open_time,
+				(array_agg(score ORDER BY fetched_at, id))[1], max(score), min(score),
+				(array_agg(score ORDER BY fetched_at DESC, id DESC))[1], count(*)
+				FROM sport_line_history
+				WHERE sport_type = $1 AND fetched_at >= $3 AND fetched_at < $4
+				GROUP BY open_time ORDER BY open_time LIMIT $5;`
+
+	if bucket < time.Second || limit < 1 || limit > query.MaxCandlePageSize || !from.Before(to) {
+		return nil, appErr.ErrInvalidArgument
+	}
+	rows, err := r.conn.Query(context.Background(), sql, sportType, int64(bucket/time.Second), from, to, limit)
+	if err != nil {
+		return nil, r.queryError(err)
+	}
+	defer rows.Close()
+
+	candles := make([]*model.Candle, 0)
+	for rows.Next() {
+		candle := model.Candle{SportType: sportType}
+		err = rows.Scan(&candle.OpenTime, &candle.Open, &candle.High, &candle.Low, &candle.Close, &candle.Count)
+		if err != nil {
+			return nil, infrastructure.InternalError(r.logger, err)
+		}
+		candles = append(candles, &candle)
+	}
+	if rows.Err() != nil {
+		return nil, infrastructure.InternalError(r.logger, rows.Err())
+	}
+	return candles, nil
+}
+
+func (r *SportLineQueryServiceImpl) queryError(err error) error {
+	if r.isTableNotExistError(err) {
+		return appErr.ErrTableNotExist
+	}
+	return infrastructure.InternalError(r.logger, err)
+}
+
+func encodeHistoryCursor(id int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(id, 10)))
+}
+
+func decodeHistoryCursor(cursor string) (int64, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+	bytes, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(string(bytes), 10, 64)
+}
@@ -6,9 +6,13 @@ import (
 	"github.com/col3name/lines/pkg/common/domain"
 	"github.com/col3name/lines/pkg/common/infrastructure/postgres"
 	"github.com/col3name/lines/pkg/kiddy-line-processor/application/fake"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/domain/model"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/domain/query"
 	"github.com/pashagolub/pgxmock"
+	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
 	"testing"
+	"time"
 )
 
 type status int
@@ -118,7 +122,7 @@ func TestGetSportLines(t *testing.T) {
 			},
 			expected: &expectedGetLineBySport{
 				lines: []*domain.SportLine{
-					{Type: domain.Baseball, Score: 0.744},
+					{Type: domain.Baseball, Score: decimal.RequireFromString("0.744")},
 				},
 				err: nil,
 			},
@@ -169,7 +173,7 @@ func setupGetSportLinesUseCases(mock pgxmock.PgxPoolIface, input *inputGetLineBy
 	case rowsError:
 		r := pgxmock.NewRows([]string{"exists"}).AddRow(&domain.SportLine{
 			Type:  domain.Baseball,
-			Score: 0.744,
+			Score: decimal.RequireFromString("0.744"),
 		})
 		r.RowError(0, errors.ErrInternal)
 		mock.ExpectQuery("SELECT score,sport_type FROM sport_lines").
@@ -219,3 +223,444 @@ func compareSportLines(t *testing.T, expected *domain.SportLine, actual *domain.
 	assert.Equal(t, expected.Type, actual.Type)
 	assert.Equal(t, expected.Score, actual.Score)
 }
+
+type inputGetLinesAsOf struct {
+	sportTypes []domain.SportType
+	asOf       time.Time
+	status     status
+	queryErr   error
+}
+
+func TestGetLinesAsOf(t *testing.T) {
+	asOf := time.Date(2022, 9, 1, 14, 3, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		input    *inputGetLinesAsOf
+		expected *expectedGetLineBySport
+	}{
+		{
+			name:     "empty sport types list",
+			input:    &inputGetLinesAsOf{sportTypes: []domain.SportType{}, asOf: asOf, status: skip},
+			expected: &expectedGetLineBySport{lines: nil, err: errors.ErrInvalidArgument},
+		},
+		{
+			name: "table not exist error",
+			input: &inputGetLinesAsOf{
+				sportTypes: []domain.SportType{domain.Soccer},
+				asOf:       asOf,
+				status:     tableNotExist,
+				queryErr:   errBase.New("ERROR: relation \"sport_line_history\" does not exist (SQLSTATE 42P01)"),
+			},
+			expected: &expectedGetLineBySport{lines: nil, err: errors.ErrTableNotExist},
+		},
+		{
+			name: "failed query",
+			input: &inputGetLinesAsOf{
+				sportTypes: []domain.SportType{domain.Soccer},
+				asOf:       asOf,
+				status:     failedQuery,
+				queryErr:   errors.ErrInternal,
+			},
+			expected: &expectedGetLineBySport{lines: nil, err: errors.ErrInternal},
+		},
+		{
+			name: "success get lines as of time",
+			input: &inputGetLinesAsOf{
+				sportTypes: []domain.SportType{domain.Baseball, domain.Soccer},
+				asOf:       asOf,
+				status:     ok,
+			},
+			expected: &expectedGetLineBySport{
+				lines: []*domain.SportLine{
+					{Type: domain.Baseball, Score: decimal.RequireFromString("0.744")},
+					{Type: domain.Soccer, Score: decimal.RequireFromString("1.5")},
+				},
+				err: nil,
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mock, err := postgres.GetPgxMockPool(t)
+			if err != nil {
+				return
+			}
+			defer mock.Close()
+
+			input := test.input
+			expected := test.expected
+			setupGetLinesAsOfUseCases(mock, input, expected)
+
+			repo := NewSportLineQueryService(mock, fake.Logger{})
+			lines, err := repo.GetLinesAsOf(input.sportTypes, input.asOf)
+
+			compareLines(t, expected, err, lines)
+			postgres.CheckExpectationsWereMet(t, mock)
+		})
+	}
+}
+
+func setupGetLinesAsOfUseCases(mock pgxmock.PgxPoolIface, input *inputGetLinesAsOf, expected *expectedGetLineBySport) {
+	const sql = "SELECT DISTINCT ON \\(sport_type\\) score, sport_type FROM sport_line_history"
+	var types []string
+	for _, sportType := range input.sportTypes {
+		types = append(types, sportType.String())
+	}
+
+	switch input.status {
+	case tableNotExist, failedQuery:
+		mock.ExpectQuery(sql).WithArgs(types, input.asOf).WillReturnError(input.queryErr)
+	case ok:
+		rs := pgxmock.NewRows([]string{"score", "type"})
+		for _, line := range expected.lines {
+			rs.AddRow(line.Score, line.Type)
+		}
+		mock.ExpectQuery(sql).WithArgs(types, input.asOf).WillReturnRows(rs)
+	}
+}
+
+type inputGetLineHistory struct {
+	sportType domain.SportType
+	from      time.Time
+	to        time.Time
+	limit     int
+	cursor    string
+	status    status
+	queryErr  error
+	rows      []*model.SportLineHistoryRecord
+}
+
+type expectedGetLineHistory struct {
+	afterID int64
+	page    *model.SportLineHistoryPage
+	err     error
+}
+
+func historyRecord(id int64, score decimal.Decimal, fetchedAt time.Time) *model.SportLineHistoryRecord {
+	return &model.SportLineHistoryRecord{
+		ID:         id,
+		Line:       domain.SportLine{Type: domain.Soccer, Score: score},
+		FetchedAt:  fetchedAt,
+		IngestedAt: fetchedAt.Add(time.Millisecond),
+	}
+}
+
+func TestGetLineHistory(t *testing.T) {
+	from := time.Date(2022, 9, 1, 14, 0, 0, 0, time.UTC)
+	to := from.Add(time.Hour)
+	first := historyRecord(7, decimal.RequireFromString("1.5"), from.Add(time.Minute))
+	second := historyRecord(9, decimal.RequireFromString("1.6"), from.Add(2*time.Minute))
+	third := historyRecord(12, decimal.RequireFromString("1.4"), from.Add(3*time.Minute))
+
+	tests := []struct {
+		name     string
+		input    *inputGetLineHistory
+		expected *expectedGetLineHistory
+	}{
+		{
+			name:     "invalid limit",
+			input:    &inputGetLineHistory{sportType: domain.Soccer, from: from, to: to, limit: 0, status: skip},
+			expected: &expectedGetLineHistory{err: errors.ErrInvalidArgument},
+		},
+		{
+			name:     "limit greater than max page size",
+			input:    &inputGetLineHistory{sportType: domain.Soccer, from: from, to: to, limit: query.MaxHistoryPageSize + 1, status: skip},
+			expected: &expectedGetLineHistory{err: errors.ErrInvalidArgument},
+		},
+		{
+			name:     "from after to",
+			input:    &inputGetLineHistory{sportType: domain.Soccer, from: to, to: from, limit: 10, status: skip},
+			expected: &expectedGetLineHistory{err: errors.ErrInvalidArgument},
+		},
+		{
+			name:     "invalid cursor",
+			input:    &inputGetLineHistory{sportType: domain.Soccer, from: from, to: to, limit: 10, cursor: "%%%", status: skip},
+			expected: &expectedGetLineHistory{err: errors.ErrInvalidArgument},
+		},
+		{
+			name: "failed query",
+			input: &inputGetLineHistory{
+				sportType: domain.Soccer, from: from, to: to, limit: 10,
+				status: failedQuery, queryErr: errors.ErrInternal,
+			},
+			expected: &expectedGetLineHistory{err: errors.ErrInternal},
+		},
+		{
+			name: "failed row scan",
+			input: &inputGetLineHistory{
+				sportType: domain.Soccer, from: from, to: to, limit: 10,
+				status: failedRowScan,
+			},
+			expected: &expectedGetLineHistory{err: errors.ErrInternal},
+		},
+		{
+			name: "last page",
+			input: &inputGetLineHistory{
+				sportType: domain.Soccer, from: from, to: to, limit: 10,
+				status: ok, rows: []*model.SportLineHistoryRecord{first, second},
+			},
+			expected: &expectedGetLineHistory{
+				page: &model.SportLineHistoryPage{Records: []*model.SportLineHistoryRecord{first, second}},
+			},
+		},
+		{
+			name: "page with next cursor",
+			input: &inputGetLineHistory{
+				sportType: domain.Soccer, from: from, to: to, limit: 2,
+				status: ok, rows: []*model.SportLineHistoryRecord{first, second, third},
+			},
+			expected: &expectedGetLineHistory{
+				page: &model.SportLineHistoryPage{
+					Records:    []*model.SportLineHistoryRecord{first, second},
+					NextCursor: encodeHistoryCursor(second.ID),
+				},
+			},
+		},
+		{
+			name: "page after cursor",
+			input: &inputGetLineHistory{
+				sportType: domain.Soccer, from: from, to: to, limit: 2, cursor: encodeHistoryCursor(second.ID),
+				status: ok, rows: []*model.SportLineHistoryRecord{third},
+			},
+			expected: &expectedGetLineHistory{
+				afterID: second.ID,
+				page:    &model.SportLineHistoryPage{Records: []*model.SportLineHistoryRecord{third}},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mock, err := postgres.GetPgxMockPool(t)
+			if err != nil {
+				return
+			}
+			defer mock.Close()
+
+			input := test.input
+			expected := test.expected
+			setupGetLineHistoryUseCases(mock, input, expected)
+
+			repo := NewSportLineQueryService(mock, fake.Logger{})
+			page, err := repo.GetLineHistory(input.sportType, input.from, input.to, input.limit, input.cursor)
+
+			assert.Equal(t, expected.err, err)
+			assert.Equal(t, expected.page, page)
+			postgres.CheckExpectationsWereMet(t, mock)
+		})
+	}
+}
+
+func setupGetLineHistoryUseCases(mock pgxmock.PgxPoolIface, input *inputGetLineHistory, expected *expectedGetLineHistory) {
+	const sql = "SELECT id, score, sport_type, fetched_at, ingested_at FROM sport_line_history"
+	args := []interface{}{input.sportType, input.from, input.to, expected.afterID, input.limit + 1}
+
+	switch input.status {
+	case failedQuery:
+		mock.ExpectQuery(sql).WithArgs(args...).WillReturnError(input.queryErr)
+	case failedRowScan:
+		rs := pgxmock.NewRows([]string{"id"}).AddRow("id")
+		mock.ExpectQuery(sql).WithArgs(args...).WillReturnRows(rs)
+	case ok:
+		rs := pgxmock.NewRows([]string{"id", "score", "sport_type", "fetched_at", "ingested_at"})
+		for _, record := range input.rows {
+			rs.AddRow(record.ID, record.Line.Score, record.Line.Type, record.FetchedAt, record.IngestedAt)
+		}
+		mock.ExpectQuery(sql).WithArgs(args...).WillReturnRows(rs)
+	}
+}
+
+type marketRow struct {
+	sportType domain.SportType
+	market    domain.MarketType
+	point     decimal.Decimal
+	selection string
+	price     decimal.Decimal
+}
+
+func TestGetMarketsBySportTypes(t *testing.T) {
+	const sql = "SELECT sport_type, market, point, selection, price FROM sport_line_markets"
+	sportTypes := []domain.SportType{domain.Soccer, domain.Football}
+	marketTypes := []domain.MarketType{domain.Moneyline, domain.Totals}
+
+	tests := []struct {
+		name        string
+		marketTypes []domain.MarketType
+		status      status
+		rows        []marketRow
+		expected    map[domain.SportType][]*domain.Market
+		err         error
+	}{
+		{name: "empty market list", marketTypes: nil, status: skip, err: errors.ErrInvalidArgument},
+		{name: "failed query", marketTypes: marketTypes, status: failedQuery, err: errors.ErrInternal},
+		{name: "failed row scan", marketTypes: marketTypes, status: failedRowScan, err: errors.ErrInternal},
+		{
+			name:        "group selections by sport and market",
+			marketTypes: marketTypes,
+			status:      ok,
+			rows: []marketRow{
+				{domain.Football, domain.Moneyline, decimal.Decimal{}, "away", decimal.RequireFromString("2.1")},
+				{domain.Football, domain.Moneyline, decimal.Decimal{}, "home", decimal.RequireFromString("1.85")},
+				{domain.Soccer, domain.Moneyline, decimal.Decimal{}, "home", decimal.RequireFromString("1.5")},
+				{domain.Soccer, domain.Totals, decimal.RequireFromString("2.5"), "over", decimal.RequireFromString("1.95")},
+				{domain.Soccer, domain.Totals, decimal.RequireFromString("2.5"), "under", decimal.RequireFromString("1.9")},
+			},
+			expected: map[domain.SportType][]*domain.Market{
+				domain.Football: {
+					{Type: domain.Moneyline, Selections: []*domain.Selection{{Name: "away", Price: decimal.RequireFromString("2.1")}, {Name: "home", Price: decimal.RequireFromString("1.85")}}},
+				},
+				domain.Soccer: {
+					{Type: domain.Moneyline, Selections: []*domain.Selection{{Name: "home", Price: decimal.RequireFromString("1.5")}}},
+					{Type: domain.Totals, Point: decimal.RequireFromString("2.5"), Selections: []*domain.Selection{{Name: "over", Price: decimal.RequireFromString("1.95")}, {Name: "under", Price: decimal.RequireFromString("1.9")}}},
+				},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mock, err := postgres.GetPgxMockPool(t)
+			if err != nil {
+				return
+			}
+			defer mock.Close()
+
+			args := []interface{}{[]string{"soccer", "football"}, []string{"moneyline", "totals"}}
+			switch test.status {
+			case failedQuery:
+				mock.ExpectQuery(sql).WithArgs(args...).WillReturnError(errors.ErrInternal)
+			case failedRowScan:
+				mock.ExpectQuery(sql).WithArgs(args...).WillReturnRows(pgxmock.NewRows([]string{"sport_type"}).AddRow("soccer"))
+			case ok:
+				rs := pgxmock.NewRows([]string{"sport_type", "market", "point", "selection", "price"})
+				for _, row := range test.rows {
+					rs.AddRow(row.sportType, row.market, row.point, row.selection, row.price)
+				}
+				mock.ExpectQuery(sql).WithArgs(args...).WillReturnRows(rs)
+			}
+
+			repo := NewSportLineQueryService(mock, fake.Logger{})
+			markets, err := repo.GetMarketsBySportTypes(sportTypes, test.marketTypes)
+			assert.Equal(t, test.err, err)
+			assert.Equal(t, test.expected, markets)
+			postgres.CheckExpectationsWereMet(t, mock)
+		})
+	}
+}
+
+type inputGetCandles struct {
+	bucket   time.Duration
+	from     time.Time
+	to       time.Time
+	limit    int
+	status   status
+	queryErr error
+}
+
+type expectedGetCandles struct {
+	candles []*model.Candle
+	err     error
+}
+
+func TestGetCandles(t *testing.T) {
+	from := time.Date(2022, 9, 1, 14, 0, 0, 0, time.UTC)
+	to := from.Add(time.Hour)
+	candle := &model.Candle{
+		SportType: domain.Soccer,
+		OpenTime:  from,
+		Open:      decimal.RequireFromString("1.5"),
+		High:      decimal.RequireFromString("1.9"),
+		Low:       decimal.RequireFromString("1.2"),
+		Close:     decimal.RequireFromString("1.4"),
+		Count:     4,
+	}
+
+	tests := []struct {
+		name     string
+		input    *inputGetCandles
+		expected *expectedGetCandles
+	}{
+		{
+			name:     "bucket shorter than second",
+			input:    &inputGetCandles{bucket: time.Millisecond, from: from, to: to, limit: 10, status: skip},
+			expected: &expectedGetCandles{err: errors.ErrInvalidArgument},
+		},
+		{
+			name:     "limit greater than max page size",
+			input:    &inputGetCandles{bucket: time.Minute, from: from, to: to, limit: query.MaxCandlePageSize + 1, status: skip},
+			expected: &expectedGetCandles{err: errors.ErrInvalidArgument},
+		},
+		{
+			name:     "from after to",
+			input:    &inputGetCandles{bucket: time.Minute, from: to, to: from, limit: 10, status: skip},
+			expected: &expectedGetCandles{err: errors.ErrInvalidArgument},
+		},
+		{
+			name:     "failed query",
+			input:    &inputGetCandles{bucket: time.Minute, from: from, to: to, limit: 10, status: failedQuery, queryErr: errors.ErrInternal},
+			expected: &expectedGetCandles{err: errors.ErrInternal},
+		},
+		{
+			name:     "failed row scan",
+			input:    &inputGetCandles{bucket: time.Minute, from: from, to: to, limit: 10, status: failedRowScan},
+			expected: &expectedGetCandles{err: errors.ErrInternal},
+		},
+		{
+			name:     "aggregated candles",
+			input:    &inputGetCandles{bucket: time.Minute, from: from, to: to, limit: 10, status: ok},
+			expected: &expectedGetCandles{candles: []*model.Candle{candle}},
+		},
+		{
+			name:  "open and close of backfilled rows follow fetched_at",
+			input: &inputGetCandles{bucket: time.Minute, from: from, to: to, limit: 10, status: ok},
+			expected: &expectedGetCandles{candles: []*model.Candle{{
+				SportType: domain.Soccer,
+				OpenTime:  from,
+				Open:      decimal.RequireFromString("1.2"),
+				High:      decimal.RequireFromString("1.9"),
+				Low:       decimal.RequireFromString("1.2"),
+				Close:     decimal.RequireFromString("1.9"),
+				Count:     3,
+			}}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mock, err := postgres.GetPgxMockPool(t)
+			if err != nil {
+				return
+			}
+			defer mock.Close()
+
+			input := test.input
+			expected := test.expected
+			setupGetCandlesUseCases(mock, input, expected)
+
+			repo := NewSportLineQueryService(mock, fake.Logger{})
+			candles, err := repo.GetCandles(domain.Soccer, input.bucket, input.from, input.to, input.limit)
+
+			assert.Equal(t, expected.err, err)
+			assert.Equal(t, expected.candles, candles)
+			postgres.CheckExpectationsWereMet(t, mock)
+		})
+	}
+}
+
+func setupGetCandlesUseCases(mock pgxmock.PgxPoolIface, input *inputGetCandles, expected *expectedGetCandles) {
+	const sql = "SELECT to_timestamp\\(floor\\(extract\\(epoch FROM fetched_at\\) / \\$2\\) \\* \\$2\\) AS open_time"
+	args := []interface{}{domain.Soccer, int64(input.bucket / time.Second), input.from, input.to, input.limit}
+
+	switch input.status {
+	case failedQuery:
+		mock.ExpectQuery(sql).WithArgs(args...).WillReturnError(input.queryErr)
+	case failedRowScan:
+		rs := pgxmock.NewRows([]string{"open_time"}).AddRow("open_time")
+		mock.ExpectQuery(sql).WithArgs(args...).WillReturnRows(rs)
+	case ok:
+		// Rows can be inserted after rows with a later fetched_at, so open and close must not follow id.
+		const orderedSql = "(?s)" + sql + ".*array_agg\\(score ORDER BY fetched_at, id\\)\\)\\[1\\].*array_agg\\(score ORDER BY fetched_at DESC, id DESC\\)\\)\\[1\\]"
+		rs := pgxmock.NewRows([]string{"open_time", "open", "high", "low", "close", "count"})
+		for _, candle := range expected.candles {
+			rs.AddRow(candle.OpenTime, candle.Open, candle.High, candle.Low, candle.Close, candle.Count)
+		}
+		mock.ExpectQuery(orderedSql).WithArgs(args...).WillReturnRows(rs)
+	}
+}
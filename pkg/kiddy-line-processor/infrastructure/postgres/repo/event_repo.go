@@ -0,0 +1,34 @@
+package repo
+
+import (
+	"context"
+	"github.com/col3name/lines/pkg/common/application/logger"
+	"github.com/col3name/lines/pkg/common/domain"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/domain/repo"
+	"github.com/jackc/pgx/v4"
+)
+
+type eventRepo struct {
+	tx     pgx.Tx
+	logger logger.Logger
+}
+
+func NewEventRepository(tx pgx.Tx, logger logger.Logger) repo.EventRepo {
+	return &eventRepo{tx: tx, logger: logger}
+}
+
+func (r *eventRepo) Store(events []*domain.Event) error {
+	const query = `INSERT INTO events (id, sport_type, home_team, away_team, start_time, status, line)
+				VALUES ($1, $2, $3, $4, $5, $6, $7)
+				ON CONFLICT (id) DO UPDATE SET home_team = excluded.home_team, away_team = excluded.away_team,
+					start_time = excluded.start_time, status = excluded.status, line = excluded.line;`
+
+	for _, event := range events {
+		_, err := r.tx.Exec(context.Background(), query,
+			event.ID, event.SportType, event.HomeTeam, event.AwayTeam, event.StartTime, event.Status, event.Line)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
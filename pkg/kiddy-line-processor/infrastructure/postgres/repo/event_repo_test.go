@@ -0,0 +1,62 @@
+package repo
+
+import (
+	"github.com/col3name/lines/pkg/common/application/errors"
+	"github.com/col3name/lines/pkg/common/domain"
+	"github.com/col3name/lines/pkg/common/infrastructure/postgres"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/application/fake"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/application/service"
+	"github.com/pashagolub/pgxmock"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestStoreEvents(t *testing.T) {
+	startTime := time.Date(2022, 5, 1, 18, 0, 0, 0, time.UTC)
+	events := []*domain.Event{
+		{ID: "soccer-1", SportType: domain.Soccer, HomeTeam: "Lions", AwayTeam: "Tigers", StartTime: startTime, Status: domain.EventLive, Line: decimal.RequireFromString("1.5")},
+		{ID: "soccer-2", SportType: domain.Soccer, HomeTeam: "Bears", AwayTeam: "Wolves", StartTime: startTime, Status: domain.EventScheduled, Line: decimal.RequireFromString("2.5")},
+	}
+	tests := []struct {
+		name     string
+		storeErr error
+		expected error
+	}{
+		{name: "failed store", storeErr: errors.ErrInternal, expected: errors.ErrInternal},
+		{name: "success store", storeErr: nil, expected: nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mock, err := postgres.GetPgxMockPool(t)
+			if err != nil {
+				return
+			}
+			defer mock.Close()
+
+			mock.ExpectBegin()
+			for _, event := range events {
+				exec := mock.ExpectExec("INSERT INTO events").
+					WithArgs(event.ID, event.SportType, event.HomeTeam, event.AwayTeam, event.StartTime, event.Status, event.Line)
+				if test.storeErr != nil {
+					exec.WillReturnError(test.storeErr)
+					break
+				}
+				exec.WillReturnResult(pgxmock.NewResult("INSERT", 1))
+			}
+			if test.storeErr != nil {
+				mock.ExpectRollback()
+			} else {
+				mock.ExpectCommit()
+			}
+
+			uow := NewUnitOfWork(mock, fake.Logger{})
+			err = uow.Execute(func(rp service.RepositoryProvider) error {
+				return rp.EventRepo().Store(events)
+			})
+			assert.Equal(t, test.expected, err)
+			postgres.CheckExpectationsWereMet(t, mock)
+		})
+	}
+}
@@ -2,32 +2,74 @@ package repo
 
 import (
 	"context"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/domain/model"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/domain/repo"
 	"github.com/jackc/pgx/v4"
 )
 
-const CreateSportLinesSql = `BEGIN TRANSACTION;
-				CREATE TABLE sport_lines
+const MigrationLockKey = 4242001
+
+const CreateSchemaMigrationsSql = `CREATE TABLE IF NOT EXISTS schema_migrations
 				(
-					id         UUID PRIMARY KEY UNIQUE NOT NULL,
-					sport_type VARCHAR(255)            NOT NULL,
-					score      REAL                     NOT NULL
-				);
-				
-				INSERT INTO sport_lines (id, sport_type, score)
-				VALUES ('ce267749-dec9-4d39-ad81-8b4cd8c381d2', 'baseball', 1.0),
-					   ('ba9babe8-06d4-450e-8e9a-66b7512b5bd2', 'soccer', 1.0),
-					   ('4b9d52e2-1473-4cdb-bba8-c1c1cac933f5', 'football', 1.0);
-				END ;`
-
-type migration struct {
+					version    INTEGER PRIMARY KEY      NOT NULL,
+					name       VARCHAR(255)             NOT NULL,
+					applied_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+				);`
+
+type migrationRepo struct {
 	tx pgx.Tx
 }
 
-func NewMigrationRepo(tx pgx.Tx) *migration {
-	return &migration{tx: tx}
+func NewMigrationRepo(tx pgx.Tx) repo.MigrationRepo {
+	return &migrationRepo{tx: tx}
+}
+
+func (r *migrationRepo) Lock() error {
+	_, err := r.tx.Exec(context.Background(), "SELECT pg_advisory_xact_lock($1);", MigrationLockKey)
+	return err
 }
 
-func (m *migration) Migrate() error {
-	_, err := m.tx.Exec(context.Background(), CreateSportLinesSql)
+func (r *migrationRepo) CreateSchemaMigrationsTable() error {
+	_, err := r.tx.Exec(context.Background(), CreateSchemaMigrationsSql)
+	return err
+}
+
+func (r *migrationRepo) GetAppliedMigrations() ([]*model.AppliedMigration, error) {
+	const sql = "SELECT version, name, applied_at FROM schema_migrations ORDER BY version;"
+
+	rows, err := r.tx.Query(context.Background(), sql)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	migrations := make([]*model.AppliedMigration, 0)
+	for rows.Next() {
+		var migration model.AppliedMigration
+		if err = rows.Scan(&migration.Version, &migration.Name, &migration.AppliedAt); err != nil {
+			return nil, err
+		}
+		migrations = append(migrations, &migration)
+	}
+	return migrations, rows.Err()
+}
+
+func (r *migrationRepo) Apply(migration *model.Migration) error {
+	const sql = "INSERT INTO schema_migrations (version, name) VALUES ($1, $2);"
+
+	if _, err := r.tx.Exec(context.Background(), migration.Up); err != nil {
+		return err
+	}
+	_, err := r.tx.Exec(context.Background(), sql, migration.Version, migration.Name)
+	return err
+}
+
+func (r *migrationRepo) Revert(migration *model.Migration) error {
+	const sql = "DELETE FROM schema_migrations WHERE version = $1;"
+
+	if _, err := r.tx.Exec(context.Background(), migration.Down); err != nil {
+		return err
+	}
+	_, err := r.tx.Exec(context.Background(), sql, migration.Version)
 	return err
 }
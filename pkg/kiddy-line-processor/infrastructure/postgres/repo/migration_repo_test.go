@@ -0,0 +1,101 @@
+package repo
+
+import (
+	"github.com/col3name/lines/pkg/common/application/errors"
+	"github.com/col3name/lines/pkg/common/infrastructure/postgres"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/application/fake"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/application/service"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/domain/model"
+	"github.com/pashagolub/pgxmock"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestApplyMigration(t *testing.T) {
+	migration := &model.Migration{Version: 2, Name: "create_history", Up: "CREATE TABLE history", Down: "DROP TABLE history"}
+	tests := []struct {
+		name     string
+		upErr    error
+		expected error
+	}{
+		{name: "failed apply up sql", upErr: errors.ErrInternal, expected: errors.ErrInternal},
+		{name: "success apply", upErr: nil, expected: nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mock, err := postgres.GetPgxMockPool(t)
+			if err != nil {
+				return
+			}
+			defer mock.Close()
+
+			mock.ExpectBegin()
+			mock.ExpectExec("SELECT pg_advisory_xact_lock").WithArgs(MigrationLockKey).
+				WillReturnResult(pgxmock.NewResult("SELECT", 1))
+			exec := mock.ExpectExec("CREATE TABLE history")
+			if test.upErr != nil {
+				exec.WillReturnError(test.upErr)
+				mock.ExpectRollback()
+			} else {
+				exec.WillReturnResult(pgxmock.NewResult("CREATE TABLE", 0))
+				mock.ExpectExec("INSERT INTO schema_migrations").WithArgs(migration.Version, migration.Name).
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+				mock.ExpectCommit()
+			}
+
+			uow := NewUnitOfWork(mock, fake.Logger{})
+			err = uow.Execute(func(rp service.RepositoryProvider) error {
+				migrationRepo := rp.MigrationRepo()
+				if err := migrationRepo.Lock(); err != nil {
+					return err
+				}
+				return migrationRepo.Apply(migration)
+			})
+			assert.Equal(t, test.expected, err)
+			postgres.CheckExpectationsWereMet(t, mock)
+		})
+	}
+}
+
+func TestRevertMigration(t *testing.T) {
+	appliedAt := time.Date(2022, 9, 1, 14, 3, 0, 0, time.UTC)
+	migration := &model.Migration{Version: 2, Name: "create_history", Up: "CREATE TABLE history", Down: "DROP TABLE history"}
+
+	mock, err := postgres.GetPgxMockPool(t)
+	if err != nil {
+		return
+	}
+	defer mock.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").
+		WillReturnResult(pgxmock.NewResult("CREATE TABLE", 0))
+	mock.ExpectQuery("SELECT version, name, applied_at FROM schema_migrations").
+		WillReturnRows(pgxmock.NewRows([]string{"version", "name", "applied_at"}).
+			AddRow(1, "create_lines", appliedAt).
+			AddRow(2, "create_history", appliedAt))
+	mock.ExpectExec("DROP TABLE history").WillReturnResult(pgxmock.NewResult("DROP TABLE", 0))
+	mock.ExpectExec("DELETE FROM schema_migrations").WithArgs(migration.Version).
+		WillReturnResult(pgxmock.NewResult("DELETE", 1))
+	mock.ExpectCommit()
+
+	var applied []*model.AppliedMigration
+	uow := NewUnitOfWork(mock, fake.Logger{})
+	err = uow.Execute(func(rp service.RepositoryProvider) error {
+		migrationRepo := rp.MigrationRepo()
+		if err := migrationRepo.CreateSchemaMigrationsTable(); err != nil {
+			return err
+		}
+		if applied, err = migrationRepo.GetAppliedMigrations(); err != nil {
+			return err
+		}
+		return migrationRepo.Revert(migration)
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []*model.AppliedMigration{
+		{Version: 1, Name: "create_lines", AppliedAt: appliedAt},
+		{Version: 2, Name: "create_history", AppliedAt: appliedAt},
+	}, applied)
+	postgres.CheckExpectationsWereMet(t, mock)
+}
@@ -0,0 +1,32 @@
+package repo
+
+import (
+	"context"
+	"github.com/col3name/lines/pkg/common/domain"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/domain/model"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/domain/repo"
+	"github.com/jackc/pgx/v4"
+)
+
+const LineChangesChannel = "sport_line_changes"
+
+type notifyRepo struct {
+	tx pgx.Tx
+}
+
+func NewNotifyRepo(tx pgx.Tx) repo.NotifyRepo {
+	return &notifyRepo{tx: tx}
+}
+
+func (r *notifyRepo) NotifyLineChanged(sportType domain.SportType) error {
+	return r.notify(sportType, model.LineChanged)
+}
+
+func (r *notifyRepo) NotifyEventsChanged(sportType domain.SportType) error {
+	return r.notify(sportType, model.EventsChanged)
+}
+
+func (r *notifyRepo) notify(sportType domain.SportType, kind model.ChangeKind) error {
+	_, err := r.tx.Exec(context.Background(), "SELECT pg_notify($1, $2);", LineChangesChannel, model.ChangeNotification(sportType, kind))
+	return err
+}
@@ -0,0 +1,56 @@
+package repo
+
+import (
+	"github.com/col3name/lines/pkg/common/application/errors"
+	"github.com/col3name/lines/pkg/common/domain"
+	"github.com/col3name/lines/pkg/common/infrastructure/postgres"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/application/fake"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/application/service"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/domain/model"
+	"github.com/pashagolub/pgxmock"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNotifyLineChanged(t *testing.T) {
+	tests := []struct {
+		name      string
+		kind      model.ChangeKind
+		payload   string
+		notifyErr error
+		expected  error
+	}{
+		{name: "failed notify rollbacks", kind: model.LineChanged, payload: "line:soccer", notifyErr: errors.ErrInternal, expected: errors.ErrInternal},
+		{name: "success notify", kind: model.LineChanged, payload: "line:soccer", notifyErr: nil, expected: nil},
+		{name: "success notify events", kind: model.EventsChanged, payload: "events:soccer", notifyErr: nil, expected: nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mock, err := postgres.GetPgxMockPool(t)
+			if err != nil {
+				return
+			}
+			defer mock.Close()
+
+			mock.ExpectBegin()
+			exec := mock.ExpectExec("SELECT pg_notify").WithArgs(LineChangesChannel, test.payload)
+			if test.notifyErr != nil {
+				exec.WillReturnError(test.notifyErr)
+				mock.ExpectRollback()
+			} else {
+				exec.WillReturnResult(pgxmock.NewResult("SELECT", 1))
+				mock.ExpectCommit()
+			}
+
+			uow := NewUnitOfWork(mock, fake.Logger{})
+			err = uow.Execute(func(rp service.RepositoryProvider) error {
+				if test.kind == model.EventsChanged {
+					return rp.NotifyRepo().NotifyEventsChanged(domain.Soccer)
+				}
+				return rp.NotifyRepo().NotifyLineChanged(domain.Soccer)
+			})
+			assert.Equal(t, test.expected, err)
+			postgres.CheckExpectationsWereMet(t, mock)
+		})
+	}
+}
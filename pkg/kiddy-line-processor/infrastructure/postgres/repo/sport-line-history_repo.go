@@ -0,0 +1,27 @@
+package repo
+
+import (
+	"context"
+	"github.com/col3name/lines/pkg/common/application/logger"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/domain/model"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/domain/repo"
+	"github.com/jackc/pgx/v4"
+)
+
+type sportLineHistoryRepo struct {
+	tx     pgx.Tx
+	logger logger.Logger
+}
+
+func NewSportLineHistoryRepository(tx pgx.Tx, logger logger.Logger) repo.SportLineHistoryRepo {
+	return &sportLineHistoryRepo{tx: tx, logger: logger}
+}
+
+func (r *sportLineHistoryRepo) Append(record *model.SportLineHistoryRecord) error {
+	const query = `INSERT INTO sport_line_history (sport_type, score, fetched_at, ingested_at)
+				VALUES ($1, $2, $3, $4);`
+
+	line := record.Line
+	_, err := r.tx.Exec(context.Background(), query, line.Type, line.Score, record.FetchedAt, record.IngestedAt)
+	return err
+}
@@ -0,0 +1,59 @@
+package repo
+
+import (
+	"github.com/col3name/lines/pkg/common/application/errors"
+	"github.com/col3name/lines/pkg/common/domain"
+	"github.com/col3name/lines/pkg/common/infrastructure/postgres"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/application/fake"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/application/service"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/domain/model"
+	"github.com/pashagolub/pgxmock"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestAppendHistory(t *testing.T) {
+	fetchedAt := time.Date(2022, 9, 1, 14, 3, 0, 0, time.UTC)
+	record := &model.SportLineHistoryRecord{
+		Line:       domain.SportLine{Type: domain.Soccer, Score: decimal.RequireFromString("1.5")},
+		FetchedAt:  fetchedAt,
+		IngestedAt: fetchedAt.Add(time.Millisecond),
+	}
+	tests := []struct {
+		name      string
+		appendErr error
+		expected  error
+	}{
+		{name: "failed append", appendErr: errors.ErrInternal, expected: errors.ErrInternal},
+		{name: "success append", appendErr: nil, expected: nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mock, err := postgres.GetPgxMockPool(t)
+			if err != nil {
+				return
+			}
+			defer mock.Close()
+
+			mock.ExpectBegin()
+			exec := mock.ExpectExec("INSERT INTO sport_line_history").
+				WithArgs(record.Line.Type, record.Line.Score, record.FetchedAt, record.IngestedAt)
+			if test.appendErr != nil {
+				exec.WillReturnError(test.appendErr)
+				mock.ExpectRollback()
+			} else {
+				exec.WillReturnResult(pgxmock.NewResult("INSERT", 1))
+				mock.ExpectCommit()
+			}
+
+			uow := NewUnitOfWork(mock, fake.Logger{})
+			err = uow.Execute(func(rp service.RepositoryProvider) error {
+				return rp.SportLineHistoryRepo().Append(record)
+			})
+			assert.Equal(t, test.expected, err)
+			postgres.CheckExpectationsWereMet(t, mock)
+		})
+	}
+}
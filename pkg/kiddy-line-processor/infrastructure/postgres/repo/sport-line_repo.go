@@ -5,6 +5,7 @@ import (
 	"github.com/col3name/lines/pkg/common/application/logger"
 	"github.com/col3name/lines/pkg/common/domain"
 	"github.com/col3name/lines/pkg/kiddy-line-processor/domain/repo"
+	"github.com/gofrs/uuid"
 	"github.com/jackc/pgx/v4"
 )
 
@@ -18,9 +19,14 @@ func NewSportLineRepository(tx pgx.Tx, logger logger.Logger) repo.SportLineRepo
 }
 
 func (r *sportLineRepo) Store(model *domain.SportLine) error {
-	const query = "UPDATE sport_lines SET score = $1 WHERE sport_type = $2;"
+	const query = `INSERT INTO sport_lines (id, sport_type, score) VALUES ($1, $2, $3)
+				ON CONFLICT (sport_type) DO UPDATE SET score = excluded.score;`
 
-	result, err := r.tx.Exec(context.Background(), query, model.Score, model.Type)
+	id, err := uuid.NewV4()
+	if err != nil {
+		return err
+	}
+	result, err := r.tx.Exec(context.Background(), query, id.String(), model.Type, model.Score)
 	if err != nil {
 		return err
 	}
@@ -28,5 +34,25 @@ func (r *sportLineRepo) Store(model *domain.SportLine) error {
 	if rowsAffected == 0 {
 		return domain.ErrSportLinesDoesNotExist
 	}
+	return r.storeMarkets(model)
+}
+
+func (r *sportLineRepo) storeMarkets(model *domain.SportLine) error {
+	const deleteQuery = "DELETE FROM sport_line_markets WHERE sport_type = $1;"
+	const insertQuery = `INSERT INTO sport_line_markets (sport_type, market, point, selection, price)
+				VALUES ($1, $2, $3, $4, $5);`
+
+	_, err := r.tx.Exec(context.Background(), deleteQuery, model.Type)
+	if err != nil {
+		return err
+	}
+	for _, market := range model.Markets {
+		for _, selection := range market.Selections {
+			_, err = r.tx.Exec(context.Background(), insertQuery, model.Type, market.Type, market.Point, selection.Name, selection.Price)
+			if err != nil {
+				return err
+			}
+		}
+	}
 	return nil
 }
@@ -8,6 +8,7 @@ import (
 	"github.com/col3name/lines/pkg/kiddy-line-processor/application/service"
 	"github.com/jackc/pgconn"
 	"github.com/pashagolub/pgxmock"
+	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
 	"testing"
 )
@@ -47,7 +48,7 @@ func TestStore(t *testing.T) {
 		{
 			name: "failed start transaction",
 			input: &inputStore{
-				sport: &domain.SportLine{Type: domain.Baseball, Score: 0.744},
+				sport: &domain.SportLine{Type: domain.Baseball, Score: decimal.RequireFromString("0.744")},
 			},
 			expected: &expectedStore{
 				status: failedStartTransaction,
@@ -58,7 +59,7 @@ func TestStore(t *testing.T) {
 		{
 			name: "failed rollback transaction",
 			input: &inputStore{
-				sport: &domain.SportLine{Type: domain.Baseball, Score: 0.744},
+				sport: &domain.SportLine{Type: domain.Baseball, Score: decimal.RequireFromString("0.744")},
 			},
 			expected: &expectedStore{
 				status: failedDoRollback,
@@ -69,7 +70,7 @@ func TestStore(t *testing.T) {
 		{
 			name: "success rollback transaction",
 			input: &inputStore{
-				sport: &domain.SportLine{Type: domain.Baseball, Score: 0.744},
+				sport: &domain.SportLine{Type: domain.Baseball, Score: decimal.RequireFromString("0.744")},
 			},
 			expected: &expectedStore{
 				status: successDoRollback,
@@ -80,7 +81,7 @@ func TestStore(t *testing.T) {
 		{
 			name: "failed commit transaction sport line doesn't exist",
 			input: &inputStore{
-				sport:       &domain.SportLine{Type: domain.Baseball, Score: 0.744},
+				sport:       &domain.SportLine{Type: domain.Baseball, Score: decimal.RequireFromString("0.744")},
 				errorCommit: domain.ErrSportLinesDoesNotExist,
 			},
 			expected: &expectedStore{
@@ -92,29 +93,29 @@ func TestStore(t *testing.T) {
 		{
 			name: "failed commit transaction",
 			input: &inputStore{
-				sport: &domain.SportLine{Type: domain.Baseball, Score: 0.744},
+				sport: &domain.SportLine{Type: domain.Baseball, Score: decimal.RequireFromString("0.744")},
 			},
 			expected: &expectedStore{
 				status: failedDoCommit,
 				err:    errors.ErrInternal,
-				result: pgconn.CommandTag("UPDATE 1"),
+				result: pgconn.CommandTag("INSERT 0 1"),
 			},
 		},
 		{
 			name: "success commit transaction",
 			input: &inputStore{
-				sport: &domain.SportLine{Type: domain.Baseball, Score: 0.744},
+				sport: &domain.SportLine{Type: domain.Baseball, Score: decimal.RequireFromString("0.744")},
 			},
 			expected: &expectedStore{
 				status: successDoCommit,
 				err:    nil,
-				result: pgconn.CommandTag("UPDATE 1"),
+				result: pgconn.CommandTag("INSERT 0 1"),
 			},
 		},
 		{
 			name: "failed save",
 			input: &inputStore{
-				sport: &domain.SportLine{Type: domain.Baseball, Score: 0.744},
+				sport: &domain.SportLine{Type: domain.Baseball, Score: decimal.RequireFromString("0.744")},
 			},
 			expected: &expectedStore{
 				status: ok,
@@ -125,12 +126,23 @@ func TestStore(t *testing.T) {
 		{
 			name: "success save",
 			input: &inputStore{
-				sport: &domain.SportLine{Type: domain.Baseball, Score: 0.744},
+				sport: &domain.SportLine{Type: domain.Baseball, Score: decimal.RequireFromString("0.744")},
 			},
 			expected: &expectedStore{
 				status: ok,
 				err:    nil,
-				result: pgxmock.NewResult("UPDATE", 1),
+				result: pgxmock.NewResult("INSERT", 1),
+			},
+		},
+		{
+			name: "upsert newly registered sport",
+			input: &inputStore{
+				sport: &domain.SportLine{Type: "hockey", Score: decimal.RequireFromString("1.25")},
+			},
+			expected: &expectedStore{
+				status: ok,
+				err:    nil,
+				result: pgxmock.NewResult("INSERT", 1),
 			},
 		},
 	}
@@ -166,45 +178,136 @@ func setupStoreUseCases(mock pgxmock.PgxPoolIface, test *storeTest) {
 	switch expected.status {
 	case ok:
 		mock.ExpectBegin()
-		exec := mock.ExpectExec("UPDATE sport_lines")
+		exec := mock.ExpectExec("INSERT INTO sport_lines").
+			WithArgs(pgxmock.AnyArg(), inputType, inputScore)
 		if expectedErr != nil {
 			exec.WillReturnError(expectedErr)
 			mock.ExpectRollback()
 		} else {
 			exec.WillReturnResult(expected.result)
+			expectDeleteMarkets(mock, inputType)
 			mock.ExpectCommit().WillReturnError(nil)
 		}
 	case failedStartTransaction:
 		mock.ExpectBegin().WillReturnError(expectedErr)
 	case failedDoRollback:
 		mock.ExpectBegin().WillReturnError(nil)
-		mock.ExpectExec("UPDATE sport_lines").WillReturnError(expectedErr)
+		mock.ExpectExec("INSERT INTO sport_lines").WillReturnError(expectedErr)
 		mock.ExpectRollback().WillReturnError(expectedErr)
 	case successDoRollback:
 		mock.ExpectBegin().WillReturnError(nil)
-		mock.ExpectExec("UPDATE sport_lines").
-			WithArgs(inputScore, inputType).
+		mock.ExpectExec("INSERT INTO sport_lines").
+			WithArgs(pgxmock.AnyArg(), inputType, inputScore).
 			WillReturnError(expectedErr)
 		mock.ExpectRollback().WillReturnError(nil)
 	case failedDoCommitUserDoesNotExist:
 		mock.ExpectBegin().WillReturnError(nil)
-		mock.ExpectExec("UPDATE sport_lines").
-			WithArgs(inputScore, inputType).
+		mock.ExpectExec("INSERT INTO sport_lines").
+			WithArgs(pgxmock.AnyArg(), inputType, inputScore).
 			WillReturnResult(expected.result).
 			WillReturnError(input.errorCommit)
 		mock.ExpectRollback().WillReturnError(expected.err)
 	case failedDoCommit:
 		mock.ExpectBegin().WillReturnError(nil)
-		mock.ExpectExec("UPDATE sport_lines").
-			WithArgs(inputScore, inputType).
+		mock.ExpectExec("INSERT INTO sport_lines").
+			WithArgs(pgxmock.AnyArg(), inputType, inputScore).
 			WillReturnResult(expected.result).
 			WillReturnError(nil)
+		expectDeleteMarkets(mock, inputType)
 		mock.ExpectCommit().WillReturnError(errors.ErrInternal)
 	case successDoCommit:
 		mock.ExpectBegin().WillReturnError(nil)
-		mock.ExpectExec("UPDATE sport_lines").
+		mock.ExpectExec("INSERT INTO sport_lines").
 			WillReturnResult(expected.result).
 			WillReturnError(nil)
+		expectDeleteMarkets(mock, inputType)
 		mock.ExpectCommit().WillReturnError(nil)
 	}
 }
+
+func expectDeleteMarkets(mock pgxmock.PgxPoolIface, sportType domain.SportType) {
+	mock.ExpectExec("DELETE FROM sport_line_markets").
+		WithArgs(sportType).
+		WillReturnResult(pgxmock.NewResult("DELETE", 0))
+}
+
+func TestStoreMarkets(t *testing.T) {
+	sport := &domain.SportLine{
+		Type:  domain.Soccer,
+		Score: decimal.RequireFromString("1.5"),
+		Markets: []*domain.Market{
+			{Type: domain.Moneyline, Selections: []*domain.Selection{{Name: "home", Price: decimal.RequireFromString("1.85")}, {Name: "away", Price: decimal.RequireFromString("2.1")}}},
+			{Type: domain.Totals, Point: decimal.RequireFromString("2.5"), Selections: []*domain.Selection{{Name: "over", Price: decimal.RequireFromString("1.95")}}},
+		},
+	}
+	tests := []struct {
+		name      string
+		insertErr error
+		expected  error
+	}{
+		{name: "failed store selection", insertErr: errors.ErrInternal, expected: errors.ErrInternal},
+		{name: "success store markets", insertErr: nil, expected: nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mock, err := postgres.GetPgxMockPool(t)
+			if err != nil {
+				return
+			}
+			defer mock.Close()
+
+			mock.ExpectBegin()
+			mock.ExpectExec("INSERT INTO sport_lines").
+				WithArgs(pgxmock.AnyArg(), sport.Type, sport.Score).
+				WillReturnResult(pgxmock.NewResult("INSERT", 1))
+			mock.ExpectExec("DELETE FROM sport_line_markets").
+				WithArgs(sport.Type).
+				WillReturnResult(pgxmock.NewResult("DELETE", 3))
+			if test.insertErr != nil {
+				mock.ExpectExec("INSERT INTO sport_line_markets").WillReturnError(test.insertErr)
+				mock.ExpectRollback()
+			} else {
+				for _, market := range sport.Markets {
+					for _, selection := range market.Selections {
+						mock.ExpectExec("INSERT INTO sport_line_markets").
+							WithArgs(sport.Type, market.Type, market.Point, selection.Name, selection.Price).
+							WillReturnResult(pgxmock.NewResult("INSERT", 1))
+					}
+				}
+				mock.ExpectCommit()
+			}
+
+			uow := NewUnitOfWork(mock, fake.Logger{})
+			err = uow.Execute(func(rp service.RepositoryProvider) error {
+				return rp.SportLineRepo().Store(sport)
+			})
+			assert.Equal(t, test.expected, err)
+			postgres.CheckExpectationsWereMet(t, mock)
+		})
+	}
+}
+
+func TestStoreClearsPulledMarkets(t *testing.T) {
+	mock, err := postgres.GetPgxMockPool(t)
+	if err != nil {
+		return
+	}
+	defer mock.Close()
+
+	sport := &domain.SportLine{Type: domain.Soccer, Score: decimal.RequireFromString("1.5")}
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO sport_lines").
+		WithArgs(pgxmock.AnyArg(), sport.Type, sport.Score).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectExec("DELETE FROM sport_line_markets").
+		WithArgs(sport.Type).
+		WillReturnResult(pgxmock.NewResult("DELETE", 2))
+	mock.ExpectCommit()
+
+	uow := NewUnitOfWork(mock, fake.Logger{})
+	err = uow.Execute(func(rp service.RepositoryProvider) error {
+		return rp.SportLineRepo().Store(sport)
+	})
+	assert.Nil(t, err)
+	postgres.CheckExpectationsWereMet(t, mock)
+}
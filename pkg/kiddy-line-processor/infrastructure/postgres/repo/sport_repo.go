@@ -0,0 +1,44 @@
+package repo
+
+import (
+	"context"
+	"github.com/col3name/lines/pkg/common/application/logger"
+	"github.com/col3name/lines/pkg/common/domain"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/domain/repo"
+	"github.com/jackc/pgx/v4"
+)
+
+type sportRepo struct {
+	tx     pgx.Tx
+	logger logger.Logger
+}
+
+func NewSportRepository(tx pgx.Tx, logger logger.Logger) repo.SportRepo {
+	return &sportRepo{tx: tx, logger: logger}
+}
+
+func (r *sportRepo) Add(sportType domain.SportType) error {
+	const sql = "INSERT INTO sports (name) VALUES ($1) ON CONFLICT DO NOTHING;"
+
+	result, err := r.tx.Exec(context.Background(), sql, sportType)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return domain.ErrSportAlreadyExists
+	}
+	return nil
+}
+
+func (r *sportRepo) Remove(sportType domain.SportType) error {
+	const sql = "DELETE FROM sports WHERE name = $1;"
+
+	result, err := r.tx.Exec(context.Background(), sql, sportType)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return domain.ErrUnsupportedSportType
+	}
+	return nil
+}
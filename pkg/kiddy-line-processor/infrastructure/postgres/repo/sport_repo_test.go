@@ -0,0 +1,83 @@
+package repo
+
+import (
+	"github.com/col3name/lines/pkg/common/domain"
+	"github.com/col3name/lines/pkg/common/infrastructure/postgres"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/application/fake"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/application/service"
+	"github.com/pashagolub/pgxmock"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestAddSport(t *testing.T) {
+	tests := []struct {
+		name         string
+		rowsAffected int64
+		expected     error
+	}{
+		{name: "sport already exists", rowsAffected: 0, expected: domain.ErrSportAlreadyExists},
+		{name: "success add", rowsAffected: 1, expected: nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mock, err := postgres.GetPgxMockPool(t)
+			if err != nil {
+				return
+			}
+			defer mock.Close()
+
+			sportType := domain.SportType("tennis")
+			mock.ExpectBegin()
+			mock.ExpectExec("INSERT INTO sports").WithArgs(sportType).
+				WillReturnResult(pgxmock.NewResult("INSERT", test.rowsAffected))
+			mock.ExpectCommit()
+
+			var addErr error
+			uow := NewUnitOfWork(mock, fake.Logger{})
+			err = uow.Execute(func(rp service.RepositoryProvider) error {
+				addErr = rp.SportRepo().Add(sportType)
+				return nil
+			})
+			assert.Nil(t, err)
+			assert.Equal(t, test.expected, addErr)
+			postgres.CheckExpectationsWereMet(t, mock)
+		})
+	}
+}
+
+func TestRemoveSport(t *testing.T) {
+	tests := []struct {
+		name         string
+		rowsAffected int64
+		expected     error
+	}{
+		{name: "sport does not exist", rowsAffected: 0, expected: domain.ErrUnsupportedSportType},
+		{name: "success remove", rowsAffected: 1, expected: nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mock, err := postgres.GetPgxMockPool(t)
+			if err != nil {
+				return
+			}
+			defer mock.Close()
+
+			sportType := domain.SportType("tennis")
+			mock.ExpectBegin()
+			mock.ExpectExec("DELETE FROM sports").WithArgs(sportType).
+				WillReturnResult(pgxmock.NewResult("DELETE", test.rowsAffected))
+			mock.ExpectCommit()
+
+			var removeErr error
+			uow := NewUnitOfWork(mock, fake.Logger{})
+			err = uow.Execute(func(rp service.RepositoryProvider) error {
+				removeErr = rp.SportRepo().Remove(sportType)
+				return nil
+			})
+			assert.Nil(t, err)
+			assert.Equal(t, test.expected, removeErr)
+			postgres.CheckExpectationsWereMet(t, mock)
+		})
+	}
+}
@@ -24,7 +24,7 @@ func NewUnitOfWork(conn postgres.PgxPoolIface, logger logger.Logger) *unitOfWork
 
 func (u *unitOfWork) Execute(fn service.Job) error {
 	cancelFunc, err := u.db.WithTx(func(tx pgx.Tx) error {
-		return fn(&repositoryProvider{tx: tx})
+		return fn(&repositoryProvider{tx: tx, logger: u.logger})
 	}, u.logger)
 	if err != nil {
 		return infrastructure.InternalError(u.logger, err)
@@ -45,3 +45,19 @@ func (r *repositoryProvider) MigrationRepo() repo.MigrationRepo {
 func (r *repositoryProvider) SportLineRepo() repo.SportLineRepo {
 	return NewSportLineRepository(r.tx, r.logger)
 }
+
+func (r *repositoryProvider) SportLineHistoryRepo() repo.SportLineHistoryRepo {
+	return NewSportLineHistoryRepository(r.tx, r.logger)
+}
+
+func (r *repositoryProvider) NotifyRepo() repo.NotifyRepo {
+	return NewNotifyRepo(r.tx)
+}
+
+func (r *repositoryProvider) SportRepo() repo.SportRepo {
+	return NewSportRepository(r.tx, r.logger)
+}
+
+func (r *repositoryProvider) EventRepo() repo.EventRepo {
+	return NewEventRepository(r.tx, r.logger)
+}
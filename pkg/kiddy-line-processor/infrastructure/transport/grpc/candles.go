@@ -0,0 +1,39 @@
+package grpc
+
+import (
+	"context"
+	"github.com/col3name/lines/pkg/common/application/errors"
+	commonDomain "github.com/col3name/lines/pkg/common/domain"
+	pb "github.com/col3name/lines/pkg/kiddy-line-processor/infrastructure/transport/grpc/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"time"
+)
+
+func (s *Server) GetCandles(_ context.Context, req *pb.GetCandlesRequest) (*pb.GetCandlesResponse, error) {
+	sportType, err := commonDomain.NewSportType(req.Sport, s.sportRegistry)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+	bucket, err := time.ParseDuration(req.Bucket)
+	if err != nil || req.From == nil || req.To == nil {
+		return nil, toStatusError(errors.ErrInvalidArgument)
+	}
+
+	candles, err := s.candleService.GetCandles(sportType, bucket, req.From.AsTime(), req.To.AsTime())
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+	response := &pb.GetCandlesResponse{Candles: make([]*pb.Candle, 0, len(candles))}
+	for _, candle := range candles {
+		response.Candles = append(response.Candles, &pb.Candle{
+			Sport:    candle.SportType.String(),
+			OpenTime: timestamppb.New(candle.OpenTime),
+			Open:     candle.Open.String(),
+			High:     candle.High.String(),
+			Low:      candle.Low.String(),
+			Close:    candle.Close.String(),
+			Count:    int32(candle.Count),
+		})
+	}
+	return response, nil
+}
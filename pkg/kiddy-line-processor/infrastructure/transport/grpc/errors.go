@@ -0,0 +1,40 @@
+package grpc
+
+import (
+	goErrors "errors"
+	"github.com/col3name/lines/pkg/common/application/errors"
+	commonDomain "github.com/col3name/lines/pkg/common/domain"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/application/service"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/application/service/subscription"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	errIntervalNotPositive   = goErrors.New("interval must be positive number")
+	errIntervalOutOfBounds   = goErrors.New("interval is out of allowed bounds")
+	errSportIntervalRejected = goErrors.New("sport interval is out of allowed bounds or sport is not subscribed")
+	errServerShuttingDown    = goErrors.New("server is shutting down")
+	errAdminUnauthenticated  = goErrors.New("admin token is missing or invalid")
+)
+
+func toStatusError(err error) error {
+	switch err {
+	case errors.ErrInvalidArgument:
+		return status.Error(codes.InvalidArgument, err.Error())
+	case commonDomain.ErrUnsupportedSportType:
+		return status.Error(codes.NotFound, err.Error())
+	case subscription.ErrInvalidClientId:
+		return status.Error(codes.InvalidArgument, err.Error())
+	case subscription.ErrClientAlreadyConnected:
+		return status.Error(codes.AlreadyExists, err.Error())
+	case service.ErrSlowConsumer:
+		return status.Error(codes.ResourceExhausted, err.Error())
+	case errServerShuttingDown:
+		return status.Error(codes.Unavailable, err.Error())
+	case errAdminUnauthenticated:
+		return status.Error(codes.Unauthenticated, err.Error())
+	default:
+		return status.Error(codes.Internal, errors.ErrInternal.Error())
+	}
+}
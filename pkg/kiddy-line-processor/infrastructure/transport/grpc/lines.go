@@ -0,0 +1,42 @@
+package grpc
+
+import (
+	"context"
+	commonDomain "github.com/col3name/lines/pkg/common/domain"
+	pb "github.com/col3name/lines/pkg/kiddy-line-processor/infrastructure/transport/grpc/proto"
+)
+
+func (s *Server) GetLines(_ context.Context, req *pb.GetLinesRequest) (*pb.GetLinesResponse, error) {
+	sportTypes, err := s.parseLinesRequest(req.Sports)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	lines, err := s.sportLineQueryService.GetLinesBySportTypes(sportTypes)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+	markets, err := s.sportLineQueryService.GetMarketsBySportTypes(sportTypes, commonDomain.AllMarketTypes)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+	for _, line := range lines {
+		line.Markets = markets[line.Type]
+	}
+	return &pb.GetLinesResponse{Sports: toSports(lines)}, nil
+}
+
+func (s *Server) parseLinesRequest(sports []string) ([]commonDomain.SportType, error) {
+	if len(sports) == 0 {
+		return s.sportRegistry.Sports(), nil
+	}
+	result := make([]commonDomain.SportType, 0, len(sports))
+	for _, sport := range sports {
+		sportType, err := commonDomain.NewSportType(sport, s.sportRegistry)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, sportType)
+	}
+	return result, nil
+}
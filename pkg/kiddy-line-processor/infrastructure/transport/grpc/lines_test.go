@@ -0,0 +1,109 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	commonDomain "github.com/col3name/lines/pkg/common/domain"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/application/fake"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/domain/model"
+	pb "github.com/col3name/lines/pkg/kiddy-line-processor/infrastructure/transport/grpc/proto"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"testing"
+	"time"
+)
+
+type mockSportLineQueryService struct {
+	requested []commonDomain.SportType
+	err       error
+}
+
+func (m *mockSportLineQueryService) GetLinesBySportTypes(sportTypes []commonDomain.SportType) ([]*commonDomain.SportLine, error) {
+	m.requested = sportTypes
+	if m.err != nil {
+		return nil, m.err
+	}
+	lines := make([]*commonDomain.SportLine, 0, len(sportTypes))
+	for _, sportType := range sportTypes {
+		lines = append(lines, &commonDomain.SportLine{Type: sportType, Score: decimal.RequireFromString("1.5")})
+	}
+	return lines, nil
+}
+
+func (m *mockSportLineQueryService) GetMarketsBySportTypes(sportTypes []commonDomain.SportType, _ []commonDomain.MarketType) (map[commonDomain.SportType][]*commonDomain.Market, error) {
+	markets := make(map[commonDomain.SportType][]*commonDomain.Market)
+	for _, sportType := range sportTypes {
+		markets[sportType] = []*commonDomain.Market{{
+			Type:       commonDomain.Moneyline,
+			Selections: []*commonDomain.Selection{{Name: "home", Price: decimal.RequireFromString("2.1")}},
+		}}
+	}
+	return markets, nil
+}
+
+func (m *mockSportLineQueryService) GetLinesAsOf(_ []commonDomain.SportType, _ time.Time) ([]*commonDomain.SportLine, error) {
+	return nil, nil
+}
+
+func (m *mockSportLineQueryService) GetLineHistory(_ commonDomain.SportType, _, _ time.Time, _ int, _ string) (*model.SportLineHistoryPage, error) {
+	return nil, nil
+}
+
+func (m *mockSportLineQueryService) GetCandles(_ commonDomain.SportType, _ time.Duration, _, _ time.Time, _ int) ([]*model.Candle, error) {
+	return nil, nil
+}
+
+func TestGetLines(t *testing.T) {
+	tests := []struct {
+		name          string
+		sports        []string
+		queryErr      error
+		expectedCode  codes.Code
+		expectedSport []commonDomain.SportType
+	}{
+		{
+			name:          "requested sports",
+			sports:        []string{"Soccer"},
+			expectedCode:  codes.OK,
+			expectedSport: []commonDomain.SportType{commonDomain.Soccer},
+		},
+		{
+			name:          "all sports",
+			expectedCode:  codes.OK,
+			expectedSport: []commonDomain.SportType{commonDomain.Baseball, commonDomain.Soccer},
+		},
+		{
+			name:         "unsupported sport",
+			sports:       []string{"soccer", "curling"},
+			expectedCode: codes.NotFound,
+		},
+		{
+			name:         "query error",
+			sports:       []string{"soccer"},
+			queryErr:     errors.New("connection refused"),
+			expectedCode: codes.Internal,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			queryService := &mockSportLineQueryService{err: test.queryErr}
+			server := &Server{sportLineQueryService: queryService, sportRegistry: &mockSportRegistry{}, logger: &fake.Logger{}}
+
+			response, err := server.GetLines(context.Background(), &pb.GetLinesRequest{Sports: test.sports})
+			assert.Equal(t, test.expectedCode, status.Code(err))
+			if test.expectedCode != codes.OK {
+				assert.Nil(t, response)
+				return
+			}
+			assert.Equal(t, test.expectedSport, queryService.requested)
+			assert.Equal(t, len(test.expectedSport), len(response.Sports))
+			for i, sport := range response.Sports {
+				assert.Equal(t, test.expectedSport[i].String(), sport.Type)
+				assert.Equal(t, "1.5", sport.Line)
+				assert.Equal(t, "2.1", sport.Markets[0].Selections[0].Price)
+			}
+		})
+	}
+}
@@ -19,6 +19,9 @@ const _ = grpc.SupportPackageIsVersion7
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type KiddyLineProcessorClient interface {
 	SubscribeOnSportsLines(ctx context.Context, opts ...grpc.CallOption) (KiddyLineProcessor_SubscribeOnSportsLinesClient, error)
+	GetCandles(ctx context.Context, in *GetCandlesRequest, opts ...grpc.CallOption) (*GetCandlesResponse, error)
+	ListSubscriptions(ctx context.Context, in *ListSubscriptionsRequest, opts ...grpc.CallOption) (*ListSubscriptionsResponse, error)
+	GetLines(ctx context.Context, in *GetLinesRequest, opts ...grpc.CallOption) (*GetLinesResponse, error)
 }
 
 type kiddyLineProcessorClient struct {
@@ -60,11 +63,41 @@ func (x *kiddyLineProcessorSubscribeOnSportsLinesClient) Recv() (*SubscribeRespo
 	return m, nil
 }
 
+func (c *kiddyLineProcessorClient) GetCandles(ctx context.Context, in *GetCandlesRequest, opts ...grpc.CallOption) (*GetCandlesResponse, error) {
+	out := new(GetCandlesResponse)
+	err := c.cc.Invoke(ctx, "/proto.KiddyLineProcessor/GetCandles", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kiddyLineProcessorClient) ListSubscriptions(ctx context.Context, in *ListSubscriptionsRequest, opts ...grpc.CallOption) (*ListSubscriptionsResponse, error) {
+	out := new(ListSubscriptionsResponse)
+	err := c.cc.Invoke(ctx, "/proto.KiddyLineProcessor/ListSubscriptions", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kiddyLineProcessorClient) GetLines(ctx context.Context, in *GetLinesRequest, opts ...grpc.CallOption) (*GetLinesResponse, error) {
+	out := new(GetLinesResponse)
+	err := c.cc.Invoke(ctx, "/proto.KiddyLineProcessor/GetLines", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // KiddyLineProcessorServer is the server API for KiddyLineProcessor service.
 // All implementations must embed UnimplementedKiddyLineProcessorServer
 // for forward compatibility
 type KiddyLineProcessorServer interface {
 	SubscribeOnSportsLines(KiddyLineProcessor_SubscribeOnSportsLinesServer) error
+	GetCandles(context.Context, *GetCandlesRequest) (*GetCandlesResponse, error)
+	ListSubscriptions(context.Context, *ListSubscriptionsRequest) (*ListSubscriptionsResponse, error)
+	GetLines(context.Context, *GetLinesRequest) (*GetLinesResponse, error)
 	mustEmbedUnimplementedKiddyLineProcessorServer()
 }
 
@@ -75,6 +108,15 @@ type UnimplementedKiddyLineProcessorServer struct {
 func (UnimplementedKiddyLineProcessorServer) SubscribeOnSportsLines(KiddyLineProcessor_SubscribeOnSportsLinesServer) error {
 	return status.Errorf(codes.Unimplemented, "method SubscribeOnSportsLines not implemented")
 }
+func (UnimplementedKiddyLineProcessorServer) GetCandles(context.Context, *GetCandlesRequest) (*GetCandlesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetCandles not implemented")
+}
+func (UnimplementedKiddyLineProcessorServer) ListSubscriptions(context.Context, *ListSubscriptionsRequest) (*ListSubscriptionsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListSubscriptions not implemented")
+}
+func (UnimplementedKiddyLineProcessorServer) GetLines(context.Context, *GetLinesRequest) (*GetLinesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetLines not implemented")
+}
 func (UnimplementedKiddyLineProcessorServer) mustEmbedUnimplementedKiddyLineProcessorServer() {}
 
 // UnsafeKiddyLineProcessorServer may be embedded to opt out of forward compatibility for this service.
@@ -114,13 +156,80 @@ func (x *kiddyLineProcessorSubscribeOnSportsLinesServer) Recv() (*SubscribeReque
 	return m, nil
 }
 
+func _KiddyLineProcessor_GetCandles_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetCandlesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KiddyLineProcessorServer).GetCandles(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.KiddyLineProcessor/GetCandles",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KiddyLineProcessorServer).GetCandles(ctx, req.(*GetCandlesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _KiddyLineProcessor_ListSubscriptions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListSubscriptionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KiddyLineProcessorServer).ListSubscriptions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.KiddyLineProcessor/ListSubscriptions",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KiddyLineProcessorServer).ListSubscriptions(ctx, req.(*ListSubscriptionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _KiddyLineProcessor_GetLines_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetLinesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KiddyLineProcessorServer).GetLines(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.KiddyLineProcessor/GetLines",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KiddyLineProcessorServer).GetLines(ctx, req.(*GetLinesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // KiddyLineProcessor_ServiceDesc is the grpc.ServiceDesc for KiddyLineProcessor service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
 var KiddyLineProcessor_ServiceDesc = grpc.ServiceDesc{
 	ServiceName: "proto.KiddyLineProcessor",
 	HandlerType: (*KiddyLineProcessorServer)(nil),
-	Methods:     []grpc.MethodDesc{},
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetCandles",
+			Handler:    _KiddyLineProcessor_GetCandles_Handler,
+		},
+		{
+			MethodName: "ListSubscriptions",
+			Handler:    _KiddyLineProcessor_ListSubscriptions_Handler,
+		},
+		{
+			MethodName: "GetLines",
+			Handler:    _KiddyLineProcessor_GetLines_Handler,
+		},
+	},
 	Streams: []grpc.StreamDesc{
 		{
 			StreamName:    "SubscribeOnSportsLines",
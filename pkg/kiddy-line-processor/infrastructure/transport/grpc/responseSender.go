@@ -2,21 +2,176 @@ package grpc
 
 import (
 	"github.com/col3name/lines/pkg/common/domain"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/application/service"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/application/service/subscription"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/domain/model"
 	pb "github.com/col3name/lines/pkg/kiddy-line-processor/infrastructure/transport/grpc/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"time"
 )
 
 type ResponseSenderGrpc struct {
 	Stream pb.KiddyLineProcessor_SubscribeOnSportsLinesServer
+	buffer *sendBuffer
 }
 
-func (s *ResponseSenderGrpc) Send(sports []*domain.SportLine) error {
+func NewResponseSender(stream pb.KiddyLineProcessor_SubscribeOnSportsLinesServer, config BufferConfig) *ResponseSenderGrpc {
+	return &ResponseSenderGrpc{Stream: stream, buffer: newSendBuffer(config)}
+}
+
+func (s *ResponseSenderGrpc) Run() {
+	for {
+		msg, ok := s.buffer.pop()
+		if !ok {
+			return
+		}
+		if err := s.Stream.Send(msg.response); err != nil {
+			s.buffer.abort(msg, err)
+			return
+		}
+	}
+}
+
+func (s *ResponseSenderGrpc) Close() {
+	s.buffer.shutdown()
+}
+
+func (s *ResponseSenderGrpc) GoAway(reason string) error {
+	err := s.send(&pb.SubscribeResponse{Payload: &pb.SubscribeResponse_GoingAway{GoingAway: &pb.GoingAway{Reason: reason}}})
+	s.buffer.drain()
+	return err
+}
+
+func (s *ResponseSenderGrpc) Failed() <-chan error {
+	return s.buffer.failed
+}
+
+func (s *ResponseSenderGrpc) Stats() service.SendStats {
+	return s.buffer.stats()
+}
+
+func (s *ResponseSenderGrpc) Undelivered() []string {
+	return s.buffer.undelivered()
+}
+
+func (s *ResponseSenderGrpc) Send(update *model.LineUpdate) error {
+	return s.buffer.push(&outboundMessage{update: update, response: &pb.SubscribeResponse{Payload: &pb.SubscribeResponse_Update{Update: &pb.LineUpdate{
+		SubscriptionId: update.SubscriptionId,
+		Sports:         toSports(update.Sports),
+		Events:         toEvents(update.Events),
+		Sequence:       update.Sequence,
+		Snapshot:       update.Snapshot,
+	}}}})
+}
+
+func (s *ResponseSenderGrpc) Ack(ack *model.SubscriptionAck) error {
+	response := &pb.SubscriptionAck{
+		SubscriptionId:   ack.SubscriptionId,
+		Removed:          ack.Removed,
+		IntervalInSecond: int32(ack.Interval / time.Second),
+		IntervalMs:       ack.Interval.Milliseconds(),
+		SportIntervalsMs: toIntervalsMs(ack.SportIntervals),
+		EventIds:         ack.EventIDs,
+		OddsFormat:       ack.OddsFormat.String(),
+		DeliveryMode:     ack.Mode.String(),
+	}
+	response.DeltaMode, response.DeltaThreshold = toDelta(ack.Delta)
+	for _, sportType := range ack.Sports {
+		response.Sports = append(response.Sports, sportType.String())
+	}
+	for _, market := range ack.Markets {
+		response.Markets = append(response.Markets, market.String())
+	}
+	return s.send(&pb.SubscribeResponse{Payload: &pb.SubscribeResponse_Ack{Ack: response}})
+}
+
+func (s *ResponseSenderGrpc) Reject(subscriptionId string, err error) error {
+	code := pb.ErrorCode_NOT_ACCEPTED
+	switch err {
+	case subscription.ErrUnknownSubscription:
+		code = pb.ErrorCode_UNKNOWN_SUBSCRIPTION
+	case subscription.ErrEmptySubscription:
+		code = pb.ErrorCode_EMPTY_SUBSCRIPTION
+	}
+	return s.SendError(&pb.SubscriptionError{Code: code, SubscriptionId: subscriptionId, Message: err.Error()})
+}
+
+func (s *ResponseSenderGrpc) SendError(subscriptionError *pb.SubscriptionError) error {
+	return s.send(&pb.SubscribeResponse{Payload: &pb.SubscribeResponse_Error{Error: subscriptionError}})
+}
+
+func (s *ResponseSenderGrpc) send(response *pb.SubscribeResponse) error {
+	return s.buffer.push(&outboundMessage{response: response})
+}
+
+func toSports(sports []*domain.SportLine) []*pb.Sport {
 	var list []*pb.Sport
 	for _, sport := range sports {
 		list = append(list, &pb.Sport{
-			Type: sport.Type.String(),
-			Line: sport.Score,
+			Type:    sport.Type.String(),
+			Line:    sport.Score.String(),
+			Markets: toMarkets(sport.Markets),
+		})
+	}
+	return list
+}
+
+func toPrice(selection *domain.Selection) string {
+	if selection.Odds != "" {
+		return selection.Odds
+	}
+	return selection.Price.String()
+}
+
+func toEvents(events []*domain.Event) []*pb.Event {
+	var list []*pb.Event
+	for _, event := range events {
+		list = append(list, &pb.Event{
+			Id:        event.ID,
+			Sport:     event.SportType.String(),
+			HomeTeam:  event.HomeTeam,
+			AwayTeam:  event.AwayTeam,
+			StartTime: timestamppb.New(event.StartTime),
+			Status:    event.Status.String(),
+			Line:      event.Line.String(),
 		})
 	}
-	response := &pb.SubscribeResponse{Sports: list}
-	return s.Stream.Send(response)
+	return list
+}
+
+func toMarkets(markets []*domain.Market) []*pb.Market {
+	var list []*pb.Market
+	for _, market := range markets {
+		selections := make([]*pb.Selection, 0, len(market.Selections))
+		for _, selection := range market.Selections {
+			selections = append(selections, &pb.Selection{Name: selection.Name, Price: toPrice(selection)})
+		}
+		list = append(list, &pb.Market{
+			Type:       market.Type.String(),
+			Point:      market.Point.String(),
+			Selections: selections,
+		})
+	}
+	return list
+}
+
+func toIntervalsMs(intervals model.SportIntervalMap) map[string]int64 {
+	if len(intervals) == 0 {
+		return nil
+	}
+	result := make(map[string]int64, len(intervals))
+	for sportType, interval := range intervals {
+		result[sportType.String()] = interval.Milliseconds()
+	}
+	return result
+}
+
+func toDelta(policy model.DeltaPolicy) (string, string) {
+	if policy == nil {
+		return "", ""
+	}
+	if !policy.Threshold().IsPositive() {
+		return policy.Mode().String(), ""
+	}
+	return policy.Mode().String(), policy.Threshold().String()
 }
@@ -0,0 +1,262 @@
+package grpc
+
+import (
+	"errors"
+	"expvar"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/application/service"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/domain/model"
+	pb "github.com/col3name/lines/pkg/kiddy-line-processor/infrastructure/transport/grpc/proto"
+	"sort"
+	"strings"
+	"sync"
+)
+
+type OverflowPolicy string
+
+const (
+	OverflowDropOldest OverflowPolicy = "drop_oldest"
+	OverflowCoalesce   OverflowPolicy = "coalesce"
+	OverflowDisconnect OverflowPolicy = "disconnect"
+)
+
+var (
+	ErrUnsupportedOverflowPolicy = errors.New("unsupported overflow policy")
+	errSenderClosed              = errors.New("response sender is closed")
+)
+
+var (
+	droppedUpdates   = expvar.NewInt("subscription_updates_dropped")
+	coalescedUpdates = expvar.NewInt("subscription_updates_coalesced")
+)
+
+func (p OverflowPolicy) String() string {
+	return string(p)
+}
+
+func NewOverflowPolicy(policy string) (OverflowPolicy, error) {
+	switch strings.ToLower(policy) {
+	case "", OverflowDropOldest.String():
+		return OverflowDropOldest, nil
+	case OverflowCoalesce.String():
+		return OverflowCoalesce, nil
+	case OverflowDisconnect.String():
+		return OverflowDisconnect, nil
+	default:
+		return "", ErrUnsupportedOverflowPolicy
+	}
+}
+
+type BufferConfig struct {
+	Size   int
+	Policy OverflowPolicy
+}
+
+type outboundMessage struct {
+	response *pb.SubscribeResponse
+	update   *model.LineUpdate
+}
+
+type sendBuffer struct {
+	config    BufferConfig
+	queue     []*outboundMessage
+	stale     map[string]bool
+	dropped   uint64
+	coalesced uint64
+	closed    bool
+	draining  bool
+	failed    chan error
+	mu        sync.Mutex
+	cond      *sync.Cond
+}
+
+func newSendBuffer(config BufferConfig) *sendBuffer {
+	buffer := &sendBuffer{config: config, stale: make(map[string]bool), failed: make(chan error, 1)}
+	buffer.cond = sync.NewCond(&buffer.mu)
+	return buffer
+}
+
+func (b *sendBuffer) push(msg *outboundMessage) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed || b.draining {
+		return errSenderClosed
+	}
+	if b.needsSnapshot(msg.update) {
+		return service.ErrSnapshotRequired
+	}
+	if b.config.Size > 0 && len(b.queue) >= b.config.Size {
+		if err := b.overflow(msg.update); err != nil {
+			return err
+		}
+		if b.needsSnapshot(msg.update) {
+			return service.ErrSnapshotRequired
+		}
+	}
+	if msg.update != nil {
+		delete(b.stale, msg.update.SubscriptionId)
+	}
+	b.queue = append(b.queue, msg)
+	b.cond.Signal()
+	return nil
+}
+
+// needsSnapshot reports whether the update is a delta on top of an update the client will never get.
+func (b *sendBuffer) needsSnapshot(update *model.LineUpdate) bool {
+	return update != nil && !update.Snapshot && b.stale[update.SubscriptionId]
+}
+
+func (b *sendBuffer) overflow(update *model.LineUpdate) error {
+	switch b.config.Policy {
+	case OverflowDisconnect:
+		b.fail(service.ErrSlowConsumer)
+		return service.ErrSlowConsumer
+	case OverflowCoalesce:
+		if update == nil {
+			break
+		}
+		removed := b.remove(update.SubscriptionId)
+		if !update.Snapshot {
+			b.stale[update.SubscriptionId] = true
+			b.countCoalesced(removed + 1)
+			return service.ErrSnapshotRequired
+		}
+		b.countCoalesced(removed)
+	}
+	if len(b.queue) >= b.config.Size && !b.dropOldest() {
+		b.fail(service.ErrSlowConsumer)
+		return service.ErrSlowConsumer
+	}
+	return nil
+}
+
+func (b *sendBuffer) remove(subscriptionId string) int {
+	queue := b.queue[:0]
+	for _, msg := range b.queue {
+		if msg.update == nil || msg.update.SubscriptionId != subscriptionId {
+			queue = append(queue, msg)
+		}
+	}
+	removed := len(b.queue) - len(queue)
+	b.queue = queue
+	return removed
+}
+
+// dropOldest drops the oldest queued update along with the queued deltas built on it.
+// It reports false if only control messages are queued.
+func (b *sendBuffer) dropOldest() bool {
+	for i, msg := range b.queue {
+		if msg.update == nil {
+			continue
+		}
+		subscriptionId := msg.update.SubscriptionId
+		queue := b.queue[:i]
+		dropped := 1
+		reset := false
+		for _, next := range b.queue[i+1:] {
+			if !reset && next.update != nil && next.update.SubscriptionId == subscriptionId {
+				if !next.update.Snapshot {
+					dropped++
+					continue
+				}
+				reset = true
+			}
+			queue = append(queue, next)
+		}
+		b.queue = queue
+		if !reset {
+			b.stale[subscriptionId] = true
+		}
+		b.dropped += uint64(dropped)
+		droppedUpdates.Add(int64(dropped))
+		return true
+	}
+	return false
+}
+
+func (b *sendBuffer) countCoalesced(count int) {
+	b.coalesced += uint64(count)
+	coalescedUpdates.Add(int64(count))
+}
+
+func (b *sendBuffer) pop() (*outboundMessage, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for len(b.queue) == 0 && !b.closed && !b.draining {
+		b.cond.Wait()
+	}
+	if b.closed || len(b.queue) == 0 {
+		return nil, false
+	}
+	msg := b.queue[0]
+	b.queue[0] = nil
+	b.queue = b.queue[1:]
+	return msg, true
+}
+
+func (b *sendBuffer) abort(msg *outboundMessage, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if msg.update != nil {
+		b.stale[msg.update.SubscriptionId] = true
+	}
+	b.fail(err)
+}
+
+func (b *sendBuffer) fail(err error) {
+	if b.closed {
+		return
+	}
+	b.close()
+	b.failed <- err
+}
+
+func (b *sendBuffer) drain() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.draining = true
+	b.cond.Broadcast()
+}
+
+func (b *sendBuffer) shutdown() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.close()
+}
+
+func (b *sendBuffer) close() {
+	for _, msg := range b.queue {
+		if msg.update != nil {
+			b.stale[msg.update.SubscriptionId] = true
+		}
+	}
+	b.closed = true
+	b.queue = nil
+	b.cond.Broadcast()
+}
+
+// undelivered lists the subscriptions whose accepted updates never reached the client.
+func (b *sendBuffer) undelivered() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	lost := make(map[string]bool, len(b.stale))
+	for subscriptionId := range b.stale {
+		lost[subscriptionId] = true
+	}
+	for _, msg := range b.queue {
+		if msg.update != nil {
+			lost[msg.update.SubscriptionId] = true
+		}
+	}
+	result := make([]string, 0, len(lost))
+	for subscriptionId := range lost {
+		result = append(result, subscriptionId)
+	}
+	sort.Strings(result)
+	return result
+}
+
+func (b *sendBuffer) stats() service.SendStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return service.SendStats{Queued: len(b.queue), Dropped: b.dropped, Coalesced: b.coalesced}
+}
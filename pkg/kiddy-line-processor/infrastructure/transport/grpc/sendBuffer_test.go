@@ -0,0 +1,178 @@
+package grpc
+
+import (
+	"github.com/col3name/lines/pkg/kiddy-line-processor/application/service"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/domain/model"
+	pb "github.com/col3name/lines/pkg/kiddy-line-processor/infrastructure/transport/grpc/proto"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func update(subscriptionId string, sequence uint64, snapshot bool) *outboundMessage {
+	return &outboundMessage{
+		response: &pb.SubscribeResponse{},
+		update:   &model.LineUpdate{SubscriptionId: subscriptionId, Sequence: sequence, Snapshot: snapshot},
+	}
+}
+
+func TestSendBufferOverflow(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   OverflowPolicy
+		messages []*outboundMessage
+		errors   []error
+		queued   []uint64
+		stats    service.SendStats
+		failed   error
+	}{
+		{
+			name:     "drop oldest with the deltas built on it",
+			policy:   OverflowDropOldest,
+			messages: []*outboundMessage{update("a", 1, true), update("a", 2, false), update("b", 1, true)},
+			errors:   []error{nil, nil, nil},
+			queued:   []uint64{1},
+			stats:    service.SendStats{Queued: 1, Dropped: 2},
+		},
+		{
+			name:     "drop oldest requires snapshot of the subscription",
+			policy:   OverflowDropOldest,
+			messages: []*outboundMessage{update("a", 1, true), update("b", 1, true), update("b", 2, false), update("a", 2, false), update("a", 3, true), update("a", 4, false)},
+			errors:   []error{nil, nil, nil, service.ErrSnapshotRequired, nil, nil},
+			queued:   []uint64{3, 4},
+			stats:    service.SendStats{Queued: 2, Dropped: 3},
+		},
+		{
+			name:     "drop oldest keeps deltas after a queued snapshot",
+			policy:   OverflowDropOldest,
+			messages: []*outboundMessage{update("a", 1, true), update("a", 2, true), update("a", 3, false)},
+			errors:   []error{nil, nil, nil},
+			queued:   []uint64{2, 3},
+			stats:    service.SendStats{Queued: 2, Dropped: 1},
+		},
+		{
+			name:     "drop oldest keeps control messages",
+			policy:   OverflowDropOldest,
+			messages: []*outboundMessage{{response: &pb.SubscribeResponse{}}, update("a", 1, true), update("a", 2, false), update("a", 3, true)},
+			errors:   []error{nil, nil, service.ErrSnapshotRequired, nil},
+			queued:   []uint64{0, 3},
+			stats:    service.SendStats{Queued: 2, Dropped: 1},
+		},
+		{
+			name:     "control message makes room by dropping an update",
+			policy:   OverflowCoalesce,
+			messages: []*outboundMessage{update("a", 1, true), update("b", 1, true), {response: &pb.SubscribeResponse{}}},
+			errors:   []error{nil, nil, nil},
+			queued:   []uint64{1, 0},
+			stats:    service.SendStats{Queued: 2, Dropped: 1},
+		},
+		{
+			name:     "control messages are bounded",
+			policy:   OverflowDropOldest,
+			messages: []*outboundMessage{{response: &pb.SubscribeResponse{}}, {response: &pb.SubscribeResponse{}}, {response: &pb.SubscribeResponse{}}},
+			errors:   []error{nil, nil, service.ErrSlowConsumer},
+			stats:    service.SendStats{},
+			failed:   service.ErrSlowConsumer,
+		},
+		{
+			name:     "coalesce delta",
+			policy:   OverflowCoalesce,
+			messages: []*outboundMessage{update("a", 1, true), update("a", 2, false), update("a", 3, false)},
+			errors:   []error{nil, nil, service.ErrSnapshotRequired},
+			stats:    service.SendStats{Coalesced: 3},
+		},
+		{
+			name:     "coalesce to snapshot",
+			policy:   OverflowCoalesce,
+			messages: []*outboundMessage{update("a", 1, true), update("b", 1, true), update("a", 2, true)},
+			errors:   []error{nil, nil, nil},
+			queued:   []uint64{1, 2},
+			stats:    service.SendStats{Queued: 2, Coalesced: 1},
+		},
+		{
+			name:     "coalesce snapshot into full buffer of other subscriptions",
+			policy:   OverflowCoalesce,
+			messages: []*outboundMessage{update("a", 1, true), update("b", 1, true), update("c", 1, true)},
+			errors:   []error{nil, nil, nil},
+			queued:   []uint64{1, 1},
+			stats:    service.SendStats{Queued: 2, Dropped: 1},
+		},
+		{
+			name:     "disconnect",
+			policy:   OverflowDisconnect,
+			messages: []*outboundMessage{update("a", 1, true), update("a", 2, false), update("a", 3, false), update("a", 4, false)},
+			errors:   []error{nil, nil, service.ErrSlowConsumer, errSenderClosed},
+			stats:    service.SendStats{},
+			failed:   service.ErrSlowConsumer,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			buffer := newSendBuffer(BufferConfig{Size: 2, Policy: test.policy})
+			var errors []error
+			for _, msg := range test.messages {
+				errors = append(errors, buffer.push(msg))
+			}
+			assert.Equal(t, test.errors, errors)
+			var queued []uint64
+			for _, msg := range buffer.queue {
+				if msg.update == nil {
+					queued = append(queued, 0)
+					continue
+				}
+				queued = append(queued, msg.update.Sequence)
+			}
+			assert.Equal(t, test.queued, queued)
+			assert.Equal(t, test.stats, buffer.stats())
+			select {
+			case err := <-buffer.failed:
+				assert.Equal(t, test.failed, err)
+			default:
+				assert.Nil(t, test.failed)
+			}
+		})
+	}
+}
+
+func TestSendBufferPopAfterShutdown(t *testing.T) {
+	buffer := newSendBuffer(BufferConfig{Size: 2, Policy: OverflowDropOldest})
+	assert.Nil(t, buffer.push(update("a", 1, true)))
+
+	msg, ok := buffer.pop()
+	assert.True(t, ok)
+	assert.Equal(t, uint64(1), msg.update.Sequence)
+
+	buffer.shutdown()
+	_, ok = buffer.pop()
+	assert.False(t, ok)
+	assert.Equal(t, errSenderClosed, buffer.push(update("a", 2, false)))
+}
+
+func TestSendBufferDrain(t *testing.T) {
+	buffer := newSendBuffer(BufferConfig{Size: 2, Policy: OverflowDropOldest})
+	assert.Nil(t, buffer.push(update("a", 1, true)))
+	assert.Nil(t, buffer.push(update("a", 2, false)))
+
+	buffer.drain()
+	assert.Equal(t, errSenderClosed, buffer.push(update("a", 3, false)))
+	for _, sequence := range []uint64{1, 2} {
+		msg, ok := buffer.pop()
+		assert.True(t, ok)
+		assert.Equal(t, sequence, msg.update.Sequence)
+	}
+	_, ok := buffer.pop()
+	assert.False(t, ok)
+}
+
+func TestSendBufferUndelivered(t *testing.T) {
+	buffer := newSendBuffer(BufferConfig{Size: 2, Policy: OverflowDropOldest})
+	assert.Nil(t, buffer.push(update("a", 1, true)))
+	assert.Nil(t, buffer.push(update("b", 1, true)))
+	assert.Nil(t, buffer.push(update("c", 1, true)))
+	assert.Equal(t, []string{"a", "b", "c"}, buffer.undelivered())
+
+	msg, ok := buffer.pop()
+	assert.True(t, ok)
+	assert.Equal(t, "b", msg.update.SubscriptionId)
+	buffer.shutdown()
+	assert.Equal(t, []string{"a", "c"}, buffer.undelivered())
+}
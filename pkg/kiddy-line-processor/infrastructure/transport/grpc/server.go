@@ -4,92 +4,426 @@ import (
 	"github.com/col3name/lines/pkg/common/application/logger"
 	commonDomain "github.com/col3name/lines/pkg/common/domain"
 	"github.com/col3name/lines/pkg/common/infrastructure/util/array"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/application/service"
 	"github.com/col3name/lines/pkg/kiddy-line-processor/application/service/sport-line"
 	"github.com/col3name/lines/pkg/kiddy-line-processor/application/service/subscription"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/domain/model"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/domain/query"
 	pb "github.com/col3name/lines/pkg/kiddy-line-processor/infrastructure/transport/grpc/proto"
+	"github.com/shopspring/decimal"
+	"google.golang.org/grpc/metadata"
 	"io"
-	"math/rand"
+	"sort"
+	"strconv"
+	"sync"
 	"time"
 )
 
+type IntervalBounds struct {
+	Min time.Duration
+	Max time.Duration
+}
+
+func (b IntervalBounds) contains(interval time.Duration) bool {
+	return interval >= b.Min && (b.Max <= 0 || interval <= b.Max)
+}
+
 type Server struct {
 	pb.UnimplementedKiddyLineProcessorServer
-	subscriptionManager subscription.Service
-	logger              logger.Logger
+	subscriptionManager   subscription.Service
+	intervalBounds        IntervalBounds
+	sendBuffer            BufferConfig
+	adminToken            string
+	candleService         sport_line.CandleService
+	sportLineQueryService query.SportLineQueryService
+	sportRegistry         commonDomain.SportRegistry
+	logger                logger.Logger
+	shutdown              chan struct{}
+	shutdownOnce          sync.Once
 }
 
-func NewServer(sportLineService sport_line.SportLineService, logger logger.Logger) *Server {
+func NewServer(
+	sportLineService sport_line.SportLineService,
+	candleService sport_line.CandleService,
+	sportLineQueryService query.SportLineQueryService,
+	lineChanges service.LineChangeSubscriber,
+	sessionGracePeriod time.Duration,
+	intervalBounds IntervalBounds,
+	sendBuffer BufferConfig,
+	adminToken string,
+	sportRegistry commonDomain.SportRegistry,
+	logger logger.Logger,
+) *Server {
 	return &Server{
-		subscriptionManager: subscription.NewSubscriptionManager(sportLineService, logger),
-		logger:              logger,
+		subscriptionManager:   subscription.NewSubscriptionManager(sportLineService, lineChanges, sessionGracePeriod, logger),
+		intervalBounds:        intervalBounds,
+		sendBuffer:            sendBuffer,
+		adminToken:            adminToken,
+		candleService:         candleService,
+		sportLineQueryService: sportLineQueryService,
+		sportRegistry:         sportRegistry,
+		logger:                logger,
+		shutdown:              make(chan struct{}),
+	}
+}
+
+func (s *Server) Shutdown() {
+	s.shutdownOnce.Do(func() {
+		close(s.shutdown)
+	})
+}
+
+func (s *Server) isShuttingDown() bool {
+	select {
+	case <-s.shutdown:
+		return true
+	default:
+		return false
 	}
 }
 
 func (s *Server) SubscribeOnSportsLines(stream pb.KiddyLineProcessor_SubscribeOnSportsLinesServer) error {
-	errorsCh := make(chan error)
-	clientUniqueCode := rand.Intn(1e6)
+	if s.isShuttingDown() {
+		return toStatusError(errServerShuttingDown)
+	}
+	errorsCh := make(chan error, 1)
+	sender := NewResponseSender(stream, s.sendBuffer)
+	sessionId, resumed, err := s.connectSession(stream.Context(), sender)
+	if err != nil {
+		return toStatusError(err)
+	}
+	defer s.subscriptionManager.Disconnect(sessionId)
+
+	// Messages queued by a resumed session stay in the buffer until the header is out.
+	err = stream.SendHeader(metadata.Pairs(SessionIdHeader, sessionId, SessionResumedHeader, strconv.FormatBool(resumed)))
+	if err != nil {
+		sender.Close()
+		return err
+	}
+	senderDone := make(chan struct{})
+	go func() {
+		sender.Run()
+		close(senderDone)
+	}()
+	defer func() {
+		sender.Close()
+		<-senderDone
+	}()
 
-	go s.receiveSubscriptions(stream, clientUniqueCode, errorsCh)
-	go s.sendDataToSubscribers(stream, clientUniqueCode)
+	go s.receiveSubscriptions(stream, sender, sessionId, errorsCh)
 
-	return <-errorsCh
+	select {
+	case err := <-errorsCh:
+		return err
+	case err := <-sender.Failed():
+		s.logger.Println("Error in sending message to client :: ", err)
+		return toStatusError(err)
+	case <-s.shutdown:
+		if err = sender.GoAway(errServerShuttingDown.Error()); err == nil {
+			select {
+			case <-senderDone:
+			case <-stream.Context().Done():
+			}
+		}
+		return toStatusError(errServerShuttingDown)
+	case <-stream.Context().Done():
+		return stream.Context().Err()
+	}
 }
 
-func (s *Server) receiveSubscriptions(stream pb.KiddyLineProcessor_SubscribeOnSportsLinesServer, clientId int, errCh chan error) {
+func (s *Server) receiveSubscriptions(stream pb.KiddyLineProcessor_SubscribeOnSportsLinesServer, sender *ResponseSenderGrpc, clientId string, errCh chan error) {
 	for {
 		in, err := stream.Recv()
 		if err == io.EOF {
 			s.logger.Println(err)
-			s.subscriptionManager.Unsubscribe(clientId)
-			errCh <- err
+			errCh <- nil
+			return
 		}
 		if err != nil {
 			s.logger.Println("Error in receiving message from client :: ", err)
 			errCh <- err
-			s.subscriptionManager.Unsubscribe(clientId)
-			continue
+			return
 		}
-		if in.IntervalInSecond < 1 || array.Empty(in.Sports) {
-			s.logger.Println("Error in receiving message from client. interval must be positive number :: ", err)
+		if err = s.handleSubscribeRequest(sender, clientId, in); err != nil {
+			s.logger.Println("Error in sending message to client :: ", err)
 			errCh <- err
-			continue
+			return
 		}
-		sportsList := s.parseSportRequest(in.Sports)
-		if array.EmptyST(sportsList) {
-			s.logger.Println("Error in receiving message from client. :: ")
-			errCh <- err
-			continue
+	}
+}
+
+func (s *Server) handleSubscribeRequest(sender *ResponseSenderGrpc, clientId string, in *pb.SubscribeRequest) error {
+	msg, rejections := s.parseSubscribeRequest(clientId, in)
+	for _, rejection := range rejections {
+		rejection.SubscriptionId = in.SubscriptionId
+		if err := sender.SendError(rejection); err != nil {
+			return err
 		}
-		s.subscriptionManager.PushMessage(&subscription.MessageToSubscribeDTO{
-			ClientId:             clientId,
-			Sports:               sportsList,
-			UpdateIntervalSecond: in.IntervalInSecond,
+	}
+	if msg == nil {
+		return nil
+	}
+	if !s.subscriptionManager.PushMessage(msg) {
+		return sender.SendError(&pb.SubscriptionError{
+			Code:           pb.ErrorCode_NOT_ACCEPTED,
+			SubscriptionId: in.SubscriptionId,
+			Message:        "subscription was not accepted",
 		})
 	}
+	return nil
 }
 
-func (s *Server) parseSportRequest(sports []string) []commonDomain.SportType {
-	result := make([]commonDomain.SportType, 3)
+func (s *Server) isResyncRequest(in *pb.SubscribeRequest) bool {
+	return array.Empty(in.Sports) && array.Empty(in.EventIds) && (in.Snapshot || in.ReplayFrom > 0)
+}
+
+func (s *Server) parseSubscribeRequest(clientId string, in *pb.SubscribeRequest) (*subscription.MessageToSubscribeDTO, []*pb.SubscriptionError) {
+	switch in.Operation {
+	case pb.Operation_UNSUBSCRIBE:
+		return &subscription.MessageToSubscribeDTO{
+			ClientId:       clientId,
+			SubscriptionId: in.SubscriptionId,
+			Operation:      subscription.OperationUnsubscribe,
+		}, nil
+	case pb.Operation_RESET_BASELINE:
+		return &subscription.MessageToSubscribeDTO{
+			ClientId:       clientId,
+			SubscriptionId: in.SubscriptionId,
+			Operation:      subscription.OperationResetBaseline,
+		}, nil
+	case pb.Operation_ADD_SPORTS:
+		sports, rejectedSports := s.parseSportRequest(in.Sports)
+		msg, rejections := s.parseSportsOperation(clientId, in.SubscriptionId, subscription.OperationAddSports, sports, rejectedSports)
+		if msg != nil {
+			var intervalRejection *pb.SubscriptionError
+			msg.SportIntervals, intervalRejection = s.parseSportIntervals(in.SportIntervalsMs, sports)
+			if intervalRejection != nil {
+				rejections = append(rejections, intervalRejection)
+			}
+		}
+		return msg, rejections
+	case pb.Operation_REMOVE_SPORTS:
+		sports, rejectedSports := s.parseKnownSportRequest(in.Sports)
+		return s.parseSportsOperation(clientId, in.SubscriptionId, subscription.OperationRemoveSports, sports, rejectedSports)
+	}
+	if s.isResyncRequest(in) {
+		return &subscription.MessageToSubscribeDTO{
+			ClientId:       clientId,
+			SubscriptionId: in.SubscriptionId,
+			Snapshot:       in.Snapshot,
+			ReplayFrom:     in.ReplayFrom,
+		}, nil
+	}
+
+	deliveryMode, err := model.NewDeliveryMode(in.DeliveryMode)
+	if err != nil {
+		return nil, []*pb.SubscriptionError{rejection(pb.ErrorCode_INVALID_DELIVERY_MODE, "deliveryMode", err, in.DeliveryMode)}
+	}
+	interval, intervalRejection := s.parseInterval(in)
+	if deliveryMode == model.DeliveryInterval && intervalRejection != nil {
+		return nil, []*pb.SubscriptionError{intervalRejection}
+	}
+	oddsFormat, err := commonDomain.NewOddsFormat(in.OddsFormat)
+	if err != nil {
+		return nil, []*pb.SubscriptionError{rejection(pb.ErrorCode_INVALID_ODDS_FORMAT, "oddsFormat", err, in.OddsFormat)}
+	}
+	delta, deltaRejection := parseDeltaPolicy(in)
+	if deltaRejection != nil {
+		return nil, []*pb.SubscriptionError{deltaRejection}
+	}
+
+	var rejections []*pb.SubscriptionError
+	sports, rejectedSports := s.parseSportRequest(in.Sports)
+	if len(rejectedSports) > 0 {
+		rejections = append(rejections, rejection(pb.ErrorCode_UNSUPPORTED_SPORT, "sports", commonDomain.ErrUnsupportedSportType, rejectedSports...))
+	}
+	markets, rejectedMarkets := s.parseMarketRequest(in.Markets)
+	if len(rejectedMarkets) > 0 {
+		rejections = append(rejections, rejection(pb.ErrorCode_UNSUPPORTED_MARKET, "markets", commonDomain.ErrUnsupportedMarketType, rejectedMarkets...))
+	}
+	eventIDs := s.parseEventRequest(in.EventIds)
+	if array.EmptyST(sports) && array.Empty(eventIDs) {
+		if len(rejections) > 0 {
+			return nil, rejections
+		}
+		return nil, []*pb.SubscriptionError{rejection(pb.ErrorCode_EMPTY_SUBSCRIPTION, "sports", subscription.ErrEmptySubscription)}
+	}
+	sportIntervals, intervalRejection := s.parseSportIntervals(in.SportIntervalsMs, sports)
+	if intervalRejection != nil {
+		rejections = append(rejections, intervalRejection)
+	}
+
+	return &subscription.MessageToSubscribeDTO{
+		ClientId:       clientId,
+		SubscriptionId: in.SubscriptionId,
+		Operation:      subscription.OperationSubscribe,
+		Sports:         sports,
+		Markets:        markets,
+		EventIDs:       eventIDs,
+		OddsFormat:     oddsFormat,
+		UpdateInterval: interval,
+		SportIntervals: sportIntervals,
+		DeliveryMode:   deliveryMode,
+		Delta:          delta,
+		Snapshot:       in.Snapshot,
+		ReplayFrom:     in.ReplayFrom,
+	}, rejections
+}
+
+func (s *Server) parseInterval(in *pb.SubscribeRequest) (time.Duration, *pb.SubscriptionError) {
+	if in.IntervalMs != 0 {
+		interval := time.Duration(in.IntervalMs) * time.Millisecond
+		return interval, s.checkInterval(interval, "intervalMs", strconv.FormatInt(in.IntervalMs, 10))
+	}
+	interval := time.Duration(in.IntervalInSecond) * time.Second
+	return interval, s.checkInterval(interval, "intervalInSecond", strconv.Itoa(int(in.IntervalInSecond)))
+}
+
+func (s *Server) checkInterval(interval time.Duration, field string, rejected string) *pb.SubscriptionError {
+	if interval <= 0 {
+		return rejection(pb.ErrorCode_INVALID_INTERVAL, field, errIntervalNotPositive, rejected)
+	}
+	if !s.intervalBounds.contains(interval) {
+		return rejection(pb.ErrorCode_INVALID_INTERVAL, field, errIntervalOutOfBounds, rejected)
+	}
+	return nil
+}
+
+func parseDeltaPolicy(in *pb.SubscribeRequest) (model.DeltaPolicy, *pb.SubscriptionError) {
+	mode, err := model.NewDeltaMode(in.DeltaMode)
+	if err != nil {
+		return nil, rejection(pb.ErrorCode_INVALID_DELTA_MODE, "deltaMode", err, in.DeltaMode)
+	}
+	threshold := decimal.Zero
+	if in.DeltaThreshold != "" {
+		threshold, err = decimal.NewFromString(in.DeltaThreshold)
+		if err != nil {
+			return nil, rejection(pb.ErrorCode_INVALID_DELTA_MODE, "deltaThreshold", model.ErrInvalidDeltaThreshold, in.DeltaThreshold)
+		}
+	}
+	policy, err := model.NewDeltaPolicy(mode, threshold)
+	if err != nil {
+		return nil, rejection(pb.ErrorCode_INVALID_DELTA_MODE, "deltaThreshold", err, in.DeltaThreshold)
+	}
+	return policy, nil
+}
+
+func (s *Server) parseSportIntervals(intervalsMs map[string]int64, sports []commonDomain.SportType) (model.SportIntervalMap, *pb.SubscriptionError) {
+	result := make(model.SportIntervalMap, len(intervalsMs))
+	var rejected []string
+
+	for sport, intervalMs := range intervalsMs {
+		sportType, err := commonDomain.ParseSportType(sport)
+		interval := time.Duration(intervalMs) * time.Millisecond
+		if err != nil || !containsSport(sports, sportType) || interval <= 0 || !s.intervalBounds.contains(interval) {
+			rejected = append(rejected, sport)
+			continue
+		}
+		result[sportType] = interval
+	}
+	if len(rejected) == 0 {
+		return result, nil
+	}
+	sort.Strings(rejected)
+	return result, rejection(pb.ErrorCode_INVALID_INTERVAL, "sportIntervalsMs", errSportIntervalRejected, rejected...)
+}
+
+func containsSport(sports []commonDomain.SportType, sportType commonDomain.SportType) bool {
+	for _, item := range sports {
+		if item == sportType {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) parseSportsOperation(
+	clientId, subscriptionId string,
+	operation subscription.Operation,
+	sports []commonDomain.SportType,
+	rejectedSports []string,
+) (*subscription.MessageToSubscribeDTO, []*pb.SubscriptionError) {
+	var rejections []*pb.SubscriptionError
+	if len(rejectedSports) > 0 {
+		rejections = append(rejections, rejection(pb.ErrorCode_UNSUPPORTED_SPORT, "sports", commonDomain.ErrUnsupportedSportType, rejectedSports...))
+	}
+	if array.EmptyST(sports) {
+		if len(rejections) > 0 {
+			return nil, rejections
+		}
+		return nil, []*pb.SubscriptionError{rejection(pb.ErrorCode_EMPTY_SUBSCRIPTION, "sports", subscription.ErrEmptySubscription)}
+	}
+	return &subscription.MessageToSubscribeDTO{
+		ClientId:       clientId,
+		SubscriptionId: subscriptionId,
+		Operation:      operation,
+		Sports:         sports,
+	}, rejections
+}
+
+func rejection(code pb.ErrorCode, field string, err error, rejected ...string) *pb.SubscriptionError {
+	return &pb.SubscriptionError{Code: code, Field: field, Rejected: rejected, Message: err.Error()}
+}
+
+func (s *Server) parseSportRequest(sports []string) ([]commonDomain.SportType, []string) {
+	result := make([]commonDomain.SportType, 0, len(sports))
+	var rejected []string
 
 	for _, sportType := range sports {
-		val, err := commonDomain.NewSportType(sportType)
-		if err == nil {
-			result = append(result, val)
+		val, err := commonDomain.NewSportType(sportType, s.sportRegistry)
+		if err != nil {
+			rejected = append(rejected, sportType)
+			continue
 		}
+		result = append(result, val)
 	}
 
-	return result
+	return result, rejected
 }
 
-func (s *Server) sendDataToSubscribers(stream pb.KiddyLineProcessor_SubscribeOnSportsLinesServer, clientId int) {
-	for {
-		for {
-			sender := &ResponseSenderGrpc{Stream: stream}
-			ok := s.subscriptionManager.Subscribe(sender, clientId)
-			if !ok {
-				break
-			}
+func (s *Server) parseKnownSportRequest(sports []string) ([]commonDomain.SportType, []string) {
+	result := make([]commonDomain.SportType, 0, len(sports))
+	var rejected []string
+
+	for _, sport := range sports {
+		sportType, err := commonDomain.ParseSportType(sport)
+		if err != nil {
+			rejected = append(rejected, sport)
+			continue
 		}
-		time.Sleep(100 * time.Millisecond)
+		result = append(result, sportType)
 	}
+
+	return result, rejected
+}
+
+func (s *Server) parseMarketRequest(markets []string) ([]commonDomain.MarketType, []string) {
+	result := make([]commonDomain.MarketType, 0, len(markets))
+	var rejected []string
+
+	for _, market := range markets {
+		val, err := commonDomain.NewMarketType(market)
+		if err != nil {
+			rejected = append(rejected, market)
+			continue
+		}
+		result = append(result, val)
+	}
+
+	return result, rejected
+}
+
+func (s *Server) parseEventRequest(eventIDs []string) []string {
+	result := make([]string, 0, len(eventIDs))
+	seen := make(map[string]struct{}, len(eventIDs))
+
+	for _, id := range eventIDs {
+		if _, ok := seen[id]; ok || len(id) == 0 {
+			continue
+		}
+		seen[id] = struct{}{}
+		result = append(result, id)
+	}
+
+	return result
 }
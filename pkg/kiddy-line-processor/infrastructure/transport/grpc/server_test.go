@@ -0,0 +1,379 @@
+package grpc
+
+import (
+	"context"
+	commonDomain "github.com/col3name/lines/pkg/common/domain"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/application/fake"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/application/service"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/application/service/line-change"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/application/service/subscription"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/domain/model"
+	pb "github.com/col3name/lines/pkg/kiddy-line-processor/infrastructure/transport/grpc/proto"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"strings"
+	"testing"
+	"time"
+)
+
+type mockSportRegistry struct{}
+
+func (m *mockSportRegistry) IsSupported(sportType commonDomain.SportType) bool {
+	return sportType == commonDomain.Soccer || sportType == commonDomain.Baseball
+}
+
+func (m *mockSportRegistry) Sports() []commonDomain.SportType {
+	return []commonDomain.SportType{commonDomain.Baseball, commonDomain.Soccer}
+}
+
+func TestParseSubscribeRequest(t *testing.T) {
+	tests := []struct {
+		name              string
+		input             *pb.SubscribeRequest
+		expectedOperation subscription.Operation
+		expectedSports    []commonDomain.SportType
+		expectedInterval  time.Duration
+		expectedIntervals model.SportIntervalMap
+		expectedCodes     []pb.ErrorCode
+		rejected          []string
+	}{
+		{
+			name:              "valid request",
+			input:             &pb.SubscribeRequest{Sports: []string{"soccer"}, IntervalInSecond: 1},
+			expectedOperation: subscription.OperationSubscribe,
+			expectedSports:    []commonDomain.SportType{commonDomain.Soccer},
+		},
+		{
+			name:          "interval < 1",
+			input:         &pb.SubscribeRequest{Sports: []string{"soccer"}},
+			expectedCodes: []pb.ErrorCode{pb.ErrorCode_INVALID_INTERVAL},
+			rejected:      []string{"0"},
+		},
+		{
+			name:              "millisecond interval",
+			input:             &pb.SubscribeRequest{Sports: []string{"soccer"}, IntervalInSecond: 5, IntervalMs: 250},
+			expectedOperation: subscription.OperationSubscribe,
+			expectedSports:    []commonDomain.SportType{commonDomain.Soccer},
+			expectedInterval:  250 * time.Millisecond,
+		},
+		{
+			name:          "interval below minimum",
+			input:         &pb.SubscribeRequest{Sports: []string{"soccer"}, IntervalMs: 50},
+			expectedCodes: []pb.ErrorCode{pb.ErrorCode_INVALID_INTERVAL},
+			rejected:      []string{"50"},
+		},
+		{
+			name:          "interval above maximum",
+			input:         &pb.SubscribeRequest{Sports: []string{"soccer"}, IntervalInSecond: 7200},
+			expectedCodes: []pb.ErrorCode{pb.ErrorCode_INVALID_INTERVAL},
+			rejected:      []string{"7200"},
+		},
+		{
+			name: "per sport intervals",
+			input: &pb.SubscribeRequest{
+				Sports:           []string{"soccer", "baseball"},
+				IntervalMs:       1000,
+				SportIntervalsMs: map[string]int64{"soccer": 200, "baseball": 10, "curling": 500},
+			},
+			expectedOperation: subscription.OperationSubscribe,
+			expectedSports:    []commonDomain.SportType{commonDomain.Soccer, commonDomain.Baseball},
+			expectedInterval:  time.Second,
+			expectedIntervals: model.SportIntervalMap{commonDomain.Soccer: 200 * time.Millisecond},
+			expectedCodes:     []pb.ErrorCode{pb.ErrorCode_INVALID_INTERVAL},
+			rejected:          []string{"baseball", "curling"},
+		},
+		{
+			name: "add sports with interval",
+			input: &pb.SubscribeRequest{
+				SubscriptionId:   "a",
+				Operation:        pb.Operation_ADD_SPORTS,
+				Sports:           []string{"baseball"},
+				SportIntervalsMs: map[string]int64{"baseball": 300},
+			},
+			expectedOperation: subscription.OperationAddSports,
+			expectedSports:    []commonDomain.SportType{commonDomain.Baseball},
+			expectedIntervals: model.SportIntervalMap{commonDomain.Baseball: 300 * time.Millisecond},
+		},
+		{
+			name:              "threshold delta mode",
+			input:             &pb.SubscribeRequest{Sports: []string{"soccer"}, IntervalInSecond: 1, DeltaMode: "threshold", DeltaThreshold: "0.05"},
+			expectedOperation: subscription.OperationSubscribe,
+			expectedSports:    []commonDomain.SportType{commonDomain.Soccer},
+		},
+		{
+			name:          "unknown delta mode",
+			input:         &pb.SubscribeRequest{Sports: []string{"soccer"}, IntervalInSecond: 1, DeltaMode: "ratio"},
+			expectedCodes: []pb.ErrorCode{pb.ErrorCode_INVALID_DELTA_MODE},
+			rejected:      []string{"ratio"},
+		},
+		{
+			name:          "threshold delta mode without threshold",
+			input:         &pb.SubscribeRequest{Sports: []string{"soccer"}, IntervalInSecond: 1, DeltaMode: "threshold"},
+			expectedCodes: []pb.ErrorCode{pb.ErrorCode_INVALID_DELTA_MODE},
+			rejected:      []string{""},
+		},
+		{
+			name:          "malformed delta threshold",
+			input:         &pb.SubscribeRequest{Sports: []string{"soccer"}, IntervalInSecond: 1, DeltaMode: "threshold", DeltaThreshold: "five"},
+			expectedCodes: []pb.ErrorCode{pb.ErrorCode_INVALID_DELTA_MODE},
+			rejected:      []string{"five"},
+		},
+		{
+			name:              "on change without interval",
+			input:             &pb.SubscribeRequest{Sports: []string{"soccer"}, DeliveryMode: model.DeliveryOnChange.String()},
+			expectedOperation: subscription.OperationSubscribe,
+			expectedSports:    []commonDomain.SportType{commonDomain.Soccer},
+		},
+		{
+			name:          "unknown delivery mode",
+			input:         &pb.SubscribeRequest{Sports: []string{"soccer"}, DeliveryMode: "push"},
+			expectedCodes: []pb.ErrorCode{pb.ErrorCode_INVALID_DELIVERY_MODE},
+			rejected:      []string{"push"},
+		},
+		{
+			name:          "unknown odds format",
+			input:         &pb.SubscribeRequest{Sports: []string{"soccer"}, IntervalInSecond: 1, OddsFormat: "roman"},
+			expectedCodes: []pb.ErrorCode{pb.ErrorCode_INVALID_ODDS_FORMAT},
+			rejected:      []string{"roman"},
+		},
+		{
+			name:              "partially unsupported sports",
+			input:             &pb.SubscribeRequest{Sports: []string{"soccer", "curling"}, IntervalInSecond: 1},
+			expectedOperation: subscription.OperationSubscribe,
+			expectedSports:    []commonDomain.SportType{commonDomain.Soccer},
+			expectedCodes:     []pb.ErrorCode{pb.ErrorCode_UNSUPPORTED_SPORT},
+			rejected:          []string{"curling"},
+		},
+		{
+			name:          "only unsupported sports",
+			input:         &pb.SubscribeRequest{Sports: []string{"curling"}, IntervalInSecond: 1},
+			expectedCodes: []pb.ErrorCode{pb.ErrorCode_UNSUPPORTED_SPORT},
+			rejected:      []string{"curling"},
+		},
+		{
+			name:              "unsupported market",
+			input:             &pb.SubscribeRequest{Sports: []string{"soccer"}, Markets: []string{"corners"}, IntervalInSecond: 1},
+			expectedOperation: subscription.OperationSubscribe,
+			expectedSports:    []commonDomain.SportType{commonDomain.Soccer},
+			expectedCodes:     []pb.ErrorCode{pb.ErrorCode_UNSUPPORTED_MARKET},
+			rejected:          []string{"corners"},
+		},
+		{
+			name:              "add sports",
+			input:             &pb.SubscribeRequest{SubscriptionId: "a", Operation: pb.Operation_ADD_SPORTS, Sports: []string{"baseball", "curling"}},
+			expectedOperation: subscription.OperationAddSports,
+			expectedSports:    []commonDomain.SportType{commonDomain.Baseball},
+			expectedCodes:     []pb.ErrorCode{pb.ErrorCode_UNSUPPORTED_SPORT},
+			rejected:          []string{"curling"},
+		},
+		{
+			name:          "add only unsupported sports",
+			input:         &pb.SubscribeRequest{SubscriptionId: "a", Operation: pb.Operation_ADD_SPORTS, Sports: []string{"curling"}},
+			expectedCodes: []pb.ErrorCode{pb.ErrorCode_UNSUPPORTED_SPORT},
+			rejected:      []string{"curling"},
+		},
+		{
+			name:              "remove sport missing in registry",
+			input:             &pb.SubscribeRequest{SubscriptionId: "a", Operation: pb.Operation_REMOVE_SPORTS, Sports: []string{"curling"}},
+			expectedOperation: subscription.OperationRemoveSports,
+			expectedSports:    []commonDomain.SportType{"curling"},
+		},
+		{
+			name:          "remove without sports",
+			input:         &pb.SubscribeRequest{SubscriptionId: "a", Operation: pb.Operation_REMOVE_SPORTS},
+			expectedCodes: []pb.ErrorCode{pb.ErrorCode_EMPTY_SUBSCRIPTION},
+		},
+		{
+			name:              "unsubscribe",
+			input:             &pb.SubscribeRequest{SubscriptionId: "a", Operation: pb.Operation_UNSUBSCRIBE},
+			expectedOperation: subscription.OperationUnsubscribe,
+		},
+		{
+			name:              "reset baseline",
+			input:             &pb.SubscribeRequest{SubscriptionId: "a", Operation: pb.Operation_RESET_BASELINE},
+			expectedOperation: subscription.OperationResetBaseline,
+		},
+		{
+			name:          "empty subscription",
+			input:         &pb.SubscribeRequest{IntervalInSecond: 1},
+			expectedCodes: []pb.ErrorCode{pb.ErrorCode_EMPTY_SUBSCRIPTION},
+		},
+	}
+	server := &Server{
+		intervalBounds: IntervalBounds{Min: 100 * time.Millisecond, Max: time.Hour},
+		sportRegistry:  &mockSportRegistry{},
+		logger:         &fake.Logger{},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			msg, rejections := server.parseSubscribeRequest("1", test.input)
+			if test.expectedOperation == "" {
+				assert.Nil(t, msg)
+			} else {
+				assert.Equal(t, "1", msg.ClientId)
+				assert.Equal(t, test.input.SubscriptionId, msg.SubscriptionId)
+				assert.Equal(t, test.expectedOperation, msg.Operation)
+				assert.Equal(t, test.expectedSports, msg.Sports)
+				if test.expectedInterval > 0 {
+					assert.Equal(t, test.expectedInterval, msg.UpdateInterval)
+				}
+				if test.expectedIntervals != nil {
+					assert.Equal(t, test.expectedIntervals, msg.SportIntervals)
+				}
+			}
+			var codes []pb.ErrorCode
+			var rejected []string
+			for _, rejection := range rejections {
+				codes = append(codes, rejection.Code)
+				rejected = append(rejected, rejection.Rejected...)
+				assert.NotEmpty(t, rejection.Message)
+			}
+			assert.Equal(t, test.expectedCodes, codes)
+			assert.Equal(t, test.rejected, rejected)
+		})
+	}
+}
+
+type fakeSubscribeStream struct {
+	grpc.ServerStream
+	ctx     context.Context
+	headers chan metadata.MD
+	sent    chan *pb.SubscribeResponse
+}
+
+func (f *fakeSubscribeStream) Context() context.Context {
+	return f.ctx
+}
+
+func (f *fakeSubscribeStream) SendHeader(md metadata.MD) error {
+	f.headers <- md
+	return nil
+}
+
+func (f *fakeSubscribeStream) Send(response *pb.SubscribeResponse) error {
+	f.sent <- response
+	return nil
+}
+
+func (f *fakeSubscribeStream) Recv() (*pb.SubscribeRequest, error) {
+	<-f.ctx.Done()
+	return nil, f.ctx.Err()
+}
+
+func TestShutdownSendsGoingAway(t *testing.T) {
+	server := NewServer(nil, nil, nil, line_change.NewLineChangeBus(), 0, IntervalBounds{}, BufferConfig{Size: 1}, "", &mockSportRegistry{}, &fake.Logger{})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream := &fakeSubscribeStream{ctx: ctx, headers: make(chan metadata.MD, 1), sent: make(chan *pb.SubscribeResponse, 1)}
+	result := make(chan error, 1)
+	go func() {
+		result <- server.SubscribeOnSportsLines(stream)
+	}()
+	<-stream.headers
+
+	server.Shutdown()
+	response := <-stream.sent
+	assert.Equal(t, errServerShuttingDown.Error(), response.GetGoingAway().GetReason())
+	assert.Equal(t, codes.Unavailable, status.Code(<-result))
+
+	assert.Equal(t, codes.Unavailable, status.Code(server.SubscribeOnSportsLines(stream)))
+	server.Shutdown()
+}
+
+type mockSubscriptionManager struct {
+	subscription.Service
+	detached  map[string]bool
+	connected []string
+}
+
+func (m *mockSubscriptionManager) Connect(clientId string, _ service.ResponseSenderService) (bool, error) {
+	m.connected = append(m.connected, clientId)
+	return false, nil
+}
+
+func (m *mockSubscriptionManager) Resume(clientId string, _ service.ResponseSenderService) error {
+	if !m.detached[clientId] {
+		return subscription.ErrUnknownSession
+	}
+	m.connected = append(m.connected, clientId)
+	return nil
+}
+
+func (m *mockSubscriptionManager) List() []*subscription.SubscriptionDTO {
+	return []*subscription.SubscriptionDTO{{ClientId: "session", SubscriptionId: "main"}}
+}
+
+func TestConnectSession(t *testing.T) {
+	tests := []struct {
+		name            string
+		requested       []string
+		expectedResumed bool
+		expectedErr     error
+	}{
+		{name: "no id", expectedResumed: false},
+		{name: "empty id", requested: []string{""}, expectedResumed: false},
+		{name: "unknown id", requested: []string{"chosen"}, expectedResumed: false},
+		{name: "detached session", requested: []string{"session"}, expectedResumed: true},
+		{name: "too long id", requested: []string{strings.Repeat("a", maxSessionIdLength+1)}, expectedErr: subscription.ErrInvalidClientId},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			manager := &mockSubscriptionManager{detached: map[string]bool{"session": true}}
+			server := &Server{subscriptionManager: manager}
+			ctx := context.Background()
+			for _, id := range test.requested {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(SessionIdHeader, id))
+			}
+
+			sessionId, resumed, err := server.connectSession(ctx, NewResponseSender(nil, BufferConfig{Size: 1}))
+			assert.Equal(t, test.expectedErr, err)
+			if err != nil {
+				assert.Empty(t, manager.connected)
+				return
+			}
+			assert.Equal(t, test.expectedResumed, resumed)
+			assert.Equal(t, []string{sessionId}, manager.connected)
+			if test.expectedResumed {
+				assert.Equal(t, test.requested[0], sessionId)
+			} else {
+				assert.Len(t, sessionId, 2*sessionIdSize)
+			}
+		})
+	}
+}
+
+func TestListSubscriptionsRequiresAdminToken(t *testing.T) {
+	tests := []struct {
+		name         string
+		adminToken   string
+		token        []string
+		expectedCode codes.Code
+	}{
+		{name: "admin api disabled", adminToken: "", token: []string{""}, expectedCode: codes.Unauthenticated},
+		{name: "missing token", adminToken: "secret", expectedCode: codes.Unauthenticated},
+		{name: "wrong token", adminToken: "secret", token: []string{"guess"}, expectedCode: codes.Unauthenticated},
+		{name: "valid token", adminToken: "secret", token: []string{"secret"}, expectedCode: codes.OK},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := &Server{subscriptionManager: &mockSubscriptionManager{}, adminToken: test.adminToken}
+			ctx := context.Background()
+			for _, token := range test.token {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(AdminTokenHeader, token))
+			}
+
+			response, err := server.ListSubscriptions(ctx, &pb.ListSubscriptionsRequest{})
+			assert.Equal(t, test.expectedCode, status.Code(err))
+			if test.expectedCode != codes.OK {
+				assert.Nil(t, response)
+				return
+			}
+			assert.Equal(t, 1, len(response.Subscriptions))
+			assert.Equal(t, publicSessionId("session"), response.Subscriptions[0].ClientId)
+			assert.NotContains(t, response.Subscriptions[0].ClientId, "session")
+		})
+	}
+}
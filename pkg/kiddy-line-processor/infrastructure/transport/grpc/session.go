@@ -0,0 +1,67 @@
+package grpc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/application/service"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/application/service/subscription"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	SessionIdHeader      = "x-session-id"
+	SessionResumedHeader = "x-session-resumed"
+
+	sessionIdSize       = 16
+	maxSessionIdLength  = 128
+	publicSessionIdSize = 12
+)
+
+// connectSession resumes the session named in the request metadata if the server still holds it.
+// Any other stream gets a new server-issued id, so a client can not choose the id of another session.
+func (s *Server) connectSession(ctx context.Context, sender service.ResponseSenderService) (string, bool, error) {
+	requested, err := requestedSessionId(ctx)
+	if err != nil {
+		return "", false, err
+	}
+	if len(requested) > 0 {
+		err = s.subscriptionManager.Resume(requested, sender)
+		if err != subscription.ErrUnknownSession {
+			return requested, err == nil, err
+		}
+	}
+	sessionId, err := newSessionId()
+	if err != nil {
+		return "", false, err
+	}
+	resumed, err := s.subscriptionManager.Connect(sessionId, sender)
+	return sessionId, resumed, err
+}
+
+func requestedSessionId(ctx context.Context) (string, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get(SessionIdHeader)
+	if len(values) == 0 {
+		return "", nil
+	}
+	if len(values[0]) > maxSessionIdLength {
+		return "", subscription.ErrInvalidClientId
+	}
+	return values[0], nil
+}
+
+func newSessionId() (string, error) {
+	buf := make([]byte, sessionIdSize)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// publicSessionId identifies a session in admin output without revealing the id that resumes it.
+func publicSessionId(sessionId string) string {
+	sum := sha256.Sum256([]byte(sessionId))
+	return hex.EncodeToString(sum[:])[:publicSessionIdSize]
+}
@@ -0,0 +1,58 @@
+package grpc
+
+import (
+	"context"
+	"crypto/subtle"
+	pb "github.com/col3name/lines/pkg/kiddy-line-processor/infrastructure/transport/grpc/proto"
+	"google.golang.org/grpc/metadata"
+	"time"
+)
+
+const AdminTokenHeader = "x-admin-token"
+
+func (s *Server) ListSubscriptions(ctx context.Context, _ *pb.ListSubscriptionsRequest) (*pb.ListSubscriptionsResponse, error) {
+	if !s.isAdmin(ctx) {
+		return nil, toStatusError(errAdminUnauthenticated)
+	}
+	subscriptions := s.subscriptionManager.List()
+
+	response := &pb.ListSubscriptionsResponse{Subscriptions: make([]*pb.Subscription, 0, len(subscriptions))}
+	for _, sub := range subscriptions {
+		item := &pb.Subscription{
+			ClientId:         publicSessionId(sub.ClientId),
+			SubscriptionId:   sub.SubscriptionId,
+			Connected:        sub.Connected,
+			IntervalInSecond: int32(sub.UpdateInterval / time.Second),
+			IntervalMs:       sub.UpdateInterval.Milliseconds(),
+			SportIntervalsMs: toIntervalsMs(sub.SportIntervals),
+			EventIds:         sub.EventIDs,
+			OddsFormat:       sub.OddsFormat.String(),
+			DeliveryMode:     sub.DeliveryMode.String(),
+			Queued:           int32(sub.SendStats.Queued),
+			Dropped:          sub.SendStats.Dropped,
+			Coalesced:        sub.SendStats.Coalesced,
+		}
+		item.DeltaMode, item.DeltaThreshold = toDelta(sub.Delta)
+		for _, sportType := range sub.Sports {
+			item.Sports = append(item.Sports, sportType.String())
+		}
+		for _, market := range sub.Markets {
+			item.Markets = append(item.Markets, market.String())
+		}
+		response.Subscriptions = append(response.Subscriptions, item)
+	}
+	return response, nil
+}
+
+// isAdmin reports whether the request carries the configured admin token. Without a token the admin API is closed.
+func (s *Server) isAdmin(ctx context.Context) bool {
+	if len(s.adminToken) == 0 {
+		return false
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get(AdminTokenHeader)
+	if len(values) == 0 {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(values[0]), []byte(s.adminToken)) == 1
+}
@@ -1,16 +1,280 @@
 package router
 
 import (
+	"encoding/json"
+	"expvar"
+	"github.com/col3name/lines/pkg/common/application/errors"
 	"github.com/col3name/lines/pkg/common/application/logger"
+	commonDomain "github.com/col3name/lines/pkg/common/domain"
 	httpUtil "github.com/col3name/lines/pkg/common/infrastructure/transport/http"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/application/service/sport-line"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/domain/model"
+	"github.com/col3name/lines/pkg/kiddy-line-processor/domain/query"
 	"github.com/gorilla/mux"
+	"github.com/shopspring/decimal"
 	"net/http"
+	"strconv"
+	"time"
 )
 
-func Router(logger logger.Logger) http.Handler {
+const defaultHistoryPageSize = 100
+
+func Router(
+	sportLineQueryService query.SportLineQueryService,
+	eventQueryService query.EventQueryService,
+	candleService sport_line.CandleService,
+	sportRegistry commonDomain.SportRegistry,
+	logger logger.Logger,
+) http.Handler {
+	controller := sportLineController{
+		sportLineQueryService: sportLineQueryService,
+		eventQueryService:     eventQueryService,
+		candleService:         candleService,
+		sportRegistry:         sportRegistry,
+		logger:                logger,
+	}
+
 	router := mux.NewRouter()
 
 	router.HandleFunc("/ready", httpUtil.ReadyCheckHandler)
+	router.Handle("/debug/vars", expvar.Handler())
+
+	apiV1Route := router.PathPrefix("/api/v1").Subrouter()
+	apiV1Route.HandleFunc("/lines", controller.getLinesAsOfHandler).Methods(http.MethodGet)
+	apiV1Route.HandleFunc("/lines/{sport}/history", controller.getLineHistoryHandler).Methods(http.MethodGet)
+	apiV1Route.HandleFunc("/lines/{sport}/candles", controller.getCandlesHandler).Methods(http.MethodGet)
+	apiV1Route.HandleFunc("/lines/{sport}/events", controller.getEventsHandler).Methods(http.MethodGet)
 
 	return httpUtil.LogMiddleware(router, logger)
 }
+
+type sportLineController struct {
+	sportLineQueryService query.SportLineQueryService
+	eventQueryService     query.EventQueryService
+	candleService         sport_line.CandleService
+	sportRegistry         commonDomain.SportRegistry
+	logger                logger.Logger
+}
+
+type sportLineResponse struct {
+	Sport string          `json:"sport"`
+	Line  decimal.Decimal `json:"line"`
+}
+
+type historyRecordResponse struct {
+	Sport      string          `json:"sport"`
+	Line       decimal.Decimal `json:"line"`
+	FetchedAt  time.Time       `json:"fetchedAt"`
+	IngestedAt time.Time       `json:"ingestedAt"`
+}
+
+type candleResponse struct {
+	Sport    string          `json:"sport"`
+	OpenTime time.Time       `json:"openTime"`
+	Open     decimal.Decimal `json:"open"`
+	High     decimal.Decimal `json:"high"`
+	Low      decimal.Decimal `json:"low"`
+	Close    decimal.Decimal `json:"close"`
+	Count    int             `json:"count"`
+}
+
+type eventResponse struct {
+	ID        string          `json:"id"`
+	Sport     string          `json:"sport"`
+	HomeTeam  string          `json:"homeTeam"`
+	AwayTeam  string          `json:"awayTeam"`
+	StartTime time.Time       `json:"startTime"`
+	Status    string          `json:"status"`
+	Line      decimal.Decimal `json:"line"`
+}
+
+type historyPageResponse struct {
+	Records    []historyRecordResponse `json:"records"`
+	NextCursor string                  `json:"nextCursor,omitempty"`
+}
+
+func (c *sportLineController) getLinesAsOfHandler(w http.ResponseWriter, req *http.Request) {
+	values := req.URL.Query()
+	sportTypes, err := c.parseSports(values["sport"])
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	asOf, err := parseTime(values.Get("asOf"), time.Now())
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	lines, err := c.sportLineQueryService.GetLinesAsOf(sportTypes, asOf)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	response := make([]sportLineResponse, 0, len(lines))
+	for _, line := range lines {
+		response = append(response, sportLineResponse{Sport: line.Type.String(), Line: line.Score})
+	}
+	c.writeResponse(w, response)
+}
+
+func (c *sportLineController) getLineHistoryHandler(w http.ResponseWriter, req *http.Request) {
+	sportType, err := commonDomain.NewSportType(mux.Vars(req)["sport"], c.sportRegistry)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	values := req.URL.Query()
+	to, err := parseTime(values.Get("to"), time.Now())
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	from, err := parseTime(values.Get("from"), to.Add(-time.Hour))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	limit, err := parseLimit(values.Get("limit"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	page, err := c.sportLineQueryService.GetLineHistory(sportType, from, to, limit, values.Get("cursor"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	c.writeResponse(w, toHistoryPageResponse(page))
+}
+
+func (c *sportLineController) getEventsHandler(w http.ResponseWriter, req *http.Request) {
+	sportType, err := commonDomain.NewSportType(mux.Vars(req)["sport"], c.sportRegistry)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	events, err := c.eventQueryService.GetEventsBySportType(sportType)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	response := make([]eventResponse, 0, len(events))
+	for _, event := range events {
+		response = append(response, eventResponse{
+			ID:        event.ID,
+			Sport:     event.SportType.String(),
+			HomeTeam:  event.HomeTeam,
+			AwayTeam:  event.AwayTeam,
+			StartTime: event.StartTime,
+			Status:    event.Status.String(),
+			Line:      event.Line,
+		})
+	}
+	c.writeResponse(w, response)
+}
+
+func (c *sportLineController) getCandlesHandler(w http.ResponseWriter, req *http.Request) {
+	sportType, err := commonDomain.NewSportType(mux.Vars(req)["sport"], c.sportRegistry)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	values := req.URL.Query()
+	bucket, err := time.ParseDuration(values.Get("bucket"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	to, err := parseTime(values.Get("to"), time.Now())
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	from, err := parseTime(values.Get("from"), to.Add(-time.Hour))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	candles, err := c.candleService.GetCandles(sportType, bucket, from, to)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	response := make([]candleResponse, 0, len(candles))
+	for _, candle := range candles {
+		response = append(response, candleResponse{
+			Sport:    candle.SportType.String(),
+			OpenTime: candle.OpenTime,
+			Open:     candle.Open,
+			High:     candle.High,
+			Low:      candle.Low,
+			Close:    candle.Close,
+			Count:    candle.Count,
+		})
+	}
+	c.writeResponse(w, response)
+}
+
+func (c *sportLineController) parseSports(sports []string) ([]commonDomain.SportType, error) {
+	if len(sports) == 0 {
+		return c.sportRegistry.Sports(), nil
+	}
+	result := make([]commonDomain.SportType, 0, len(sports))
+	for _, sport := range sports {
+		sportType, err := commonDomain.NewSportType(sport, c.sportRegistry)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, sportType)
+	}
+	return result, nil
+}
+
+func (c *sportLineController) writeResponse(w http.ResponseWriter, response interface{}) {
+	data, err := json.Marshal(response)
+	if err != nil {
+		c.logger.Error(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	httpUtil.WriteJSON(w, string(data))
+}
+
+func toHistoryPageResponse(page *model.SportLineHistoryPage) *historyPageResponse {
+	records := make([]historyRecordResponse, 0, len(page.Records))
+	for _, record := range page.Records {
+		records = append(records, historyRecordResponse{
+			Sport:      record.Line.Type.String(),
+			Line:       record.Line.Score,
+			FetchedAt:  record.FetchedAt,
+			IngestedAt: record.IngestedAt,
+		})
+	}
+	return &historyPageResponse{Records: records, NextCursor: page.NextCursor}
+}
+
+func parseTime(value string, defaultValue time.Time) (time.Time, error) {
+	if value == "" {
+		return defaultValue, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+func parseLimit(value string) (int, error) {
+	if value == "" {
+		return defaultHistoryPageSize, nil
+	}
+	return strconv.Atoi(value)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	if err == errors.ErrInvalidArgument {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusInternalServerError)
+}
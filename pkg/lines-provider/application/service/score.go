@@ -1,26 +1,119 @@
 package service
 
 import (
+	"fmt"
 	"github.com/col3name/lines/pkg/common/domain"
 	"github.com/col3name/lines/pkg/common/infrastructure/util/number"
+	"github.com/shopspring/decimal"
+	"math"
+	"time"
 )
 
+const (
+	eventsPerSport = 3
+	eventDuration  = 2 * time.Hour
+)
+
+var teams = []string{"Lions", "Tigers", "Bears", "Wolves", "Eagles", "Sharks"}
+
 type ScoreService interface {
-	GenerateScore(sportType string) (float64, error)
+	GenerateScore(sportType string) (decimal.Decimal, error)
+	GenerateMarkets(sportType string) ([]*domain.Market, error)
+	GenerateEvents(sportType string) ([]*domain.Event, error)
+}
+
+type scoreService struct {
+	sportRegistry domain.SportRegistry
+	startedAt     time.Time
+}
+
+func NewScoreService(sportRegistry domain.SportRegistry) ScoreService {
+	return &scoreService{sportRegistry: sportRegistry, startedAt: time.Now().Truncate(time.Hour)}
+}
+
+func (s *scoreService) GenerateScore(sportType string) (decimal.Decimal, error) {
+	_, err := domain.NewSportType(sportType, s.sportRegistry)
+	if err != nil {
+		return decimal.Zero, err
+	}
+
+	return randLine(), nil
 }
 
-type scoreService struct{}
+func (s *scoreService) GenerateMarkets(sportType string) ([]*domain.Market, error) {
+	_, err := domain.NewSportType(sportType, s.sportRegistry)
+	if err != nil {
+		return nil, err
+	}
 
-func NewScoreService() ScoreService {
-	return &scoreService{}
+	return []*domain.Market{
+		{
+			Type: domain.Moneyline,
+			Selections: []*domain.Selection{
+				{Name: "home", Price: randPrice(1.2, 4)},
+				{Name: "away", Price: randPrice(1.2, 4)},
+			},
+		},
+		{
+			Type:  domain.Spread,
+			Point: randPoint(-3, 3),
+			Selections: []*domain.Selection{
+				{Name: "home", Price: randPrice(1.8, 2.05)},
+				{Name: "away", Price: randPrice(1.8, 2.05)},
+			},
+		},
+		{
+			Type:  domain.Totals,
+			Point: randPoint(0.5, 5.5),
+			Selections: []*domain.Selection{
+				{Name: "over", Price: randPrice(1.8, 2.05)},
+				{Name: "under", Price: randPrice(1.8, 2.05)},
+			},
+		},
+	}, nil
 }
 
-func (s *scoreService) GenerateScore(sportType string) (float64, error) {
-	_, isSupported := domain.SupportSports[sportType]
-	if !isSupported {
-		return 0, domain.ErrUnsupportedSportType
+func (s *scoreService) GenerateEvents(sportType string) ([]*domain.Event, error) {
+	sport, err := domain.NewSportType(sportType, s.sportRegistry)
+	if err != nil {
+		return nil, err
 	}
-	score := number.RandFloat(0.5, 3)
 
-	return score, nil
+	now := time.Now()
+	events := make([]*domain.Event, 0, eventsPerSport)
+	for i := 0; i < eventsPerSport; i++ {
+		startTime := s.startedAt.Add(time.Duration(i-1) * time.Hour)
+		events = append(events, &domain.Event{
+			ID:        fmt.Sprintf("%s-%d", sport, i+1),
+			SportType: sport,
+			HomeTeam:  teams[(2*i)%len(teams)],
+			AwayTeam:  teams[(2*i+1)%len(teams)],
+			StartTime: startTime,
+			Status:    eventStatus(startTime, now),
+			Line:      randLine(),
+		})
+	}
+	return events, nil
+}
+
+func eventStatus(startTime, now time.Time) domain.EventStatus {
+	if now.Before(startTime) {
+		return domain.EventScheduled
+	}
+	if now.Before(startTime.Add(eventDuration)) {
+		return domain.EventLive
+	}
+	return domain.EventFinished
+}
+
+func randLine() decimal.Decimal {
+	return decimal.NewFromFloat(number.RandFloat(0.5, 3)).Round(3)
+}
+
+func randPrice(min, max float64) decimal.Decimal {
+	return decimal.NewFromFloat(number.RandFloat(min, max)).Round(2)
+}
+
+func randPoint(min, max float64) decimal.Decimal {
+	return decimal.NewFromFloat(math.Round(number.RandFloat(min, max)*2) / 2)
 }
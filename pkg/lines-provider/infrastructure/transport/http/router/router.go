@@ -1,12 +1,15 @@
 package router
 
 import (
-	"fmt"
+	"encoding/json"
+	"github.com/col3name/lines/pkg/common/domain"
 	httpUtil "github.com/col3name/lines/pkg/common/infrastructure/transport/http"
 	"github.com/col3name/lines/pkg/lines-provider/application/service"
 	"github.com/gorilla/mux"
+	"github.com/shopspring/decimal"
 	"net/http"
 	"strings"
+	"time"
 )
 
 func Router(scoreService service.ScoreService) *mux.Router {
@@ -18,6 +21,7 @@ func Router(scoreService service.ScoreService) *mux.Router {
 
 	apiV1Route := router.PathPrefix("/api/v1").Subrouter()
 	apiV1Route.HandleFunc("/lines/{sport}", controller.getSportLineHandler).Methods(http.MethodGet)
+	apiV1Route.HandleFunc("/lines/{sport}/events", controller.getEventsHandler).Methods(http.MethodGet)
 
 	return router
 }
@@ -26,6 +30,35 @@ type sportLineController struct {
 	scoreService service.ScoreService
 }
 
+type selectionResponse struct {
+	Name  string `json:"name"`
+	Price string `json:"price"`
+}
+
+type marketResponse struct {
+	Type       string              `json:"type"`
+	Point      string              `json:"point"`
+	Selections []selectionResponse `json:"selections"`
+}
+
+type linesResponse struct {
+	Lines   map[string]string           `json:"lines"`
+	Markets map[string][]marketResponse `json:"markets"`
+}
+
+type eventResponse struct {
+	ID        string `json:"id"`
+	HomeTeam  string `json:"homeTeam"`
+	AwayTeam  string `json:"awayTeam"`
+	StartTime string `json:"startTime"`
+	Status    string `json:"status"`
+	Line      string `json:"line"`
+}
+
+type eventsResponse struct {
+	Events []eventResponse `json:"events"`
+}
+
 func (c *sportLineController) getSportLineHandler(w http.ResponseWriter, req *http.Request) {
 	sport := c.parseRequest(req)
 
@@ -34,8 +67,35 @@ func (c *sportLineController) getSportLineHandler(w http.ResponseWriter, req *ht
 		w.WriteHeader(http.StatusNotFound)
 		return
 	}
+	markets, err := c.scoreService.GenerateMarkets(strings.ToLower(sport))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	data, err := c.marshalLines(sport, score, markets)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	httpUtil.WriteJSON(w, data)
+}
+
+func (c *sportLineController) getEventsHandler(w http.ResponseWriter, req *http.Request) {
+	sport := c.parseRequest(req)
+
+	events, err := c.scoreService.GenerateEvents(strings.ToLower(sport))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
 
-	httpUtil.WriteJSON(w, c.marshalScore(sport, score))
+	data, err := c.marshalEvents(events)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	httpUtil.WriteJSON(w, data)
 }
 
 func (c *sportLineController) parseRequest(req *http.Request) string {
@@ -43,6 +103,38 @@ func (c *sportLineController) parseRequest(req *http.Request) string {
 	return vars["sport"]
 }
 
-func (c *sportLineController) marshalScore(sport string, score float64) string {
-	return fmt.Sprintf("{\"lines\": {\"%s\":\"%f\"}}", strings.ToUpper(sport), score)
+func (c *sportLineController) marshalLines(sport string, score decimal.Decimal, markets []*domain.Market) (string, error) {
+	key := strings.ToUpper(sport)
+	resp := linesResponse{
+		Lines:   map[string]string{key: score.String()},
+		Markets: map[string][]marketResponse{key: make([]marketResponse, 0, len(markets))},
+	}
+	for _, market := range markets {
+		marketResp := marketResponse{Type: market.Type.String(), Point: market.Point.String()}
+		for _, selection := range market.Selections {
+			marketResp.Selections = append(marketResp.Selections, selectionResponse{
+				Name:  selection.Name,
+				Price: selection.Price.String(),
+			})
+		}
+		resp.Markets[key] = append(resp.Markets[key], marketResp)
+	}
+	data, err := json.Marshal(resp)
+	return string(data), err
+}
+
+func (c *sportLineController) marshalEvents(events []*domain.Event) (string, error) {
+	resp := eventsResponse{Events: make([]eventResponse, 0, len(events))}
+	for _, event := range events {
+		resp.Events = append(resp.Events, eventResponse{
+			ID:        event.ID,
+			HomeTeam:  event.HomeTeam,
+			AwayTeam:  event.AwayTeam,
+			StartTime: event.StartTime.Format(time.RFC3339),
+			Status:    event.Status.String(),
+			Line:      event.Line.String(),
+		})
+	}
+	data, err := json.Marshal(resp)
+	return string(data), err
 }